@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/joho/godotenv"
+	"github.com/ronilsonalves/5lnk/config/db"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/stats"
+	"log"
+)
+
+// Command rollups builds the hourly stats rollups of the stats saved before the server started rolling them up, the
+// cutover it records. It runs once after the deploy, and can be run again when stopped: the hours already rolled up
+// are skipped.
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Fatalln("Error loading .env file", err.Error())
+	}
+
+	db, err := db.GetDB()
+	if err != nil {
+		log.Fatalln("Error connecting to db: ", err.Error())
+	}
+
+	if err := db.AutoMigrate(&domain.StatsRollupBackfill{}); err != nil {
+		log.Fatalln("Error while migrating the StatsRollupBackfill model")
+	}
+
+	if err := stats.NewStatsRepository(db).BackfillRollups(); err != nil {
+		log.Fatalln("Error building the stats rollups: ", err.Error())
+	}
+	log.Println("INFO: stats rollups built")
+}
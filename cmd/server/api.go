@@ -6,16 +6,25 @@ import (
 	"github.com/gin-contrib/cache/persistence"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	"github.com/ronilsonalves/5lnk/cmd/server/handler"
 	"github.com/ronilsonalves/5lnk/config/auth"
 	"github.com/ronilsonalves/5lnk/config/db"
 	"github.com/ronilsonalves/5lnk/docs"
+	"github.com/ronilsonalves/5lnk/internal/alias"
 	"github.com/ronilsonalves/5lnk/internal/apikey"
 	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/export"
 	"github.com/ronilsonalves/5lnk/internal/link"
 	links_page "github.com/ronilsonalves/5lnk/internal/links-page"
+	"github.com/ronilsonalves/5lnk/internal/rules"
+	"github.com/ronilsonalves/5lnk/internal/shortcode"
 	"github.com/ronilsonalves/5lnk/internal/stats"
+	"github.com/ronilsonalves/5lnk/internal/urlsafety"
+	"github.com/ronilsonalves/5lnk/internal/workspace"
+	"github.com/ronilsonalves/5lnk/pkg/crawler"
+	"github.com/ronilsonalves/5lnk/pkg/geoip"
 	"github.com/ronilsonalves/5lnk/pkg/middleware"
 	"github.com/swaggo/files"       // swagger embed files
 	"github.com/swaggo/gin-swagger" // gin-swagger middleware
@@ -23,6 +32,9 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 )
 
@@ -57,40 +69,138 @@ func main() {
 		log.Fatalln("Error while migrating the LinksPage model")
 	}
 
+	// Sequence used by the sequential short code strategies
+	if err := db.Exec("CREATE SEQUENCE IF NOT EXISTS links_shortcode_seq").Error; err != nil {
+		log.Fatalln("Error while creating the short code sequence")
+	}
+
+	// Auto migrate the LinkVariant model
+	if err := db.AutoMigrate(&domain.LinkVariant{}); err != nil {
+		log.Fatalln("Error while migrating the LinkVariant model")
+	}
+
+	// Auto migrate the RedirectRule model
+	if err := db.AutoMigrate(&domain.RedirectRule{}); err != nil {
+		log.Fatalln("Error while migrating the RedirectRule model")
+	}
+
 	// Auto migrate the Stats model
-	if err := db.AutoMigrate(&domain.Stats{}); err != nil {
-		log.Fatalln("Error while migrating the Stats model")
+	if err := stats.Migrate(db); err != nil {
+		log.Fatalln("Error while migrating the Stats model: ", err.Error())
+	}
+
+	// Auto migrate the VisitorSketch model
+	if err := db.AutoMigrate(&domain.VisitorSketch{}); err != nil {
+		log.Fatalln("Error while migrating the VisitorSketch model")
+	}
+
+	// Auto migrate the APIKey model, used when the api keys are stored in Postgres
+	if err := db.AutoMigrate(&domain.APIKey{}); err != nil {
+		log.Fatalln("Error while migrating the APIKey model")
+	}
+
+	// Auto migrate the Workspace models
+	if err := db.AutoMigrate(&domain.Workspace{}, &domain.WorkspaceMember{}, &domain.WorkspaceInvitation{}); err != nil {
+		log.Fatalln("Error while migrating the Workspace models")
+	}
+
+	// Auto migrate the StatsRollup model, the stats saved before it are rolled up by cmd/rollups
+	if err := stats.MigrateRollups(db); err != nil {
+		log.Fatalln("Error while migrating the StatsRollup model: ", err.Error())
 	}
 
 	// Initialize the random number generator
 	rand.Seed(time.Now().UnixNano())
 
-	// Firebase Auth
-	ctx := context.Background()
-	app, err := auth.InitializeFirebase(ctx)
+	// Stopped by an interrupt or termination signal
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// User authentication provider
+	authenticator, err := auth.NewAuthenticatorFromEnv(ctx)
+	if err != nil {
+		log.Fatalf("error initializing the auth provider: %v\n\n", err)
+	}
+
+	// Offline GeoIP database
+	geo, err := geoip.Open(os.Getenv("GEOIP_DB_PATH"))
+	if err != nil {
+		log.Fatalln("Error loading the GeoIP database: ", err.Error())
+	}
+	bots, err := crawler.Open(os.Getenv("BOT_IP_RANGES_PATH"))
 	if err != nil {
-		log.Fatalf("error initializing app: %v\n\n", err)
+		log.Fatalln("Error loading the crawler IP ranges: ", err.Error())
 	}
 
 	// Handlers Init
 	l := link.NewLinkRepository(db)
 	lpr := links_page.NewLinksPageRepository(db)
 	sr := stats.NewStatsRepository(db)
-	s := link.NewLinkService(l)
-	lps := links_page.NewLinksPageService(lpr, s)
-	ss := stats.NewStatsService(sr)
-	lp := handler.NewLinksPageHandler(lps, ss)
-	aS := apikey.NewApiKeyService(app)
-	h := handler.NewLinkHandler(s, ss)
+	codes, err := shortcode.NewIssuerFromEnv(l.NextSequence)
+	if err != nil {
+		log.Fatalln("Error configuring the short code generator: ", err.Error())
+	}
+	aliases, err := alias.NewPolicyFromEnv()
+	if err != nil {
+		log.Fatalln("Error configuring the alias policy: ", err.Error())
+	}
+	urls, err := urlsafety.NewCheckerFromEnv()
+	if err != nil {
+		log.Fatalln("Error configuring the url safety checker: ", err.Error())
+	}
+	// Rule destinations are resolved by the link service, which follows the rules of the links it reaches
+	var s link.Service
+	rr := rules.NewRulesRepository(db)
+	rs := rules.NewRulesService(rr, urls, func(linkId uuid.UUID, destination string) (string, error) {
+		return s.ResolveDestination(linkId, destination)
+	})
+	chains, err := link.NewChainPolicyFromEnv(rs.GetDestinationsByLink)
+	if err != nil {
+		log.Fatalln("Error configuring the link chain policy: ", err.Error())
+	}
+	ws := workspace.NewWorkspaceService(workspace.NewWorkspaceRepository(db))
+	s = link.NewLinkService(l, codes, aliases, urls, chains, ws)
+	lps := links_page.NewLinksPageService(lpr, s, aliases, urls, ws)
+	queue, err := stats.NewQueueFromEnv(sr)
+	if err != nil {
+		log.Fatalln("Error configuring the stats queue: ", err.Error())
+	}
+	hub := stats.NewHub()
+	ss := stats.NewStatsService(sr, queue, hub)
+	lp := handler.NewLinksPageHandler(lps, ss, geo, bots)
+	akr, err := apikey.NewRepositoryFromEnv(ctx, db)
+	if err != nil {
+		log.Fatalln("Error configuring the api key store: ", err.Error())
+	}
+	aS := apikey.NewApiKeyService(akr)
+	h := handler.NewLinkHandler(s, ss, rs, geo, bots)
+	rh := handler.NewRulesHandler(rs, s)
 	aH := handler.NewAPIKeyHandler(aS)
 	sh := handler.NewStatsHandler(ss)
+	eh := handler.NewExportHandler(export.NewExportService(l, lpr, sr))
+	adm := handler.NewAdminHandler(s)
+	wh := handler.NewWorkspaceHandler(ws)
+
+	// Archive expired links in background
+	sweepInterval, err := time.ParseDuration(os.Getenv("LINK_SWEEP_INTERVAL"))
+	if err != nil || sweepInterval <= 0 {
+		sweepInterval = time.Minute * 10
+	}
+	link.StartExpirationSweeper(ctx, s, sweepInterval)
 
 	// Cache Init
 	store := persistence.NewRedisCache(os.Getenv("REDIS_HOST"), os.Getenv("REDIS_PASS"), time.Minute)
 	inMemory := persistence.NewInMemoryStore(time.Minute * 5)
 
 	r := gin.New()
-	r.Use(gin.Recovery(), gin.Logger(), cors.New(cors.Config{
+	// The client address is only read from the forwarding headers set by the trusted proxies
+	trustedProxies := strings.FieldsFunc(os.Getenv("TRUSTED_PROXIES"), func(r rune) bool { return r == ',' || r == ' ' })
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalln("Error configuring the trusted proxies: ", err.Error())
+	}
+	// Without trusted proxies the headers are never read, so the client address is the peer of the connection
+	r.ForwardedByClientIP = len(trustedProxies) > 0
+	r.Use(gin.Recovery(), middleware.Logger(), cors.New(cors.Config{
 		AllowOrigins: []string{"http://localhost:3000", "http://localhost:8080", "https://*.5lnk.live", "https://www.5lnk.live", "https://*.vercel.app"},
 		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders: []string{"Origin", "Content-Type", "Authorization"},
@@ -116,80 +226,178 @@ func main() {
 			c.Redirect(http.StatusMovedPermanently, "https://5lnk.live/?source=api_endpoint")
 		}))
 
-	// Redirect to the original URL
-	r.GET(":shortened",
-		cache.CachePage(store, time.Minute*1, h.RedirectShortenedURL()))
+	// Redirect to the original URL. Not cached: every hit must reach the handler to enforce the link lifecycle.
+	r.GET(":shortened", h.RedirectShortenedURL())
+	r.POST(":shortened", h.UnlockShortenedURL())
+
+	// Admin endpoints, authenticated by the admin token instead of the user authentication
+	admin := r.Group("/api/v1/admin", middleware.RequireAdmin())
+	{
+		admin.PUT("/links/:id/flag", adm.FlagLink())
+		admin.DELETE("/links/:id/flag", adm.UnflagLink())
+		admin.GET("/links/cycles", adm.GetLinkCycles())
+	}
+
+	// Stats streams, authenticated on their own as browsers pass the token in the query
+	streams := r.Group("/api/v1/stats/user/:userId", middleware.TokenFromQuery(), middleware.Authenticate(authenticator, aS),
+		middleware.RequireScope(apikey.ScopeStatsRead), middleware.RequireUser("userId"))
+	{
+		streams.GET("/stream", sh.StreamUserStats())
+		streams.GET("/ws", sh.StreamUserStatsWebSocket())
+	}
+	workspaceStreams := r.Group("/api/v1/stats/workspace/:workspaceId", middleware.TokenFromQuery(),
+		middleware.Authenticate(authenticator, aS), middleware.RequireScope(apikey.ScopeStatsRead),
+		wh.AuthorizeWorkspace("workspaceId", domain.RoleViewer))
+	{
+		workspaceStreams.GET("/stream", sh.StreamWorkspaceStats())
+		workspaceStreams.GET("/ws", sh.StreamWorkspaceStatsWebSocket())
+	}
 
-	// User authentication
-	r.Use(middleware.Authenticate(ctx, app))
+	// User authentication. Resources are authorized against the principal, and the scopes of its API key, before the
+	// cache, shared by every user.
+	r.Use(middleware.Authenticate(authenticator, aS))
 	// API v1
 	api := r.Group("/api/v1")
 	{
-		apiKeys := api.Group("/apikeys")
+		apiKeys := api.Group("/apikeys", middleware.RequireToken())
 		{
 			apiKeys.POST("", aH.PostAPIKey())
-			apiKeys.GET(":userId", aH.RetrieveAPIKey())
-			apiKeys.DELETE(":userId", aH.DeleteAPIKey())
+			apiKeys.GET(":userId", middleware.RequireUser("userId"), aH.RetrieveAPIKeys())
+			apiKeys.DELETE(":userId", middleware.RequireUser("userId"), aH.DeleteAPIKeys())
+			apiKeys.DELETE(":userId/:keyId", middleware.RequireUser("userId"), aH.DeleteAPIKey())
+		}
+
+		workspaces := api.Group("/workspaces", middleware.RequireToken())
+		{
+			viewer, admin := wh.AuthorizeWorkspace("workspaceId", domain.RoleViewer), wh.AuthorizeWorkspace("workspaceId", domain.RoleAdmin)
+			workspaces.POST("", wh.PostWorkspace())
+			workspaces.GET("/user/:userId", middleware.RequireUser("userId"), wh.GetAllWorkspacesByUser())
+			workspaces.POST("/invitations/:invitationId/accept", wh.AcceptInvitation())
+			workspaces.GET(":workspaceId", viewer, wh.GetWorkspace())
+			workspaces.PUT(":workspaceId", admin, wh.UpdateWorkspace())
+			workspaces.DELETE(":workspaceId", wh.AuthorizeWorkspace("workspaceId", domain.RoleOwner), wh.DeleteWorkspace())
+			workspaces.PUT(":workspaceId/members/:userId", viewer, wh.UpdateMember())
+			workspaces.DELETE(":workspaceId/members/:userId", viewer, wh.RemoveMember())
+			workspaces.POST(":workspaceId/invitations", admin, wh.PostInvitation())
+			workspaces.GET(":workspaceId/invitations", admin, wh.GetInvitations())
+			workspaces.DELETE(":workspaceId/invitations/:invitationId", admin, wh.DeleteInvitation())
 		}
 
 		links := api.Group("/links")
 		{
-			links.POST("", h.PostURL())
-			links.PUT("", h.Update())
-			links.GET(":id",
+			readLinks, writeLinks := middleware.RequireScope(apikey.ScopeLinksRead), middleware.RequireScope(apikey.ScopeLinksWrite)
+			links.POST("", writeLinks, h.PostURL())
+			links.POST("/bulk", writeLinks, h.BulkPostURL())
+			links.PUT("", writeLinks, h.Update())
+			links.GET(":id", readLinks, h.AuthorizeLink("id", domain.RoleViewer),
 				cache.CachePage(inMemory, time.Minute, h.GetLink()))
-			links.DELETE("", h.Delete())
-			links.GET("/user/:userId",
+			links.DELETE("", writeLinks, h.Delete())
+			links.GET("/user/:userId", readLinks, middleware.RequireUser("userId"),
 				cache.CachePage(store, time.Minute, h.GetAllByUser()))
+			links.GET("/workspace/:workspaceId", readLinks, wh.AuthorizeWorkspace("workspaceId", domain.RoleViewer),
+				cache.CachePage(store, time.Minute, h.GetAllByWorkspace()))
+			links.GET(":id/rules", readLinks, rh.GetRules())
+			links.POST(":id/rules", writeLinks, rh.PostRule())
+			links.PUT(":id/rules/:ruleId", writeLinks, rh.UpdateRule())
+			links.DELETE(":id/rules/:ruleId", writeLinks, rh.DeleteRule())
 		}
 
 		linksPage := api.Group("/pages")
 		{
-			linksPage.POST("", lp.PostPage())
-			linksPage.GET(":alias", lp.GetPageByAlias())
-			linksPage.GET("/user/:userId", lp.GetAllPagesByUser())
-			linksPage.PUT("", lp.Update())
-			linksPage.DELETE("", lp.Delete())
+			readPages, writePages := middleware.RequireScope(apikey.ScopePagesRead), middleware.RequireScope(apikey.ScopePagesWrite)
+			linksPage.POST("", writePages, lp.PostPage())
+			linksPage.GET(":alias", readPages, middleware.ForwardedVisitor(), lp.GetPageByAlias())
+			linksPage.GET("/user/:userId", readPages, middleware.RequireUser("userId"), lp.GetAllPagesByUser())
+			linksPage.GET("/workspace/:workspaceId", readPages, wh.AuthorizeWorkspace("workspaceId", domain.RoleViewer),
+				lp.GetAllPagesByWorkspace())
+			linksPage.PUT("", writePages, lp.Update())
+			linksPage.DELETE("", writePages, lp.Delete())
 		}
 
-		st := api.Group("/stats")
+		exp := api.Group("/export", middleware.RequireScope(apikey.ScopeLinksRead),
+			middleware.RequireScope(apikey.ScopePagesRead), middleware.RequireScope(apikey.ScopeStatsRead))
 		{
-			st.GET("/link/:linkId",
+			exp.GET("/user/:userId", middleware.RequireUser("userId"), eh.ExportByUser())
+		}
+
+		st := api.Group("/stats", middleware.RequireScope(apikey.ScopeStatsRead))
+		{
+			st.GET("/link/:linkId", h.AuthorizeLink("linkId", domain.RoleViewer),
 				cache.CachePage(store, time.Minute, sh.GetLinkStats()))
 		}
 		{
-			st.GET("/link/:linkId/stats",
+			st.GET("/link/:linkId/stats", h.AuthorizeLink("linkId", domain.RoleViewer),
 				cache.CachePage(store, time.Minute, sh.GetLinkStatsByDate()))
 		}
 		{
-			st.GET("/page/:pageId",
+			st.GET("/link/:linkId/variants", h.AuthorizeLink("linkId", domain.RoleViewer),
+				cache.CachePage(store, time.Minute, sh.GetLinkStatsByVariant()))
+		}
+		{
+			st.GET("/link/:linkId/visitors", h.AuthorizeLink("linkId", domain.RoleViewer),
+				cache.CachePage(store, time.Minute, sh.GetLinkVisitors()))
+		}
+		{
+			st.GET("/page/:pageId", lp.AuthorizePage("pageId", domain.RoleViewer),
 				cache.CachePage(store, time.Minute, sh.GetPageStats()))
 		}
 		{
-			st.GET("/page/:pageId/stats",
+			st.GET("/page/:pageId/stats", lp.AuthorizePage("pageId", domain.RoleViewer),
 				cache.CachePage(store, time.Minute, sh.GetPageStatsByDate()))
 		}
 		{
-			st.GET("/user/:userId",
+			st.GET("/page/:pageId/visitors", lp.AuthorizePage("pageId", domain.RoleViewer),
+				cache.CachePage(store, time.Minute, sh.GetPageVisitors()))
+		}
+		{
+			st.GET("/user/:userId", middleware.RequireUser("userId"),
 				cache.CachePage(store, time.Minute, sh.GetStatsByUserId()))
 		}
 		{
-			st.GET("/user/:userId/overview",
+			st.GET("/user/:userId/overview", middleware.RequireUser("userId"),
 				cache.CachePage(store, time.Minute, sh.GetUserStatsOverview()))
 		}
 		{
-			st.GET("/user/:userId/links",
+			st.GET("/workspace/:workspaceId/overview", wh.AuthorizeWorkspace("workspaceId", domain.RoleViewer),
+				cache.CachePage(store, time.Minute, sh.GetWorkspaceStatsOverview()))
+		}
+		{
+			st.GET("/user/:userId/links", middleware.RequireUser("userId"),
 				cache.CachePage(store, time.Minute, sh.GetLinkStatsByUserIdAndDate()))
 		}
 		{
-			st.GET("/user/:userId/pages",
+			st.GET("/user/:userId/pages", middleware.RequireUser("userId"),
 				cache.CachePage(store, time.Minute, sh.GetPageStatsByUserIdAndDate()))
 		}
 	}
 
+	// Aliases can't shadow any registered route
+	for _, route := range r.Routes() {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route.Path, "/"), "/")
+		if segment != "" && !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			aliases.Reserve(segment)
+		}
+	}
+
 	// Start the HTTP server
-	if err := r.Run(":8080"); err != nil {
-		gin.SetMode(gin.ReleaseMode)
-		log.Fatalln("Error in Gin server: ", err.Error())
+	server := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			gin.SetMode(gin.ReleaseMode)
+			log.Fatalln("Error in Gin server: ", err.Error())
+		}
+	}()
+
+	// Graceful shutdown: end the streams and finish the requests in flight, then save the queued stats
+	<-ctx.Done()
+	log.Println("INFO: shutting down the server")
+	hub.Close()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("ERROR: unable to shut down the server gracefully: %v", err.Error())
+	}
+	if err := queue.Close(shutdownCtx); err != nil {
+		log.Printf("ERROR: %v", err.Error())
 	}
 }
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/link"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"net/http"
+)
+
+type adminHandler struct {
+	ls link.Service
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(ls link.Service) *adminHandler {
+	return &adminHandler{
+		ls: ls,
+	}
+}
+
+// FlagLink flags a link as unsafe.
+// @BasePath /api/v1
+// FlagLink godoc
+// @Summary Flag a link as unsafe
+// @Schemes
+// @Description Flag a link as unsafe, its visitors are shown a warning before being redirected.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Link ID"
+// @Param X-Admin-Token header string true "Admin token"
+// @Param body body web.FlagLink true "Body"
+// @Success 200 {object} domain.Link
+// @Failure 400 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/admin/links/{id}/flag [PUT]
+func (h *adminHandler) FlagLink() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		linkId, err := uuid.Parse(ctx.Param("id"))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid link ID provided")
+			return
+		}
+		var request web.FlagLink
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid request body provided")
+			return
+		}
+		if err := h.ls.Flag(linkId, request.Reason); err != nil {
+			web.BadResponse(ctx, http.StatusNotFound, "error", err.Error())
+			return
+		}
+		h.respondLink(ctx, linkId)
+	}
+}
+
+// UnflagLink removes the unsafe flag of a link.
+// @BasePath /api/v1
+// UnflagLink godoc
+// @Summary Unflag a link
+// @Schemes
+// @Description Remove the unsafe flag of a link, its visitors are redirected again without warning.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Link ID"
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} domain.Link
+// @Failure 400 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/admin/links/{id}/flag [DELETE]
+func (h *adminHandler) UnflagLink() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		linkId, err := uuid.Parse(ctx.Param("id"))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid link ID provided")
+			return
+		}
+		if err := h.ls.Unflag(linkId); err != nil {
+			web.BadResponse(ctx, http.StatusNotFound, "error", err.Error())
+			return
+		}
+		h.respondLink(ctx, linkId)
+	}
+}
+
+// GetLinkCycles returns the links redirecting to each other in a loop.
+// @BasePath /api/v1
+// GetLinkCycles godoc
+// @Summary Detect redirect loops
+// @Schemes
+// @Description Detect the links redirecting to each other in a loop, each cycle lists the shortened URLs in redirect order.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param X-Admin-Token header string true "Admin token"
+// @Success 200 {object} [][]string
+// @Failure 403 {object} web.errorResponse
+// @Failure 500 {object} web.errorResponse
+// @Router /api/v1/admin/links/cycles [GET]
+func (h *adminHandler) GetLinkCycles() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		response, err := h.ls.DetectCycles()
+		if err != nil {
+			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+			return
+		}
+		web.ResponseOK(ctx, http.StatusOK, response)
+	}
+}
+
+// respondLink answers with the current state of a link.
+func (h *adminHandler) respondLink(ctx *gin.Context, linkId uuid.UUID) {
+	response, err := h.ls.GetLink(linkId)
+	if err != nil {
+		web.BadResponse(ctx, http.StatusNotFound, "error", err.Error())
+		return
+	}
+	web.ResponseOK(ctx, http.StatusOK, response)
+}
@@ -22,26 +22,33 @@ func NewAPIKeyHandler(s apikey.Service) *apikeyHandler {
 // PostAPIKey godoc
 // @Summary Create a new API Key
 // @Schemes
-// @Description Create a new API Key. If the user already has an API Key, it will be revoked.
+// @Description Create a new API Key with a name, its scopes (links:read, links:write, pages:read, pages:write, stats:read,
+// @Description visits:forward or resource:* for every action), an optional expiration date and the addresses it can be
+// @Description used from. visits:forward lets a frontend record the page views for the visitor it forwards.
+// @Description The token is only returned once, the key is identified by its prefix afterwards.
 // @Tags API Keys
 // @Accept json
 // @Produce json
-// @Param body body web.APIKey true "Body"
-// @Success 201 {object} string
+// @Param body body web.CreateAPIKey true "Body"
+// @Success 201 {object} domain.APIKey
 // @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Failure 500 {object} web.errorResponse
 // @Router /api/v1/apikeys [POST]
 func (h *apikeyHandler) PostAPIKey() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		var request web.APIKey
-		err := ctx.ShouldBindJSON(&request)
-		if err != nil {
-			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid userId provided")
+		var request web.CreateAPIKey
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "the name and the scopes of the api key are required")
+			return
+		}
+		if !bindPrincipal(ctx, &request.UserId) {
 			return
 		}
-		response, err := h.s.GenerateApiKey(request.UserId)
+		response, err := h.s.Create(request.UserId, request)
 		if err != nil {
-			web.BadResponse(ctx, http.StatusInternalServerError, "error", "an error occurred while generating the api key")
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
 			return
 		}
 
@@ -49,25 +56,26 @@ func (h *apikeyHandler) PostAPIKey() gin.HandlerFunc {
 	}
 }
 
-// RetrieveAPIKey returns an API Key.
+// RetrieveAPIKeys returns the API Keys of a user.
 // @BasePath /api/v1
-// RetrieveAPIKey godoc
-// @Summary Retrieve an API Key
+// RetrieveAPIKeys godoc
+// @Summary Retrieve the API Keys of a user
 // @Schemes
-// @Description Retrieve an API Key.
+// @Description Retrieve the API Keys of a user, without their tokens.
 // @Tags API Keys
 // @Accept json
 // @Produce json
 // @Param userId path string true "User ID"
-// @Success 200 {object} string
-// @Failure 404 {object} web.errorResponse
+// @Success 200 {object} []domain.APIKey
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 500 {object} web.errorResponse
 // @Router /api/v1/apikeys/{userId} [GET]
-func (h *apikeyHandler) RetrieveAPIKey() gin.HandlerFunc {
+func (h *apikeyHandler) RetrieveAPIKeys() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		userId := ctx.Param("userId")
-		response, err := h.s.RetrieveApiKey(userId)
+		response, err := h.s.GetAllByUser(ctx.Param("userId"))
 		if err != nil {
-			web.BadResponse(ctx, http.StatusNotFound, "error", "api key not found for userID")
+			web.BadResponse(ctx, http.StatusInternalServerError, "error", "an error occurred while retrieving the api keys")
 			return
 		}
 
@@ -75,35 +83,56 @@ func (h *apikeyHandler) RetrieveAPIKey() gin.HandlerFunc {
 	}
 }
 
-// DeleteAPIKey delete an API Key by an userId.
+// DeleteAPIKey revokes an API Key of a user.
 // @BasePath /api/v1
 // DeleteAPIKey godoc
 // @Summary Delete an API Key
 // @Schemes
-// @Description Delete an API Key.
+// @Description Revoke an API Key of a user.
 // @Tags API Keys
 // @Accept json
 // @Produce json
 // @Param userId path string true "User ID"
+// @Param keyId path string true "API Key ID"
 // @Success 204
-// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Failure 404 {object} web.errorResponse
 // @Failure 500 {object} web.errorResponse
-// @Router /api/v1/apikeys/{userId} [DELETE]
+// @Router /api/v1/apikeys/{userId}/{keyId} [DELETE]
 func (h *apikeyHandler) DeleteAPIKey() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		userId := ctx.Param("userId")
-		if userId == "" {
-			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid userId provided")
-			return
-		}
-		_, err := h.s.RetrieveApiKey(userId)
-		if err != nil {
-			web.BadResponse(ctx, http.StatusNotFound, "error", "api key not found for userID")
+		if err := h.s.Revoke(ctx.Param("userId"), ctx.Param("keyId")); err != nil {
+			if err.Error() == "record not found" {
+				web.BadResponse(ctx, http.StatusNotFound, "error", "api key not found for userID")
+				return
+			}
+			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
 			return
 		}
-		err = h.s.RevokesApiKey(userId)
-		if err != nil {
+
+		web.ResponseOK(ctx, http.StatusNoContent, nil)
+	}
+}
+
+// DeleteAPIKeys revokes every API Key of a user.
+// @BasePath /api/v1
+// DeleteAPIKeys godoc
+// @Summary Delete the API Keys of a user
+// @Schemes
+// @Description Revoke every API Key of a user.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Success 204
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 500 {object} web.errorResponse
+// @Router /api/v1/apikeys/{userId} [DELETE]
+func (h *apikeyHandler) DeleteAPIKeys() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if err := h.s.RevokeAll(ctx.Param("userId")); err != nil {
 			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
 			return
 		}
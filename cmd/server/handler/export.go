@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/ronilsonalves/5lnk/internal/export"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"log"
+	"net/http"
+	"time"
+)
+
+type exportHandler struct {
+	s export.Service
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(s export.Service) *exportHandler {
+	return &exportHandler{s: s}
+}
+
+// ExportByUser streams all links, pages and optionally stats of a user.
+// @BasePath /api/v1
+// ExportByUser godoc
+// @Summary Export all links, pages and stats of a user
+// @Schemes
+// @Description Stream all links, pages and, when requested, their stats as a CSV, JSON or NDJSON file.
+// @Tags Export
+// @Produce json,text/csv,application/x-ndjson
+// @Param userId path string true "User ID"
+// @Param format query string false "Format: csv, json (default) or ndjson"
+// @Param stats query bool false "Include stats"
+// @Success 200 {file} file
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Router /api/v1/export/user/{userId} [GET]
+func (h *exportHandler) ExportByUser() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.Param("userId")
+		format, err := export.ParseFormat(ctx.Query("format"))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+		withStats := ctx.Query("stats") == "true"
+
+		filename := fmt.Sprintf("5lnk-export-%s.%s", time.Now().Format(time.DateOnly), format)
+		ctx.Header("Content-Type", format.ContentType())
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		ctx.Status(http.StatusOK)
+
+		// The status is already sent once streaming starts, so failures can only be logged
+		if err := h.s.Export(ctx.Writer, userId, format, withStats); err != nil {
+			log.Printf("ERROR: unable to export data of user `%s` due to %v", userId, err.Error())
+			ctx.Abort()
+		}
+	}
+}
@@ -1,28 +1,65 @@
 package handler
 
 import (
+	"encoding/csv"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/ronilsonalves/5lnk/internal/domain"
 	"github.com/ronilsonalves/5lnk/internal/link"
+	"github.com/ronilsonalves/5lnk/internal/rules"
 	"github.com/ronilsonalves/5lnk/internal/stats"
+	"github.com/ronilsonalves/5lnk/pkg/crawler"
+	"github.com/ronilsonalves/5lnk/pkg/geoip"
 	"github.com/ronilsonalves/5lnk/pkg/middleware"
 	"github.com/ronilsonalves/5lnk/pkg/web"
+	"html/template"
+	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 )
 
+// maxBulkRows is the maximum number of links created by a single bulk request.
+const maxBulkRows = 1000
+
+// maxUnlockAttempts and maxLinkUnlockAttempts are how many wrong passwords a visitor, and every visitor together,
+// can try on a protected link in the unlock window.
+const (
+	maxUnlockAttempts     = 5
+	maxLinkUnlockAttempts = 50
+	unlockWindow          = time.Minute * 15
+)
+
+// unlockDuration is how long a visitor keeps access to a protected link after unlocking it.
+const unlockDuration = time.Hour
+
+// variantDuration is how long a visitor sticks to the variant of a split link assigned to them.
+const variantDuration = time.Hour * 24 * 30
+
 type linkHandler struct {
-	s  link.Service
-	st stats.Service
+	s              link.Service
+	st             stats.Service
+	rs             rules.Service
+	geo            *geoip.DB
+	bots           *crawler.Ranges
+	unlockAttempts *middleware.AttemptLimiter
+	linkAttempts   *middleware.AttemptLimiter
 }
 
 // NewLinkHandler creates a new link handler
-func NewLinkHandler(s link.Service, st stats.Service) *linkHandler {
+func NewLinkHandler(s link.Service, st stats.Service, rs rules.Service, geo *geoip.DB, bots *crawler.Ranges) *linkHandler {
 	return &linkHandler{
-		s:  s,
-		st: st,
+		s:              s,
+		st:             st,
+		rs:             rs,
+		geo:            geo,
+		bots:           bots,
+		unlockAttempts: middleware.NewAttemptLimiter(maxUnlockAttempts, unlockWindow),
+		linkAttempts:   middleware.NewAttemptLimiter(maxLinkUnlockAttempts, unlockWindow),
 	}
 }
 
@@ -39,6 +76,7 @@ func NewLinkHandler(s link.Service, st stats.Service) *linkHandler {
 // @Success 201 {object} domain.Link
 // @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Router /api/v1/links [POST]
 func (h *linkHandler) PostURL() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -48,8 +86,14 @@ func (h *linkHandler) PostURL() gin.HandlerFunc {
 			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid url provided")
 			return
 		}
+		if !bindPrincipal(ctx, &request.UserId) {
+			return
+		}
 		response, err := h.s.ShortenURL(request)
 		if err != nil {
+			if rejectForbidden(ctx, err) {
+				return
+			}
 			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
 			return
 		}
@@ -58,6 +102,67 @@ func (h *linkHandler) PostURL() gin.HandlerFunc {
 	}
 }
 
+// BulkPostURL create shortened links in bulk from a JSON array or a CSV file.
+// @BasePath /api/v1
+// BulkPostURL godoc
+// @Summary Create shortened links in bulk
+// @Schemes
+// @Description Create shortened links from a JSON array, or from a CSV file with the columns url, alias, title and domain
+// @Description sent as the `file` field of a multipart form. Each row is reported as created, deduplicated or failed.
+// @Tags Links
+// @Accept json,mpfd,text/csv
+// @Produce json
+// @Param body body []web.CreateShortenURL false "Body"
+// @Param file formData file false "CSV file"
+// @Param domain formData string false "Domain of the CSV rows without one"
+// @Success 200 {object} web.BulkLinkReport
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Router /api/v1/links/bulk [POST]
+func (h *linkHandler) BulkPostURL() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var requests []web.CreateShortenURL
+		var err error
+		switch ctx.ContentType() {
+		case "multipart/form-data":
+			var file *multipart.FileHeader
+			if file, err = ctx.FormFile("file"); err != nil {
+				web.BadResponse(ctx, http.StatusBadRequest, "error", "the csv file is required")
+				return
+			}
+			var content multipart.File
+			if content, err = file.Open(); err != nil {
+				web.BadResponse(ctx, http.StatusBadRequest, "error", "unable to read the csv file")
+				return
+			}
+			defer content.Close()
+			requests, err = parseBulkCSV(content, ctx.PostForm("domain"))
+		case "text/csv":
+			requests, err = parseBulkCSV(ctx.Request.Body, ctx.Query("domain"))
+		default:
+			if err = ctx.ShouldBindJSON(&requests); err != nil {
+				err = fmt.Errorf("invalid request body provided")
+			}
+		}
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+		if len(requests) == 0 || len(requests) > maxBulkRows {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", fmt.Sprintf("between 1 and %d links must be provided", maxBulkRows))
+			return
+		}
+		for i := range requests {
+			if !bindPrincipal(ctx, &requests[i].UserId) {
+				return
+			}
+		}
+
+		web.ResponseOK(ctx, http.StatusOK, h.s.ShortenURLs(requests))
+	}
+}
+
 // GetLink returns a shortened link.
 // @BasePath /api/v1
 // GetURL godoc
@@ -81,8 +186,12 @@ func (h *linkHandler) GetLink() gin.HandlerFunc {
 			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid link ID provided")
 			return
 		}
-		response, err := h.s.GetLink(parsedUUID)
+		response, err := h.s.GetAuthorizedLink(middleware.GetPrincipal(ctx).UserId, parsedUUID, domain.RoleViewer)
 		if err != nil {
+			if err.Error() == "record not found" {
+				web.BadResponse(ctx, http.StatusNotFound, "error", fmt.Errorf("the link `%s` not found", id).Error())
+				return
+			}
 			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
 			return
 		}
@@ -91,12 +200,37 @@ func (h *linkHandler) GetLink() gin.HandlerFunc {
 	}
 }
 
+// AuthorizeLink is a middleware that only lets the principal reach the link of the path param when they own it, or
+// have the role in its workspace. It runs before the cached handlers, as the cache is shared by every user.
+func (h *linkHandler) AuthorizeLink(param string, role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		linkId, err := uuid.Parse(ctx.Param(param))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid link ID provided")
+			return
+		}
+		if _, err := h.s.GetAuthorizedLink(middleware.GetPrincipal(ctx).UserId, linkId, role); err != nil {
+			if rejectForbidden(ctx, err) {
+				return
+			}
+			if err.Error() == "record not found" {
+				web.BadResponse(ctx, http.StatusNotFound, "error", fmt.Errorf("the link `%s` not found", linkId).Error())
+				return
+			}
+			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
 // Update update a shortened link.
 // @BasePath /api/v1
 // Update godoc
 // @Summary Update a shortened link from a URL address and a provided alias
 // @Schemes
-// @Description Update a shortened link from a long URL.
+// @Description Update a shortened link from a long URL. The password is kept unless a new one is provided, or
+// @Description removePassword is set.
 // @Tags Links
 // @Accept json
 // @Produce json
@@ -104,6 +238,7 @@ func (h *linkHandler) GetLink() gin.HandlerFunc {
 // @Success 200 {object} domain.Link
 // @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Failure 404 {object} web.errorResponse
 // @Router /api/v1/links [PUT]
 func (h *linkHandler) Update() gin.HandlerFunc {
@@ -114,8 +249,11 @@ func (h *linkHandler) Update() gin.HandlerFunc {
 			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid request body provided")
 			return
 		}
-		response, err := h.s.Update(request)
+		response, err := h.s.Update(middleware.GetPrincipal(ctx).UserId, request)
 		if err != nil {
+			if rejectForbidden(ctx, err) {
+				return
+			}
 			web.BadResponse(ctx, http.StatusNotFound, "error", err.Error())
 			return
 		}
@@ -137,6 +275,7 @@ func (h *linkHandler) Update() gin.HandlerFunc {
 // @Success 204
 // @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Failure 404 {object} web.errorResponse
 // @Router /api/v1/links [DELETE]
 func (h *linkHandler) Delete() gin.HandlerFunc {
@@ -147,7 +286,10 @@ func (h *linkHandler) Delete() gin.HandlerFunc {
 			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid request body provided")
 			return
 		}
-		if err := h.s.Delete(request); err != nil {
+		if err := h.s.Delete(middleware.GetPrincipal(ctx).UserId, request); err != nil {
+			if rejectForbidden(ctx, err) {
+				return
+			}
 			web.BadResponse(ctx, http.StatusNotFound, "error", err.Error())
 			return
 		}
@@ -169,6 +311,7 @@ func (h *linkHandler) Delete() gin.HandlerFunc {
 // @Success 200 {object} []domain.Link
 // @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Router /api/v1/links/user/{userId} [GET]
 func (h *linkHandler) GetAllByUser() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -182,43 +325,322 @@ func (h *linkHandler) GetAllByUser() gin.HandlerFunc {
 	}
 }
 
+// GetAllByWorkspace returns all shortened links by workspace.
+// @BasePath /api/v1
+// GetAllByWorkspace godoc
+// @Summary Get all shortened links by workspace
+// @Schemes
+// @Description Get all shortened links of a workspace the user is a member of.
+// @Tags Links
+// @Accept json
+// @Produce json
+// @Param workspaceId path string true "Workspace ID"
+// @Success 200 {object} []domain.Link
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/links/workspace/{workspaceId} [GET]
+func (h *linkHandler) GetAllByWorkspace() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		workspaceId, err := uuid.Parse(ctx.Param("workspaceId"))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid workspace ID provided")
+			return
+		}
+		response, err := h.s.GetAllByWorkspace(workspaceId)
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+		web.ResponseOK(ctx, http.StatusOK, response)
+	}
+}
+
 // RedirectShortenedURL redirect to original URL from a shortened link.
 // @BasePath /
 // RedirectShortenedURL godoc
 // @Summary Redirect to original URL
 // @Schemes
-// @Description Redirect to original URL
+// @Description Redirect to the destination of the first matching redirect rule, to a weighted variant, or to original URL. Password protected links serve an unlock form instead, crawlers get the Open Graph preview of the link, or a generic one when it is protected or flagged. Depending on the link, the status is 301, 302, 307 or 308, or a meta refresh or JavaScript page is served, and the UTM template and visitor query parameters are appended.
 // @Tags Links
 // @Accept json
 // @Produce json
 // @Param shortened path string true "Shortened URL"
+// @Param unlock query string false "Unlock token"
+// @Param proceed query string false "Set to 1 to skip the warning of a flagged link"
 // @Success 302 {string} redirected
+// @Success 200 {string} warning, app, interstitial redirect or crawler preview page
+// @Failure 401 {string} unlock form
 // @Failure 404 {object} web.errorResponse
+// @Failure 410 {object} web.errorResponse
 // @Router /{shortened} [GET]
 func (h *linkHandler) RedirectShortenedURL() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		ua := middleware.GetFormattedUserAgent(ctx)
-		shortened := ctx.Param("shortened")
-		lnk, err := h.s.GetLinkByShortened(shortened)
-		if err != nil {
-			web.BadResponse(ctx, http.StatusNotFound, "error", "lnk not found")
+		lnk, ok := h.findActiveLink(ctx)
+		if !ok {
+			return
+		}
+
+		// Crawlers get the social preview of the link, their hits are not counted as clicks
+		if ua := middleware.GetFormattedUserAgent(ctx); ua.Crawler {
+			h.preview(ctx, lnk, ua)
 			return
 		}
 
-		stat := domain.Stats{
-			LinkRefer: lnk.ID.String(),
-			Timestamp: time.Now(),
-			Os:        ua.OS,
-			Browser:   ua.Browser,
+		if lnk.IsProtected() {
+			token := ctx.Query("unlock")
+			if token == "" {
+				token, _ = ctx.Cookie(unlockCookieName(lnk))
+			}
+			if !link.VerifyUnlockToken(*lnk, token) {
+				web.HTMLResponse(ctx, http.StatusUnauthorized, "unlock", gin.H{"Action": "/" + lnk.Shortened})
+				return
+			}
+		}
+
+		h.redirect(ctx, lnk)
+	}
+}
+
+// UnlockShortenedURL checks the password of a protected link and redirects to the original URL.
+// @BasePath /
+// UnlockShortenedURL godoc
+// @Summary Unlock a password protected link
+// @Schemes
+// @Description Check the password of a protected link, set the unlock cookie and redirect to original URL.
+// @Tags Links
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param shortened path string true "Shortened URL"
+// @Param password formData string true "Link password"
+// @Success 302 {string} redirected
+// @Success 200 {string} warning, app or interstitial redirect page
+// @Failure 401 {string} unlock form
+// @Failure 404 {object} web.errorResponse
+// @Failure 410 {object} web.errorResponse
+// @Failure 429 {string} unlock form
+// @Router /{shortened} [POST]
+func (h *linkHandler) UnlockShortenedURL() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		lnk, ok := h.findActiveLink(ctx)
+		if !ok {
+			return
 		}
 
-		go func() {
-			err := h.st.RegisterLinkClick(stat)
-			if err != nil {
-				log.Printf("ERROR: unable to register stats for lnk: %v", err.Error())
+		if !lnk.IsProtected() {
+			ctx.Redirect(http.StatusSeeOther, "/"+lnk.Shortened)
+			return
+		}
+
+		// Attempts are limited per visitor, and per link so changing address doesn't give a fresh budget
+		attemptKey := ctx.ClientIP() + "|" + lnk.ID.String()
+		if !h.unlockAttempts.Allow(attemptKey) || !h.linkAttempts.Allow(lnk.ID.String()) {
+			web.HTMLResponse(ctx, http.StatusTooManyRequests, "unlock", gin.H{
+				"Action": "/" + lnk.Shortened,
+				"Error":  "Too many wrong attempts, try again later.",
+			})
+			return
+		}
+
+		if err := h.s.CheckPassword(*lnk, ctx.PostForm("password")); err != nil {
+			h.unlockAttempts.Fail(attemptKey)
+			h.linkAttempts.Fail(lnk.ID.String())
+			web.HTMLResponse(ctx, http.StatusUnauthorized, "unlock", gin.H{
+				"Action": "/" + lnk.Shortened,
+				"Error":  "Wrong password, try again.",
+			})
+			return
+		}
+		h.unlockAttempts.Reset(attemptKey)
+
+		expiresAt := time.Now().Add(unlockDuration)
+		secure := ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https"
+		ctx.SetSameSite(http.SameSiteLaxMode)
+		ctx.SetCookie(unlockCookieName(lnk), link.SignUnlockToken(*lnk, expiresAt), int(unlockDuration.Seconds()), "/"+lnk.Shortened, "", secure, true)
+
+		h.redirect(ctx, lnk)
+	}
+}
+
+// findActiveLink loads the link from the shortened path param, answering the request when it is not found or expired.
+func (h *linkHandler) findActiveLink(ctx *gin.Context) (*domain.Link, bool) {
+	shortened := ctx.Param("shortened")
+	lnk, err := h.s.GetLinkByShortened(shortened)
+	if err != nil {
+		web.BadResponse(ctx, http.StatusNotFound, "error", "lnk not found")
+		return nil, false
+	}
+
+	if lnk.IsArchived() || lnk.IsExpired(time.Now()) {
+		web.GoneResponse(ctx, "the lnk has expired", os.Getenv("LINK_EXPIRED_URL"))
+		return nil, false
+	}
+	return lnk, true
+}
+
+// redirect records the click and sends the visitor to the destination of the first matching rule,
+// to one of the link variants, or to the original URL.
+func (h *linkHandler) redirect(ctx *gin.Context, lnk *domain.Link) {
+	// Visitors of a flagged link are warned and must confirm before being redirected
+	if lnk.Flagged && ctx.Query("proceed") != "1" {
+		query := ctx.Request.URL.Query()
+		query.Set("proceed", "1")
+		web.HTMLResponse(ctx, http.StatusOK, "warning", gin.H{
+			"Reason":      lnk.FlagReason,
+			"ContinueURL": "/" + lnk.Shortened + "?" + query.Encode(),
+		})
+		return
+	}
+
+	ua := middleware.GetFormattedUserAgent(ctx)
+	stat := newVisit(ctx, ua, h.geo, h.bots)
+	destination, variantId := lnk.Original, ""
+	if ruleDestination, ok := h.rs.Resolve(lnk.ID, rules.Visitor{
+		OS:        ua.OS,
+		Browser:   ua.Browser,
+		Languages: rules.ParseAcceptLanguage(ctx.GetHeader("Accept-Language")),
+		Country:   stat.Country,
+		Time:      stat.Timestamp,
+	}); ok {
+		destination = ruleDestination
+	} else if len(lnk.Variants) > 0 {
+		variant := h.chooseVariant(ctx, lnk)
+		destination = variant.Destination
+		variantId = variant.ID.String()
+	}
+	destination = link.BuildDestination(destination, *lnk, ctx.Request.URL.Query())
+	appURL, fallbackURL, openApp := link.AppDestination(*lnk, ua.Platform, destination)
+	target := domain.TargetWeb
+	if openApp {
+		target = domain.TargetApp
+	}
+	stat.LinkRefer = lnk.ID.String()
+	stat.Variant = variantId
+	stat.Target = target
+
+	// The click is counted when the stats are saved, bots don't consume the click budget of the link
+	if err := h.st.RegisterLinkClick(lnk.UserId, lnk.WorkspaceId, stat); err != nil {
+		log.Printf("ERROR: unable to register stats for lnk: %v", err.Error())
+	}
+
+	// The app URI was validated when the link was saved, so it is trusted in the page
+	if openApp {
+		web.HTMLResponse(ctx, http.StatusOK, "app", gin.H{
+			"AppURL":      template.URL(appURL),
+			"FallbackURL": fallbackURL,
+		})
+		return
+	}
+	status, isRedirect := link.RedirectStatus(*lnk)
+	if !isRedirect {
+		web.HTMLResponse(ctx, status, "redirect", gin.H{
+			"Destination": destination,
+			"Script":      lnk.RedirectType == "js",
+		})
+		return
+	}
+	ctx.Redirect(status, destination)
+}
+
+// preview serves the Open Graph page of the link, falling back to its title when no preview was customised. Only the
+// short URL is shown for password protected and flagged links.
+// The hit is recorded as a bot visit.
+func (h *linkHandler) preview(ctx *gin.Context, lnk *domain.Link, ua middleware.FormattedUserAgent) {
+	stat := newVisit(ctx, ua, h.geo, h.bots)
+	stat.LinkRefer = lnk.ID.String()
+	stat.IsBot = true
+	if err := h.st.RegisterLinkClick(lnk.UserId, lnk.WorkspaceId, stat); err != nil {
+		log.Printf("ERROR: unable to register stats for lnk: %v", err.Error())
+	}
+
+	// Protected and flagged links get a generic preview, so crawlers don't disclose what the gates hide
+	if lnk.IsProtected() || lnk.Flagged {
+		web.HTMLResponse(ctx, http.StatusOK, "preview", gin.H{"Title": lnk.FinalURL, "URL": lnk.FinalURL})
+		return
+	}
+	title := lnk.OGTitle
+	if title == "" {
+		title = lnk.Title
+	}
+	if title == "" {
+		title = lnk.FinalURL
+	}
+	web.HTMLResponse(ctx, http.StatusOK, "preview", gin.H{
+		"Title":       title,
+		"Description": lnk.OGDescription,
+		"Image":       lnk.OGImage,
+		"URL":         lnk.FinalURL,
+	})
+}
+
+// chooseVariant returns the variant the visitor was already assigned to, or assigns one by weight.
+func (h *linkHandler) chooseVariant(ctx *gin.Context, lnk *domain.Link) domain.LinkVariant {
+	cookieName := "lnk_variant_" + lnk.Shortened
+	if assigned, err := ctx.Cookie(cookieName); err == nil {
+		for _, v := range lnk.Variants {
+			if v.ID.String() == assigned {
+				return v
 			}
-		}()
+		}
+	}
+	variant := link.ChooseVariant(lnk.Variants)
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(cookieName, variant.ID.String(), int(variantDuration.Seconds()), "/"+lnk.Shortened, "", false, true)
+	return variant
+}
+
+// unlockCookieName returns the name of the cookie holding the unlock token of a link.
+func unlockCookieName(lnk *domain.Link) string {
+	return "lnk_unlock_" + lnk.Shortened
+}
+
+// parseBulkCSV reads the shortening requests of a CSV file. The header must name the url column and may name
+// the alias, title and domain columns, in any order.
+func parseBulkCSV(r io.Reader, defaultDomain string) ([]web.CreateShortenURL, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
 
-		ctx.Redirect(http.StatusFound, lnk.Original)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the csv header")
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, fmt.Errorf("the csv header must contain the url column")
+	}
+	column := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var requests []web.CreateShortenURL
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv file: %v", err)
+		}
+		if len(requests) == maxBulkRows {
+			return nil, fmt.Errorf("the csv file must have at most %d rows", maxBulkRows)
+		}
+		request := web.CreateShortenURL{
+			URL:         column(record, "url"),
+			Alias:       column(record, "alias"),
+			Title:       column(record, "title"),
+			ShortDomain: column(record, "domain"),
+		}
+		if request.ShortDomain == "" {
+			request.ShortDomain = defaultDomain
+		}
+		requests = append(requests, request)
 	}
+	return requests, nil
 }
@@ -3,25 +3,31 @@ package handler
 import (
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/ronilsonalves/5lnk/internal/domain"
 	linkspage "github.com/ronilsonalves/5lnk/internal/links-page"
 	"github.com/ronilsonalves/5lnk/internal/stats"
+	"github.com/ronilsonalves/5lnk/pkg/crawler"
+	"github.com/ronilsonalves/5lnk/pkg/geoip"
 	"github.com/ronilsonalves/5lnk/pkg/middleware"
 	"github.com/ronilsonalves/5lnk/pkg/web"
 	"log"
 	"net/http"
-	"time"
 )
 
 type linksPageHandler struct {
-	s  linkspage.Service
-	st stats.Service
+	s    linkspage.Service
+	st   stats.Service
+	geo  *geoip.DB
+	bots *crawler.Ranges
 }
 
-func NewLinksPageHandler(s linkspage.Service, st stats.Service) *linksPageHandler {
+func NewLinksPageHandler(s linkspage.Service, st stats.Service, geo *geoip.DB, bots *crawler.Ranges) *linksPageHandler {
 	return &linksPageHandler{
-		s:  s,
-		st: st,
+		s:    s,
+		st:   st,
+		geo:  geo,
+		bots: bots,
 	}
 }
 
@@ -38,6 +44,7 @@ func NewLinksPageHandler(s linkspage.Service, st stats.Service) *linksPageHandle
 // @Success 201 {object} domain.LinksPage
 // @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Router /api/v1/pages [POST]
 func (h *linksPageHandler) PostPage() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
@@ -48,9 +55,15 @@ func (h *linksPageHandler) PostPage() gin.HandlerFunc {
 			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid url provided")
 			return
 		}
+		if !bindPrincipal(ctx, &request.UserId) {
+			return
+		}
 		response, err := h.s.Create(request)
 		if err != nil {
 			log.Printf("error while creating a new linksPage: %v", err.Error())
+			if rejectForbidden(ctx, err) {
+				return
+			}
 			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
 			return
 		}
@@ -87,19 +100,12 @@ func (h *linksPageHandler) GetPageByAlias() gin.HandlerFunc {
 		}
 
 		ua := middleware.GetFormattedUserAgent(ctx)
-		stat := domain.Stats{
-			PageRefer: response.ID.String(),
-			Timestamp: time.Now(),
-			Os:        ua.OS,
-			Browser:   ua.Browser,
-		}
+		stat := newVisit(ctx, ua, h.geo, h.bots)
+		stat.PageRefer = response.ID.String()
 
-		go func() {
-			err := h.st.RegisterPageView(stat)
-			if err != nil {
-				log.Printf("ERROR: unable to register page view due to %v", err.Error())
-			}
-		}()
+		if err := h.st.RegisterPageView(response.UserId, response.WorkspaceId, stat); err != nil {
+			log.Printf("ERROR: unable to register page view due to %v", err.Error())
+		}
 
 		web.ResponseOK(ctx, http.StatusOK, response)
 	}
@@ -117,6 +123,7 @@ func (h *linksPageHandler) GetPageByAlias() gin.HandlerFunc {
 // @Param userId path string true "User ID"
 // @Success 200 {object} []domain.LinksPage
 // @Failure 400 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Failure 404 {object} web.errorResponse
 // @Router /api/v1/pages/user/{userId} [GET]
 func (h *linksPageHandler) GetAllPagesByUser() gin.HandlerFunc {
@@ -136,6 +143,62 @@ func (h *linksPageHandler) GetAllPagesByUser() gin.HandlerFunc {
 	}
 }
 
+// GetAllPagesByWorkspace returns all linksPage by workspace.
+// @BasePath /api/v1
+// GetAllPagesByWorkspace godoc
+// @Summary Get all linksPage by workspace
+// @Schemes
+// @Description Get all linksPage of a workspace the user is a member of.
+// @Tags Pages
+// @Accept json
+// @Produce json
+// @Param workspaceId path string true "Workspace ID"
+// @Success 200 {object} []domain.LinksPage
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/pages/workspace/{workspaceId} [GET]
+func (h *linksPageHandler) GetAllPagesByWorkspace() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		workspaceId, err := uuid.Parse(ctx.Param("workspaceId"))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid workspace ID provided")
+			return
+		}
+		response, err := h.s.GetAllByWorkspace(workspaceId)
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+
+		web.ResponseOK(ctx, http.StatusOK, response)
+	}
+}
+
+// AuthorizePage is a middleware that only lets the principal reach the linksPage of the path param when they own it,
+// or have the role in its workspace. It runs before the cached handlers, as the cache is shared by every user.
+func (h *linksPageHandler) AuthorizePage(param string, role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		pageId, err := uuid.Parse(ctx.Param(param))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid page ID provided")
+			return
+		}
+		if _, err := h.s.GetAuthorizedPage(middleware.GetPrincipal(ctx).UserId, pageId, role); err != nil {
+			if rejectForbidden(ctx, err) {
+				return
+			}
+			if err.Error() == "record not found" {
+				web.BadResponse(ctx, http.StatusNotFound, "error", fmt.Errorf("the linksPage `%s` not found", pageId).Error())
+				return
+			}
+			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
 // Update updates a linksPage.
 // @BasePath /api/v1
 // Update godoc
@@ -149,6 +212,7 @@ func (h *linksPageHandler) GetAllPagesByUser() gin.HandlerFunc {
 // @Success 200 {object} domain.LinksPage
 // @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Failure 404 {object} web.errorResponse
 // @Router /api/v1/pages [PUT]
 func (h *linksPageHandler) Update() gin.HandlerFunc {
@@ -160,8 +224,11 @@ func (h *linksPageHandler) Update() gin.HandlerFunc {
 			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid request body provided")
 			return
 		}
-		response, err := h.s.Update(request)
+		response, err := h.s.Update(middleware.GetPrincipal(ctx).UserId, request)
 		if err != nil {
+			if rejectForbidden(ctx, err) {
+				return
+			}
 			if err.Error() == "record not found" {
 				log.Printf("the linksPage `%s` not found", request.Alias)
 				web.BadResponse(ctx, http.StatusNotFound, "error", fmt.Errorf("the linksPage `%s` not found", request.Alias).Error())
@@ -188,6 +255,7 @@ func (h *linksPageHandler) Update() gin.HandlerFunc {
 // @Success 204
 // @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Failure 404 {object} web.errorResponse
 // @Router /api/v1/pages [DELETE]
 func (h *linksPageHandler) Delete() gin.HandlerFunc {
@@ -199,7 +267,10 @@ func (h *linksPageHandler) Delete() gin.HandlerFunc {
 			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid request body provided")
 			return
 		}
-		if err := h.s.Delete(request); err != nil {
+		if err := h.s.Delete(middleware.GetPrincipal(ctx).UserId, request); err != nil {
+			if rejectForbidden(ctx, err) {
+				return
+			}
 			if err.Error() == "record not found" {
 				log.Printf("the linksPage `%s` not found", request.Alias)
 				web.BadResponse(ctx, http.StatusNotFound, "error", fmt.Errorf("the linksPage `%s` not found", request.Alias).Error())
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/ronilsonalves/5lnk/internal/workspace"
+	"github.com/ronilsonalves/5lnk/pkg/middleware"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"net/http"
+)
+
+// bindPrincipal sets the user of a request body to the authenticated principal. A body naming another user is
+// rejected, so resources can't be created on behalf of someone else.
+func bindPrincipal(ctx *gin.Context, userId *string) bool {
+	principal := middleware.GetPrincipal(ctx)
+	if *userId != "" && *userId != principal.UserId {
+		web.BadResponse(ctx, http.StatusForbidden, "error", "access denied to the resources of another user")
+		return false
+	}
+	*userId = principal.UserId
+	return true
+}
+
+// rejectForbidden responds with a forbidden status when the role of the principal in a workspace doesn't allow the
+// action, reporting whether it did.
+func rejectForbidden(ctx *gin.Context, err error) bool {
+	if !errors.Is(err, workspace.ErrForbidden) {
+		return false
+	}
+	web.BadResponse(ctx, http.StatusForbidden, "error", err.Error())
+	return true
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/link"
+	"github.com/ronilsonalves/5lnk/internal/rules"
+	"github.com/ronilsonalves/5lnk/pkg/middleware"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"log"
+	"net/http"
+)
+
+type rulesHandler struct {
+	s  rules.Service
+	ls link.Service
+}
+
+// NewRulesHandler creates a new redirect rules handler
+func NewRulesHandler(s rules.Service, ls link.Service) *rulesHandler {
+	return &rulesHandler{
+		s:  s,
+		ls: ls,
+	}
+}
+
+// GetRules returns the redirect rules of a link.
+// @BasePath /api/v1
+// GetRules godoc
+// @Summary Get the redirect rules of a link
+// @Schemes
+// @Description Get the redirect rules of a link ordered by priority.
+// @Tags Rules
+// @Accept json
+// @Produce json
+// @Param id path string true "Link ID"
+// @Success 200 {object} []domain.RedirectRule
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/links/{id}/rules [GET]
+func (h *rulesHandler) GetRules() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		linkId, ok := h.parseLinkId(ctx, domain.RoleViewer)
+		if !ok {
+			return
+		}
+		response, err := h.s.GetRulesByLink(linkId)
+		if err != nil {
+			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+			return
+		}
+		web.ResponseOK(ctx, http.StatusOK, response)
+	}
+}
+
+// PostRule creates a redirect rule for a link.
+// @BasePath /api/v1
+// PostRule godoc
+// @Summary Create a redirect rule for a link
+// @Schemes
+// @Description Create a redirect rule matching the visitor OS, browser, language, country or time of day.
+// @Tags Rules
+// @Accept json
+// @Produce json
+// @Param id path string true "Link ID"
+// @Param body body domain.RedirectRule true "Body"
+// @Success 201 {object} domain.RedirectRule
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/links/{id}/rules [POST]
+func (h *rulesHandler) PostRule() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		linkId, ok := h.parseLinkId(ctx, domain.RoleEditor)
+		if !ok {
+			return
+		}
+		var request domain.RedirectRule
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid request body provided")
+			return
+		}
+		response, err := h.s.Create(linkId, request)
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+		web.ResponseOK(ctx, http.StatusCreated, response)
+	}
+}
+
+// UpdateRule updates a redirect rule of a link.
+// @BasePath /api/v1
+// UpdateRule godoc
+// @Summary Update a redirect rule of a link
+// @Schemes
+// @Description Update a redirect rule of a link.
+// @Tags Rules
+// @Accept json
+// @Produce json
+// @Param id path string true "Link ID"
+// @Param ruleId path string true "Rule ID"
+// @Param body body domain.RedirectRule true "Body"
+// @Success 200 {object} domain.RedirectRule
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/links/{id}/rules/{ruleId} [PUT]
+func (h *rulesHandler) UpdateRule() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		linkId, ok := h.parseLinkId(ctx, domain.RoleEditor)
+		if !ok {
+			return
+		}
+		ruleId, err := uuid.Parse(ctx.Param("ruleId"))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid rule ID provided")
+			return
+		}
+		var request domain.RedirectRule
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid request body provided")
+			return
+		}
+		request.ID = ruleId
+		response, err := h.s.Update(linkId, request)
+		if err != nil {
+			if err.Error() == "record not found" {
+				web.BadResponse(ctx, http.StatusNotFound, "error", "rule not found")
+				return
+			}
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+		web.ResponseOK(ctx, http.StatusOK, response)
+	}
+}
+
+// DeleteRule deletes a redirect rule of a link.
+// @BasePath /api/v1
+// DeleteRule godoc
+// @Summary Delete a redirect rule of a link
+// @Schemes
+// @Description Delete a redirect rule of a link.
+// @Tags Rules
+// @Accept json
+// @Produce json
+// @Param id path string true "Link ID"
+// @Param ruleId path string true "Rule ID"
+// @Success 204
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/links/{id}/rules/{ruleId} [DELETE]
+func (h *rulesHandler) DeleteRule() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		linkId, ok := h.parseLinkId(ctx, domain.RoleEditor)
+		if !ok {
+			return
+		}
+		ruleId, err := uuid.Parse(ctx.Param("ruleId"))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid rule ID provided")
+			return
+		}
+		if err := h.s.Delete(linkId, ruleId); err != nil {
+			log.Printf("ERROR: unable to delete the redirect rule `%s` due to %v", ruleId, err.Error())
+			web.BadResponse(ctx, http.StatusNotFound, "error", "rule not found")
+			return
+		}
+		web.ResponseOK(ctx, http.StatusNoContent, nil)
+	}
+}
+
+// parseLinkId parses the link ID path param and checks the link belongs to the principal, or to a workspace where
+// the principal has the role.
+func (h *rulesHandler) parseLinkId(ctx *gin.Context, role string) (uuid.UUID, bool) {
+	linkId, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid link ID provided")
+		return uuid.Nil, false
+	}
+	if _, err := h.ls.GetAuthorizedLink(middleware.GetPrincipal(ctx).UserId, linkId, role); err != nil {
+		if rejectForbidden(ctx, err) {
+			return uuid.Nil, false
+		}
+		web.BadResponse(ctx, http.StatusNotFound, "error", "link not found")
+		return uuid.Nil, false
+	}
+	return linkId, true
+}
@@ -30,14 +30,62 @@ func NewStatsHandler(s stats.Service) *statsHandler {
 // @Accept json
 // @Produce json
 // @Param userId path string true "User ID"
+// @Param includeBots query bool false "Include bot visits"
+// @Param startDate query string false "Start Date of the unique visitors"
+// @Param endDate query string false "End Date of the unique visitors"
 // @Success 200 {object} web.StatsOverview
+// @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Failure 503 {object} web.errorResponse
 // @Router /api/v1/stats/user/{userId}/overview [GET]
 func (h *statsHandler) GetUserStatsOverview() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		userID := ctx.Param("userId")
-		response, err := h.s.GetUserStatsOverview(userID)
+		filter, ok := parseStatsFilter(ctx)
+		if !ok {
+			return
+		}
+		response, err := h.s.GetUserStatsOverview(userID, filter)
+		if err != nil {
+			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+			return
+		}
+		web.ResponseOK(ctx, http.StatusOK, response)
+	}
+}
+
+// GetWorkspaceStatsOverview returns a summary of the workspace links and pages stats.
+// @BasePath /api/v1
+// GetWorkspaceStatsOverview godoc
+// @Summary Returns a summary of the workspace links and pages stats.
+// @Schemes
+// @Description Returns a summary of the links and pages stats of a workspace the user is a member of.
+// @Tags Stats
+// @Accept json
+// @Produce json
+// @Param workspaceId path string true "Workspace ID"
+// @Param includeBots query bool false "Include bot visits"
+// @Param startDate query string false "Start Date of the unique visitors"
+// @Param endDate query string false "End Date of the unique visitors"
+// @Success 200 {object} web.StatsOverview
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Failure 503 {object} web.errorResponse
+// @Router /api/v1/stats/workspace/{workspaceId}/overview [GET]
+func (h *statsHandler) GetWorkspaceStatsOverview() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		workspaceId, err := uuid.Parse(ctx.Param("workspaceId"))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid workspace ID provided")
+			return
+		}
+		filter, ok := parseStatsFilter(ctx)
+		if !ok {
+			return
+		}
+		response, err := h.s.GetWorkspaceStatsOverview(workspaceId, filter)
 		if err != nil {
 			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
 			return
@@ -56,11 +104,13 @@ func (h *statsHandler) GetUserStatsOverview() gin.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param userId path string true "User ID"
+// @Param includeBots query bool false "Include bot visits"
 // @Param pageSize query int true "Page Size"
 // @Param pageNumber query int true "Page Number"
 // @Param sort query string false "Sort"
 // @Success 200 {object} web.Pagination
 // @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Failure 503 {object} web.errorResponse
 // @Router /api/v1/stats/user/{userId} [GET]
 func (h *statsHandler) GetStatsByUserId() gin.HandlerFunc {
@@ -89,7 +139,7 @@ func (h *statsHandler) GetStatsByUserId() gin.HandlerFunc {
 			PageNumber: pageNumber,
 			Sort:       pageSort,
 		}
-		response, err := h.s.GetStatsByUserId(pagination, userId)
+		response, err := h.s.GetStatsByUserId(pagination, userId, ctx.Query("includeBots") == "true")
 		if err != nil {
 			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
 			return
@@ -113,16 +163,31 @@ func (h *statsHandler) GetStatsByUserId() gin.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param linkId path string true "Link ID"
+// @Param includeBots query bool false "Include bot visits"
 // @Param pageSize query int true "Page Size"
 // @Param pageNumber query int true "Page Number"
 // @Param sort query string false "Sort"
-// @Success 200 {object} web.Pagination
+// @Param startDate query string false "Start Date of the total and unique clicks"
+// @Param endDate query string false "End Date of the total and unique clicks"
+// @Success 200 {object} web.StatsPage
+// @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
 // @Failure 503 {object} web.errorResponse
 // @Router /api/v1/stats/link/{linkId} [GET]
 func (h *statsHandler) GetLinkStats() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		linkID := ctx.Param("linkId")
+		linkUUID, err := uuid.Parse(linkID)
+		if err != nil {
+			log.Printf("ERROR: unable to convert linkId to uuid: %v", err.Error())
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid link ID provided")
+			return
+		}
+		filter, ok := parseStatsFilter(ctx)
+		if !ok {
+			return
+		}
 		pageSize, err := strconv.Atoi(ctx.Query("pageSize"))
 		if err != nil {
 			log.Printf("ERROR: unable to convert pageSize to int: %v", err.Error())
@@ -146,12 +211,17 @@ func (h *statsHandler) GetLinkStats() gin.HandlerFunc {
 			PageNumber: pageNumber,
 			Sort:       pageSort,
 		}
-		response, err := h.s.GetLinkStats(pagination, linkID)
+		response, err := h.s.GetLinkStats(pagination, linkID, filter.IncludeBots)
 		if err != nil {
 			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
 			return
 		}
-		web.ResponseOK(ctx, http.StatusOK, response)
+		visitors, err := h.s.GetLinkVisitors(linkUUID, filter)
+		if err != nil {
+			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+			return
+		}
+		web.ResponseOK(ctx, http.StatusOK, web.StatsPage{Pagination: response, Total: visitors.Total, Unique: visitors.Unique})
 	}
 }
 
@@ -165,16 +235,31 @@ func (h *statsHandler) GetLinkStats() gin.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param pageId path string true "Page ID"
+// @Param includeBots query bool false "Include bot visits"
 // @Param pageSize query int true "Page Size"
 // @Param pageNumber query int true "Page Number"
 // @Param sort query string false "Sort"
-// @Success 200 {object} web.Pagination
+// @Param startDate query string false "Start Date of the total and unique views"
+// @Param endDate query string false "End Date of the total and unique views"
+// @Success 200 {object} web.StatsPage
+// @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
 // @Failure 503 {object} web.errorResponse
 // @Router /api/v1/stats/page/{pageId} [GET]
 func (h *statsHandler) GetPageStats() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		pageId := c.Param("pageId")
+		pageUUID, err := uuid.Parse(pageId)
+		if err != nil {
+			log.Printf("ERROR: unable to convert pageId to uuid: %v", err.Error())
+			web.BadResponse(c, http.StatusBadRequest, "error", "invalid page ID provided")
+			return
+		}
+		filter, ok := parseStatsFilter(c)
+		if !ok {
+			return
+		}
 		pageSize, err := strconv.Atoi(c.Query("pageSize"))
 		if err != nil {
 			log.Printf("ERROR: unable to convert pageSize to int: %v", err.Error())
@@ -198,13 +283,18 @@ func (h *statsHandler) GetPageStats() gin.HandlerFunc {
 			PageNumber: pageNumber,
 			Sort:       pageSort,
 		}
-		response, err := h.s.GetPageStats(pagination, pageId)
+		response, err := h.s.GetPageStats(pagination, pageId, filter.IncludeBots)
+		if err != nil {
+			web.BadResponse(c, http.StatusInternalServerError, "error", err.Error())
+			return
+		}
+		visitors, err := h.s.GetPageVisitors(pageUUID, filter)
 		if err != nil {
 			web.BadResponse(c, http.StatusInternalServerError, "error", err.Error())
 			return
 		}
 
-		web.ResponseOK(c, http.StatusOK, response)
+		web.ResponseOK(c, http.StatusOK, web.StatsPage{Pagination: response, Total: visitors.Total, Unique: visitors.Unique})
 	}
 }
 
@@ -218,11 +308,16 @@ func (h *statsHandler) GetPageStats() gin.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param linkId path string true "Link ID"
+// @Param includeBots query bool false "Include bot visits"
 // @Param startDate query string false "Start Date"
 // @Param endDate query string false "End Date"
+// @Param groupBy query string false "Comma separated dimensions: os, browser, device, country, region, referrer, language, target"
+// @Param granularity query string false "Bucket size: hour, day, week or month"
+// @Param tz query string false "IANA time zone of the dates and buckets"
 // @Success 200 {object} []web.StatsByDate
 // @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
 // @Failure 503 {object} web.errorResponse
 // @Router /api/v1/stats/link/{linkId}/date [GET]
 func (h *statsHandler) GetLinkStatsByDate() gin.HandlerFunc {
@@ -233,35 +328,13 @@ func (h *statsHandler) GetLinkStatsByDate() gin.HandlerFunc {
 			web.BadResponse(c, http.StatusBadRequest, "error", "invalid link ID provided")
 			return
 		}
-		if c.Query("startDate") == "" && c.Query("endDate") == "" {
-			endDate := time.Now()
-			startDate := endDate.AddDate(0, 0, -30)
-			response, err := h.s.GetLinkStatsByDate(linkId, startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
-			if err != nil {
-				log.Printf("ERROR: unable to get stats by date: %v", err.Error())
-				web.BadResponse(c, http.StatusInternalServerError, "error", err.Error())
-				return
-			}
-			web.ResponseOK(c, http.StatusOK, response)
+		filter, ok := parseStatsFilter(c)
+		if !ok {
 			return
 		}
-
-		startDate, err := time.Parse(time.DateOnly, c.Query("startDate"))
-		if err != nil {
-			log.Printf("ERROR: unable to convert startDate to time: %v", err.Error())
-			web.BadResponse(c, http.StatusBadRequest, "error", "invalid startDate value")
-			return
-		}
-
-		endDate, err := time.Parse(time.DateOnly, c.Query("endDate"))
-		if err != nil {
-			log.Printf("ERROR: unable to convert endDate to time: %v", err.Error())
-			web.BadResponse(c, http.StatusBadRequest, "error", "invalid endDate value")
-			return
-		}
-
-		response, err := h.s.GetLinkStatsByDate(linkId, startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
+		response, err := h.s.GetLinkStatsByDate(linkId, filter)
 		if err != nil {
+			log.Printf("ERROR: unable to get stats by date: %v", err.Error())
 			web.BadResponse(c, http.StatusInternalServerError, "error", err.Error())
 			return
 		}
@@ -279,11 +352,16 @@ func (h *statsHandler) GetLinkStatsByDate() gin.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param linkId path string true "Link ID"
+// @Param includeBots query bool false "Include bot visits"
 // @Param startDate query string false "Start Date"
 // @Param endDate query string false "End Date"
+// @Param groupBy query string false "Comma separated dimensions: os, browser, device, country, region, referrer, language, target"
+// @Param granularity query string false "Bucket size: hour, day, week or month"
+// @Param tz query string false "IANA time zone of the dates and buckets"
 // @Success 200 {object} []web.StatsByDate
 // @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
 // @Failure 503 {object} web.errorResponse
 // @Router /api/v1/stats/page/{pageId}/date [GET]
 func (h *statsHandler) GetPageStatsByDate() gin.HandlerFunc {
@@ -294,35 +372,13 @@ func (h *statsHandler) GetPageStatsByDate() gin.HandlerFunc {
 			web.BadResponse(c, http.StatusBadRequest, "error", "invalid page ID provided")
 			return
 		}
-		if c.Query("startDate") == "" && c.Query("endDate") == "" {
-			endDate := time.Now()
-			startDate := endDate.AddDate(0, 0, -30)
-			response, err := h.s.GetPageStatsByDate(linkId, startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
-			if err != nil {
-				log.Printf("ERROR: unable to get stats by date: %v", err.Error())
-				web.BadResponse(c, http.StatusInternalServerError, "error", err.Error())
-				return
-			}
-			web.ResponseOK(c, http.StatusOK, response)
+		filter, ok := parseStatsFilter(c)
+		if !ok {
 			return
 		}
-
-		startDate, err := time.Parse(time.DateOnly, c.Query("startDate"))
-		if err != nil {
-			log.Printf("ERROR: unable to convert startDate to time: %v", err.Error())
-			web.BadResponse(c, http.StatusBadRequest, "error", "invalid startDate value")
-			return
-		}
-
-		endDate, err := time.Parse(time.DateOnly, c.Query("endDate"))
-		if err != nil {
-			log.Printf("ERROR: unable to convert endDate to time: %v", err.Error())
-			web.BadResponse(c, http.StatusBadRequest, "error", "invalid endDate value")
-			return
-		}
-
-		response, err := h.s.GetPageStatsByDate(linkId, startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
+		response, err := h.s.GetPageStatsByDate(linkId, filter)
 		if err != nil {
+			log.Printf("ERROR: unable to get stats by date: %v", err.Error())
 			web.BadResponse(c, http.StatusInternalServerError, "error", err.Error())
 			return
 		}
@@ -340,45 +396,28 @@ func (h *statsHandler) GetPageStatsByDate() gin.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param userId path string true "User ID"
+// @Param includeBots query bool false "Include bot visits"
 // @Param startDate query string false "Start Date"
 // @Param endDate query string false "End Date"
+// @Param groupBy query string false "Comma separated dimensions: os, browser, device, country, region, referrer, language, target"
+// @Param granularity query string false "Bucket size: hour, day, week or month"
+// @Param tz query string false "IANA time zone of the dates and buckets"
 // @Success 200 {object} []web.StatsByDate
 // @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Failure 503 {object} web.errorResponse
 // @Router /api/v1/stats/link/user/{userId}/links [GET]
 func (h *statsHandler) GetLinkStatsByUserIdAndDate() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userId := c.Param("userId")
-		if c.Query("startDate") == "" && c.Query("endDate") == "" {
-			endDate := time.Now()
-			startDate := endDate.AddDate(0, 0, -30)
-			response, err := h.s.GetLinkStatsByUserIdAndDate(userId, startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
-			if err != nil {
-				log.Printf("ERROR: unable to get stats by date: %v", err.Error())
-				web.BadResponse(c, http.StatusInternalServerError, "error", err.Error())
-				return
-			}
-			web.ResponseOK(c, http.StatusOK, response)
-			return
-		}
-
-		startDate, err := time.Parse(time.DateOnly, c.Query("startDate"))
-		if err != nil {
-			log.Printf("ERROR: unable to convert startDate to time: %v", err.Error())
-			web.BadResponse(c, http.StatusBadRequest, "error", "invalid startDate value")
-			return
-		}
-
-		endDate, err := time.Parse(time.DateOnly, c.Query("endDate"))
-		if err != nil {
-			log.Printf("ERROR: unable to convert endDate to time: %v", err.Error())
-			web.BadResponse(c, http.StatusBadRequest, "error", "invalid endDate value")
+		filter, ok := parseStatsFilter(c)
+		if !ok {
 			return
 		}
-
-		response, err := h.s.GetLinkStatsByUserIdAndDate(userId, startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
+		response, err := h.s.GetLinkStatsByUserIdAndDate(userId, filter)
 		if err != nil {
+			log.Printf("ERROR: unable to get stats by date: %v", err.Error())
 			web.BadResponse(c, http.StatusInternalServerError, "error", err.Error())
 			return
 		}
@@ -396,48 +435,204 @@ func (h *statsHandler) GetLinkStatsByUserIdAndDate() gin.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param userId path string true "User ID"
+// @Param includeBots query bool false "Include bot visits"
 // @Param startDate query string false "Start Date"
 // @Param endDate query string false "End Date"
+// @Param groupBy query string false "Comma separated dimensions: os, browser, device, country, region, referrer, language, target"
+// @Param granularity query string false "Bucket size: hour, day, week or month"
+// @Param tz query string false "IANA time zone of the dates and buckets"
 // @Success 200 {object} []web.StatsByDate
 // @Failure 400 {object} web.errorResponse
 // @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
 // @Failure 503 {object} web.errorResponse
 // @Router /api/v1/stats/user/{userId}/pages [GET]
 func (h *statsHandler) GetPageStatsByUserIdAndDate() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userId := c.Param("userId")
-		if c.Query("startDate") == "" && c.Query("endDate") == "" {
-			endDate := time.Now()
-			startDate := endDate.AddDate(0, 0, -30)
-			response, err := h.s.GetPageStatsByUserIdAndDate(userId, startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
-			if err != nil {
-				log.Printf("ERROR: unable to get stats by date: %v", err.Error())
-				web.BadResponse(c, http.StatusInternalServerError, "error", err.Error())
-				return
-			}
-			web.ResponseOK(c, http.StatusOK, response)
+		filter, ok := parseStatsFilter(c)
+		if !ok {
 			return
 		}
+		response, err := h.s.GetPageStatsByUserIdAndDate(userId, filter)
+		if err != nil {
+			log.Printf("ERROR: unable to get stats by date: %v", err.Error())
+			web.BadResponse(c, http.StatusInternalServerError, "error", err.Error())
+			return
+		}
+		web.ResponseOK(c, http.StatusOK, response)
+	}
+}
 
-		startDate, err := time.Parse(time.DateOnly, c.Query("startDate"))
+// GetLinkStatsByVariant returns the clicks of each variant of a split link, by default the last 30 days.
+// @BasePath /api/v1
+// GetLinkStatsByVariant godoc
+// @Summary Returns the clicks of each variant of a split link, by default the last 30 days.
+// @Schemes
+// @Description Returns the clicks of each variant of a split link, by default the last 30 days.
+// @Tags Stats
+// @Accept json
+// @Produce json
+// @Param linkId path string true "Link ID"
+// @Param includeBots query bool false "Include bot visits"
+// @Param startDate query string false "Start Date"
+// @Param endDate query string false "End Date"
+// @Success 200 {object} []web.VariantStats
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Failure 503 {object} web.errorResponse
+// @Router /api/v1/stats/link/{linkId}/variants [GET]
+func (h *statsHandler) GetLinkStatsByVariant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		linkId, err := uuid.Parse(c.Param("linkId"))
 		if err != nil {
-			log.Printf("ERROR: unable to convert startDate to time: %v", err.Error())
-			web.BadResponse(c, http.StatusBadRequest, "error", "invalid startDate value")
+			log.Printf("ERROR: unable to convert linkId to uuid: %v", err.Error())
+			web.BadResponse(c, http.StatusBadRequest, "error", "invalid link ID provided")
+			return
+		}
+		filter, ok := parseStatsFilter(c)
+		if !ok {
 			return
 		}
+		response, err := h.s.GetLinkStatsByVariant(linkId, filter)
+		if err != nil {
+			log.Printf("ERROR: unable to get stats by variant: %v", err.Error())
+			web.BadResponse(c, http.StatusInternalServerError, "error", err.Error())
+			return
+		}
+		web.ResponseOK(c, http.StatusOK, response)
+	}
+}
 
-		endDate, err := time.Parse(time.DateOnly, c.Query("endDate"))
+// GetLinkVisitors returns the total and unique clicks of a link for each day, by default the last 30 days.
+// @BasePath /api/v1
+// GetLinkVisitors godoc
+// @Summary Returns the total and unique clicks of a link for each day, by default the last 30 days.
+// @Schemes
+// @Description Returns the total and estimated unique clicks of a link for each day and the whole date range. Unique visitors never include bots.
+// @Tags Stats
+// @Accept json
+// @Produce json
+// @Param linkId path string true "Link ID"
+// @Param includeBots query bool false "Include bot visits in the totals"
+// @Param startDate query string false "Start Date"
+// @Param endDate query string false "End Date"
+// @Success 200 {object} web.VisitorStats
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Failure 503 {object} web.errorResponse
+// @Router /api/v1/stats/link/{linkId}/visitors [GET]
+func (h *statsHandler) GetLinkVisitors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		linkId, err := uuid.Parse(c.Param("linkId"))
 		if err != nil {
-			log.Printf("ERROR: unable to convert endDate to time: %v", err.Error())
-			web.BadResponse(c, http.StatusBadRequest, "error", "invalid endDate value")
+			log.Printf("ERROR: unable to convert linkId to uuid: %v", err.Error())
+			web.BadResponse(c, http.StatusBadRequest, "error", "invalid link ID provided")
+			return
+		}
+		filter, ok := parseStatsFilter(c)
+		if !ok {
 			return
 		}
+		response, err := h.s.GetLinkVisitors(linkId, filter)
+		if err != nil {
+			log.Printf("ERROR: unable to get link visitors: %v", err.Error())
+			web.BadResponse(c, http.StatusInternalServerError, "error", err.Error())
+			return
+		}
+		web.ResponseOK(c, http.StatusOK, response)
+	}
+}
 
-		response, err := h.s.GetPageStatsByUserIdAndDate(userId, startDate.Format(time.DateOnly), endDate.Format(time.DateOnly))
+// GetPageVisitors returns the total and unique views of a page for each day, by default the last 30 days.
+// @BasePath /api/v1
+// GetPageVisitors godoc
+// @Summary Returns the total and unique views of a page for each day, by default the last 30 days.
+// @Schemes
+// @Description Returns the total and estimated unique views of a page for each day and the whole date range. Unique visitors never include bots.
+// @Tags Stats
+// @Accept json
+// @Produce json
+// @Param pageId path string true "Page ID"
+// @Param includeBots query bool false "Include bot visits in the totals"
+// @Param startDate query string false "Start Date"
+// @Param endDate query string false "End Date"
+// @Success 200 {object} web.VisitorStats
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Failure 503 {object} web.errorResponse
+// @Router /api/v1/stats/page/{pageId}/visitors [GET]
+func (h *statsHandler) GetPageVisitors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pageId, err := uuid.Parse(c.Param("pageId"))
+		if err != nil {
+			log.Printf("ERROR: unable to convert pageId to uuid: %v", err.Error())
+			web.BadResponse(c, http.StatusBadRequest, "error", "invalid page ID provided")
+			return
+		}
+		filter, ok := parseStatsFilter(c)
+		if !ok {
+			return
+		}
+		response, err := h.s.GetPageVisitors(pageId, filter)
 		if err != nil {
+			log.Printf("ERROR: unable to get page visitors: %v", err.Error())
 			web.BadResponse(c, http.StatusInternalServerError, "error", err.Error())
 			return
 		}
 		web.ResponseOK(c, http.StatusOK, response)
 	}
 }
+
+// parseStatsFilter reads the startDate, endDate, includeBots, groupBy, granularity and tz query params, by default
+// the last 30 days in UTC without bot visits grouped by day, OS and browser. The request is answered when a param
+// is invalid.
+func parseStatsFilter(c *gin.Context) (web.StatsFilter, bool) {
+	groupBy, err := stats.ParseGroupBy(c.Query("groupBy"))
+	if err != nil {
+		web.BadResponse(c, http.StatusBadRequest, "error", err.Error())
+		return web.StatsFilter{}, false
+	}
+	granularity, err := stats.ParseGranularity(c.Query("granularity"))
+	if err != nil {
+		web.BadResponse(c, http.StatusBadRequest, "error", err.Error())
+		return web.StatsFilter{}, false
+	}
+	location, err := stats.ParseLocation(c.Query("tz"))
+	if err != nil {
+		web.BadResponse(c, http.StatusBadRequest, "error", err.Error())
+		return web.StatsFilter{}, false
+	}
+	filter := web.StatsFilter{
+		IncludeBots: c.Query("includeBots") == "true",
+		GroupBy:     groupBy,
+		Granularity: granularity,
+		Location:    location,
+	}
+	if c.Query("startDate") == "" && c.Query("endDate") == "" {
+		endDate := time.Now().In(location)
+		filter.StartDate = endDate.AddDate(0, 0, -30).Format(time.DateOnly)
+		filter.EndDate = endDate.Format(time.DateOnly)
+		return filter, true
+	}
+
+	startDate, err := time.Parse(time.DateOnly, c.Query("startDate"))
+	if err != nil {
+		log.Printf("ERROR: unable to convert startDate to time: %v", err.Error())
+		web.BadResponse(c, http.StatusBadRequest, "error", "invalid startDate value")
+		return web.StatsFilter{}, false
+	}
+
+	endDate, err := time.Parse(time.DateOnly, c.Query("endDate"))
+	if err != nil {
+		log.Printf("ERROR: unable to convert endDate to time: %v", err.Error())
+		web.BadResponse(c, http.StatusBadRequest, "error", "invalid endDate value")
+		return web.StatsFilter{}, false
+	}
+	filter.StartDate = startDate.Format(time.DateOnly)
+	filter.EndDate = endDate.Format(time.DateOnly)
+	return filter, true
+}
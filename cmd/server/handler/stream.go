@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/stats"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"golang.org/x/net/websocket"
+	"io"
+	"net/http"
+	"time"
+)
+
+// streamHeartbeat is the interval of the comments keeping idle event streams open through proxies.
+const streamHeartbeat = time.Second * 15
+
+// StreamUserStats pushes the clicks and views of the user links and pages as Server-Sent Events.
+// @BasePath /api/v1
+// StreamUserStats godoc
+// @Summary Streams the clicks and views of the user links and pages.
+// @Schemes
+// @Description Pushes a `click` or `view` Server-Sent Event for every visit to the user links and pages as it is registered. Browsers may pass the token in the access_token query param.
+// @Tags Stats
+// @Produce text/event-stream
+// @Param userId path string true "User ID"
+// @Param includeBots query bool false "Include bot visits"
+// @Success 200 {object} stats.Event
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Router /api/v1/stats/user/{userId}/stream [GET]
+func (h *statsHandler) StreamUserStats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId := c.Param("userId")
+		serveEventStream(c, func() *stats.Subscription { return h.s.Subscribe(userId) })
+	}
+}
+
+// StreamUserStatsWebSocket pushes the clicks and views of the user links and pages as WebSocket JSON messages.
+// @BasePath /api/v1
+// StreamUserStatsWebSocket godoc
+// @Summary Streams the clicks and views of the user links and pages over a WebSocket.
+// @Schemes
+// @Description Sends a JSON message for every visit to the user links and pages as it is registered. Browsers may pass the token in the access_token query param.
+// @Tags Stats
+// @Param userId path string true "User ID"
+// @Param includeBots query bool false "Include bot visits"
+// @Success 101 {object} stats.Event
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Router /api/v1/stats/user/{userId}/ws [GET]
+func (h *statsHandler) StreamUserStatsWebSocket() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId := c.Param("userId")
+		serveWebSocket(c, func() *stats.Subscription { return h.s.Subscribe(userId) })
+	}
+}
+
+// StreamWorkspaceStats pushes the clicks and views of the workspace links and pages as Server-Sent Events.
+// @BasePath /api/v1
+// StreamWorkspaceStats godoc
+// @Summary Streams the clicks and views of the workspace links and pages.
+// @Schemes
+// @Description Pushes a `click` or `view` Server-Sent Event for every visit to the links and pages of a workspace the user is a member of. Browsers may pass the token in the access_token query param.
+// @Tags Stats
+// @Produce text/event-stream
+// @Param workspaceId path string true "Workspace ID"
+// @Param includeBots query bool false "Include bot visits"
+// @Success 200 {object} stats.Event
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/stats/workspace/{workspaceId}/stream [GET]
+func (h *statsHandler) StreamWorkspaceStats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		workspaceId, ok := parseWorkspaceId(c)
+		if !ok {
+			return
+		}
+		serveEventStream(c, func() *stats.Subscription { return h.s.SubscribeWorkspace(workspaceId) })
+	}
+}
+
+// StreamWorkspaceStatsWebSocket pushes the clicks and views of the workspace links and pages as WebSocket JSON messages.
+// @BasePath /api/v1
+// StreamWorkspaceStatsWebSocket godoc
+// @Summary Streams the clicks and views of the workspace links and pages over a WebSocket.
+// @Schemes
+// @Description Sends a JSON message for every visit to the links and pages of a workspace the user is a member of. Browsers may pass the token in the access_token query param.
+// @Tags Stats
+// @Param workspaceId path string true "Workspace ID"
+// @Param includeBots query bool false "Include bot visits"
+// @Success 101 {object} stats.Event
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/stats/workspace/{workspaceId}/ws [GET]
+func (h *statsHandler) StreamWorkspaceStatsWebSocket() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		workspaceId, ok := parseWorkspaceId(c)
+		if !ok {
+			return
+		}
+		serveWebSocket(c, func() *stats.Subscription { return h.s.SubscribeWorkspace(workspaceId) })
+	}
+}
+
+// parseWorkspaceId reads the workspace ID path param, answering the request when it is not valid.
+func parseWorkspaceId(c *gin.Context) (uuid.UUID, bool) {
+	workspaceId, err := uuid.Parse(c.Param("workspaceId"))
+	if err != nil {
+		web.BadResponse(c, http.StatusBadRequest, "error", "invalid workspace ID provided")
+		return uuid.Nil, false
+	}
+	return workspaceId, true
+}
+
+// serveEventStream pushes the events of the subscription as Server-Sent Events until the client goes away.
+func serveEventStream(c *gin.Context, subscribe func() *stats.Subscription) {
+	includeBots := c.Query("includeBots") == "true"
+	subscription := subscribe()
+	defer subscription.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return false
+			}
+			if event.Stats.IsBot && !includeBots {
+				return true
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		}
+	})
+}
+
+// serveWebSocket sends the events of the subscription as WebSocket JSON messages until the client goes away.
+func serveWebSocket(c *gin.Context, subscribe func() *stats.Subscription) {
+	includeBots := c.Query("includeBots") == "true"
+	server := websocket.Server{
+		// The connection is authenticated by the token, not by cookies, so any origin is accepted
+		Handshake: func(config *websocket.Config, req *http.Request) (err error) {
+			config.Origin, err = websocket.Origin(config, req)
+			return err
+		},
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			subscription := subscribe()
+			defer subscription.Close()
+
+			// Messages from the client are discarded, reading only detects when it goes away
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				io.Copy(io.Discard, ws)
+			}()
+			for {
+				select {
+				case <-closed:
+					return
+				case event, ok := <-subscription.Events:
+					if !ok {
+						return
+					}
+					if event.Stats.IsBot && !includeBots {
+						continue
+					}
+					if err := websocket.JSON.Send(ws, event); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/rules"
+	"github.com/ronilsonalves/5lnk/internal/stats"
+	"github.com/ronilsonalves/5lnk/pkg/crawler"
+	"github.com/ronilsonalves/5lnk/pkg/geoip"
+	"github.com/ronilsonalves/5lnk/pkg/middleware"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxLanguageLength is the maximum length of a language tag.
+const maxLanguageLength = 35
+
+// newVisit returns the stats of the current request, describing the visitor without its link or page.
+func newVisit(ctx *gin.Context, ua middleware.FormattedUserAgent, geo *geoip.DB, bots *crawler.Ranges) domain.Stats {
+	now := time.Now()
+	address := middleware.VisitorAddress(ctx)
+	location := geo.Lookup(address)
+	var language string
+	if languages := rules.ParseAcceptLanguage(ctx.GetHeader("Accept-Language")); len(languages) > 0 {
+		language = languages[0]
+	}
+	if len(language) > maxLanguageLength {
+		language = language[:maxLanguageLength]
+	}
+	return domain.Stats{
+		Timestamp:   now,
+		Os:          ua.OS,
+		Browser:     ua.Browser,
+		IsBot:       ua.Bot || bots.Contains(address),
+		Referrer:    referrerHost(ctx.Request.Referer()),
+		Device:      ua.Device,
+		Language:    language,
+		Country:     location.Country,
+		Region:      location.Region,
+		VisitorHash: stats.Fingerprint(now, address, ctx.Request.UserAgent()),
+	}
+}
+
+// referrerHost returns the host of the referrer URL, without the www prefix.
+func referrerHost(referrer string) string {
+	parsed, err := url.Parse(referrer)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
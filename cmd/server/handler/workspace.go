@@ -0,0 +1,418 @@
+package handler
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/workspace"
+	"github.com/ronilsonalves/5lnk/pkg/middleware"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"net/http"
+)
+
+type workspaceHandler struct {
+	s workspace.Service
+}
+
+func NewWorkspaceHandler(s workspace.Service) *workspaceHandler {
+	return &workspaceHandler{
+		s: s,
+	}
+}
+
+// AuthorizeWorkspace is a middleware that only lets the principal reach the workspace of the path param when their
+// role in it grants the role. It runs before the cached handlers, as the cache is shared by every user.
+func (h *workspaceHandler) AuthorizeWorkspace(param string, role string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		workspaceId, err := uuid.Parse(ctx.Param(param))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid workspace ID provided")
+			return
+		}
+		if _, err := h.s.Authorize(workspaceId, middleware.GetPrincipal(ctx).UserId, role); err != nil {
+			if rejectForbidden(ctx, err) {
+				return
+			}
+			if err.Error() == "record not found" {
+				web.BadResponse(ctx, http.StatusNotFound, "error", fmt.Errorf("the workspace `%s` not found", workspaceId).Error())
+				return
+			}
+			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// PostWorkspace create and return a new workspace.
+// @BasePath /api/v1
+// PostWorkspace godoc
+// @Summary Create a new workspace
+// @Schemes
+// @Description Create a new workspace owned by the user, to share links and pages with its members.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param body body web.CreateWorkspace true "Body"
+// @Success 201 {object} domain.Workspace
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Router /api/v1/workspaces [POST]
+func (h *workspaceHandler) PostWorkspace() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var request web.CreateWorkspace
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "the name of the workspace is required")
+			return
+		}
+		response, err := h.s.Create(middleware.GetPrincipal(ctx).UserId, request)
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+
+		web.ResponseOK(ctx, http.StatusCreated, response)
+	}
+}
+
+// GetAllWorkspacesByUser returns the workspaces of a user.
+// @BasePath /api/v1
+// GetAllWorkspacesByUser godoc
+// @Summary Get all workspaces by user
+// @Schemes
+// @Description Get all workspaces the user is a member of.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param userId path string true "User ID"
+// @Success 200 {object} []domain.Workspace
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 500 {object} web.errorResponse
+// @Router /api/v1/workspaces/user/{userId} [GET]
+func (h *workspaceHandler) GetAllWorkspacesByUser() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		response, err := h.s.GetAllByUser(ctx.Param("userId"))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusInternalServerError, "error", "an error occurred while retrieving the workspaces")
+			return
+		}
+
+		web.ResponseOK(ctx, http.StatusOK, response)
+	}
+}
+
+// GetWorkspace returns a workspace with its members.
+// @BasePath /api/v1
+// GetWorkspace godoc
+// @Summary Get a workspace
+// @Schemes
+// @Description Get a workspace the user is a member of, with its members and their roles.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param workspaceId path string true "Workspace ID"
+// @Success 200 {object} domain.Workspace
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/workspaces/{workspaceId} [GET]
+func (h *workspaceHandler) GetWorkspace() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		workspaceId := uuid.MustParse(ctx.Param("workspaceId"))
+		response, err := h.s.GetWorkspace(workspaceId)
+		if err != nil {
+			if err.Error() == "record not found" {
+				web.BadResponse(ctx, http.StatusNotFound, "error", fmt.Errorf("the workspace `%s` not found", workspaceId).Error())
+				return
+			}
+			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+			return
+		}
+
+		web.ResponseOK(ctx, http.StatusOK, response)
+	}
+}
+
+// UpdateWorkspace renames a workspace.
+// @BasePath /api/v1
+// UpdateWorkspace godoc
+// @Summary Rename a workspace
+// @Schemes
+// @Description Rename a workspace, restricted to its admins and owners.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param workspaceId path string true "Workspace ID"
+// @Param body body web.CreateWorkspace true "Body"
+// @Success 200 {object} domain.Workspace
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/workspaces/{workspaceId} [PUT]
+func (h *workspaceHandler) UpdateWorkspace() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var request web.CreateWorkspace
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "the name of the workspace is required")
+			return
+		}
+		response, err := h.s.Update(uuid.MustParse(ctx.Param("workspaceId")), request)
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+
+		web.ResponseOK(ctx, http.StatusOK, response)
+	}
+}
+
+// DeleteWorkspace deletes a workspace.
+// @BasePath /api/v1
+// DeleteWorkspace godoc
+// @Summary Delete a workspace
+// @Schemes
+// @Description Delete a workspace without links nor pages, restricted to its owners.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param workspaceId path string true "Workspace ID"
+// @Success 204
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/workspaces/{workspaceId} [DELETE]
+func (h *workspaceHandler) DeleteWorkspace() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if err := h.s.Delete(uuid.MustParse(ctx.Param("workspaceId"))); err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+
+		web.ResponseOK(ctx, http.StatusNoContent, nil)
+	}
+}
+
+// UpdateMember changes the role of a workspace member.
+// @BasePath /api/v1
+// UpdateMember godoc
+// @Summary Change the role of a workspace member
+// @Schemes
+// @Description Change the role (owner, admin, editor or viewer) of a member. Admins manage the editors and viewers,
+// @Description owners manage every member, and the last owner can't be demoted.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param workspaceId path string true "Workspace ID"
+// @Param userId path string true "User ID"
+// @Param body body web.UpdateMember true "Body"
+// @Success 200 {object} domain.WorkspaceMember
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/workspaces/{workspaceId}/members/{userId} [PUT]
+func (h *workspaceHandler) UpdateMember() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var request web.UpdateMember
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "the role of the member is required")
+			return
+		}
+		userId := ctx.Param("userId")
+		response, err := h.s.SetMemberRole(uuid.MustParse(ctx.Param("workspaceId")), middleware.GetPrincipal(ctx).UserId, userId, request.Role)
+		if err != nil {
+			if rejectForbidden(ctx, err) {
+				return
+			}
+			if err.Error() == "record not found" {
+				web.BadResponse(ctx, http.StatusNotFound, "error", fmt.Errorf("the member `%s` not found", userId).Error())
+				return
+			}
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+
+		web.ResponseOK(ctx, http.StatusOK, response)
+	}
+}
+
+// RemoveMember removes a member from a workspace.
+// @BasePath /api/v1
+// RemoveMember godoc
+// @Summary Remove a workspace member
+// @Schemes
+// @Description Remove a member from a workspace, or leave it when the member is the user. The last owner can't
+// @Description leave nor be removed.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param workspaceId path string true "Workspace ID"
+// @Param userId path string true "User ID"
+// @Success 204
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/workspaces/{workspaceId}/members/{userId} [DELETE]
+func (h *workspaceHandler) RemoveMember() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userId := ctx.Param("userId")
+		if err := h.s.RemoveMember(uuid.MustParse(ctx.Param("workspaceId")), middleware.GetPrincipal(ctx).UserId, userId); err != nil {
+			if rejectForbidden(ctx, err) {
+				return
+			}
+			if err.Error() == "record not found" {
+				web.BadResponse(ctx, http.StatusNotFound, "error", fmt.Errorf("the member `%s` not found", userId).Error())
+				return
+			}
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+
+		web.ResponseOK(ctx, http.StatusNoContent, nil)
+	}
+}
+
+// PostInvitation create and return a new invitation to a workspace.
+// @BasePath /api/v1
+// PostInvitation godoc
+// @Summary Invite a user to a workspace
+// @Schemes
+// @Description Create an invitation to join a workspace with a role, valid for 7 days. Whoever holds its ID can
+// @Description accept it once, admins can only invite editors and viewers.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param workspaceId path string true "Workspace ID"
+// @Param body body web.CreateInvitation true "Body"
+// @Success 201 {object} domain.WorkspaceInvitation
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/workspaces/{workspaceId}/invitations [POST]
+func (h *workspaceHandler) PostInvitation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var request web.CreateInvitation
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "the role of the invitation is required")
+			return
+		}
+		response, err := h.s.Invite(uuid.MustParse(ctx.Param("workspaceId")), middleware.GetPrincipal(ctx).UserId, request)
+		if err != nil {
+			if rejectForbidden(ctx, err) {
+				return
+			}
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+
+		web.ResponseOK(ctx, http.StatusCreated, response)
+	}
+}
+
+// GetInvitations returns the invitations to a workspace.
+// @BasePath /api/v1
+// GetInvitations godoc
+// @Summary Get the invitations to a workspace
+// @Schemes
+// @Description Get the pending, accepted and expired invitations to a workspace, the latest first.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param workspaceId path string true "Workspace ID"
+// @Success 200 {object} []domain.WorkspaceInvitation
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/workspaces/{workspaceId}/invitations [GET]
+func (h *workspaceHandler) GetInvitations() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		response, err := h.s.GetInvitations(uuid.MustParse(ctx.Param("workspaceId")))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusInternalServerError, "error", "an error occurred while retrieving the invitations")
+			return
+		}
+
+		web.ResponseOK(ctx, http.StatusOK, response)
+	}
+}
+
+// DeleteInvitation revokes an invitation to a workspace.
+// @BasePath /api/v1
+// DeleteInvitation godoc
+// @Summary Revoke an invitation to a workspace
+// @Schemes
+// @Description Revoke an invitation to a workspace.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param workspaceId path string true "Workspace ID"
+// @Param invitationId path string true "Invitation ID"
+// @Success 204
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/workspaces/{workspaceId}/invitations/{invitationId} [DELETE]
+func (h *workspaceHandler) DeleteInvitation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		invitationId, err := uuid.Parse(ctx.Param("invitationId"))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid invitation ID provided")
+			return
+		}
+		if err := h.s.RevokeInvitation(uuid.MustParse(ctx.Param("workspaceId")), invitationId); err != nil {
+			if err.Error() == "record not found" {
+				web.BadResponse(ctx, http.StatusNotFound, "error", fmt.Errorf("the invitation `%s` not found", invitationId).Error())
+				return
+			}
+			web.BadResponse(ctx, http.StatusInternalServerError, "error", err.Error())
+			return
+		}
+
+		web.ResponseOK(ctx, http.StatusNoContent, nil)
+	}
+}
+
+// AcceptInvitation adds the user to the workspace of an invitation.
+// @BasePath /api/v1
+// AcceptInvitation godoc
+// @Summary Accept an invitation to a workspace
+// @Schemes
+// @Description Join the workspace of a pending invitation with the role it was sent with.
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param invitationId path string true "Invitation ID"
+// @Success 200 {object} domain.WorkspaceMember
+// @Failure 400 {object} web.errorResponse
+// @Failure 401 {object} web.errorResponse
+// @Failure 403 {object} web.errorResponse
+// @Failure 404 {object} web.errorResponse
+// @Router /api/v1/workspaces/invitations/{invitationId}/accept [POST]
+func (h *workspaceHandler) AcceptInvitation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		invitationId, err := uuid.Parse(ctx.Param("invitationId"))
+		if err != nil {
+			web.BadResponse(ctx, http.StatusBadRequest, "error", "invalid invitation ID provided")
+			return
+		}
+		response, err := h.s.AcceptInvitation(invitationId, middleware.GetPrincipal(ctx).UserId)
+		if err != nil {
+			if err.Error() == "record not found" {
+				web.BadResponse(ctx, http.StatusNotFound, "error", fmt.Errorf("the invitation `%s` not found", invitationId).Error())
+				return
+			}
+			web.BadResponse(ctx, http.StatusBadRequest, "error", err.Error())
+			return
+		}
+
+		web.ResponseOK(ctx, http.StatusOK, response)
+	}
+}
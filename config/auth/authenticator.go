@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Authenticator verifies the bearer tokens of the users, returning the ID of the user each token was issued to.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (string, error)
+}
+
+// NewAuthenticatorFromEnv creates the authenticator of AUTH_PROVIDER:
+//   - firebase (the default) verifies Firebase ID tokens
+//   - oidc verifies the JWTs of AUTH_OIDC_ISSUER for AUTH_OIDC_AUDIENCE against its JWKS, discovered from the issuer
+//     unless AUTH_OIDC_JWKS_URL is set. The user ID is read from the AUTH_OIDC_USER_CLAIM claim, sub by default
+//   - static accepts the fixed tokens of AUTH_STATIC_TOKENS, as token:userId pairs separated by commas, for
+//     development and tests only
+func NewAuthenticatorFromEnv(ctx context.Context) (Authenticator, error) {
+	switch provider := os.Getenv("AUTH_PROVIDER"); provider {
+	case "", "firebase":
+		return NewFirebaseAuthenticator(ctx)
+	case "oidc":
+		issuer := os.Getenv("AUTH_OIDC_ISSUER")
+		audience := os.Getenv("AUTH_OIDC_AUDIENCE")
+		if issuer == "" || audience == "" {
+			return nil, fmt.Errorf("the oidc issuer and audience are required")
+		}
+		userClaim := os.Getenv("AUTH_OIDC_USER_CLAIM")
+		if userClaim == "" {
+			userClaim = "sub"
+		}
+		return NewOIDCAuthenticator(ctx, issuer, audience, os.Getenv("AUTH_OIDC_JWKS_URL"), userClaim)
+	case "static":
+		tokens := make(map[string]string)
+		for _, pair := range strings.Split(os.Getenv("AUTH_STATIC_TOKENS"), ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			token, userId, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if !ok || token == "" || userId == "" {
+				return nil, fmt.Errorf("invalid static token `%s`, use token:userId", pair)
+			}
+			tokens[token] = userId
+		}
+		if len(tokens) == 0 {
+			return nil, fmt.Errorf("at least one static token is required")
+		}
+		log.Printf("WARNING: users are authenticated by %d static tokens, don't use them in production", len(tokens))
+		return NewStaticAuthenticator(tokens), nil
+	default:
+		return nil, fmt.Errorf("unknown auth provider `%s`, use firebase, oidc or static", provider)
+	}
+}
@@ -3,14 +3,41 @@ package auth
 import (
 	"context"
 	firebase "firebase.google.com/go/v4"
-	"log"
+	firebaseauth "firebase.google.com/go/v4/auth"
+	"fmt"
 )
 
 // InitializeFirebase initializes the firebase app with Admin SDK.
 func InitializeFirebase(c context.Context) (*firebase.App, error) {
 	app, err := firebase.NewApp(c, nil)
 	if err != nil {
-		log.Fatalf("error initializing app: %v\n\n", err)
+		return nil, fmt.Errorf("error initializing app: %v", err)
 	}
 	return app, nil
 }
+
+type firebaseAuthenticator struct {
+	client *firebaseauth.Client
+}
+
+// NewFirebaseAuthenticator creates an authenticator of Firebase ID tokens, sharing the Auth client between requests
+func NewFirebaseAuthenticator(ctx context.Context) (Authenticator, error) {
+	app, err := InitializeFirebase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client, err := app.Auth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting Auth client: %v", err)
+	}
+	return &firebaseAuthenticator{client: client}, nil
+}
+
+// Authenticate verifies a Firebase ID token
+func (a *firebaseAuthenticator) Authenticate(ctx context.Context, token string) (string, error) {
+	verified, err := a.client.VerifyIDToken(ctx, token)
+	if err != nil {
+		return "", fmt.Errorf("error verifying ID token: %v", err)
+	}
+	return verified.UID, nil
+}
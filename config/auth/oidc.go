@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/MicahParks/keyfunc"
+	"github.com/golang-jwt/jwt/v4"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// oidcMethods are the signing methods accepted from the issuer. Symmetric methods are left out, so a token can't be
+// signed with the public key as a shared secret.
+var oidcMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// jwksRefreshInterval is how often the keys of the issuer are refreshed, besides when a token names an unknown key.
+const jwksRefreshInterval = time.Hour
+
+// discoveryTimeout bounds the request discovering the JWKS of the issuer.
+const discoveryTimeout = time.Second * 10
+
+type oidcAuthenticator struct {
+	issuer    string
+	audience  string
+	userClaim string
+	jwks      *keyfunc.JWKS
+}
+
+// NewOIDCAuthenticator creates an authenticator of the JWTs of an OpenID Connect issuer for the audience. The keys
+// are read from the JWKS URL, discovered from the issuer when empty, and refreshed until the context is done.
+func NewOIDCAuthenticator(ctx context.Context, issuer, audience, jwksURL, userClaim string) (Authenticator, error) {
+	if jwksURL == "" {
+		var err error
+		if jwksURL, err = discoverJWKS(ctx, issuer); err != nil {
+			return nil, err
+		}
+	}
+	jwks, err := keyfunc.Get(jwksURL, keyfunc.Options{
+		Ctx:               ctx,
+		RefreshInterval:   jwksRefreshInterval,
+		RefreshRateLimit:  time.Minute * 5,
+		RefreshTimeout:    discoveryTimeout,
+		RefreshUnknownKID: true,
+		RefreshErrorHandler: func(err error) {
+			log.Printf("WARNING: unable to refresh the keys of the issuer `%s` due to %v", issuer, err.Error())
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the keys of the issuer `%s`: %v", issuer, err)
+	}
+	log.Printf("INFO: users are authenticated by the issuer `%s`", issuer)
+	return &oidcAuthenticator{issuer: issuer, audience: audience, userClaim: userClaim, jwks: jwks}, nil
+}
+
+// Authenticate verifies the signature, issuer, audience and expiration of a JWT
+func (a *oidcAuthenticator) Authenticate(_ context.Context, token string) (string, error) {
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, a.jwks.Keyfunc, jwt.WithValidMethods(oidcMethods)); err != nil {
+		return "", fmt.Errorf("invalid token: %v", err)
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return "", fmt.Errorf("the token has no expiration")
+	}
+	if !claims.VerifyIssuer(a.issuer, true) {
+		return "", fmt.Errorf("the token was not issued by `%s`", a.issuer)
+	}
+	if !claims.VerifyAudience(a.audience, true) {
+		return "", fmt.Errorf("the token is not meant for `%s`", a.audience)
+	}
+	userId, _ := claims[a.userClaim].(string)
+	if userId == "" {
+		return "", fmt.Errorf("the token has no `%s` claim", a.userClaim)
+	}
+	return userId, nil
+}
+
+// discoverJWKS reads the JWKS URL from the OpenID Connect discovery document of the issuer.
+func discoverJWKS(ctx context.Context, issuer string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid oidc issuer `%s`: %v", issuer, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to discover the oidc issuer `%s`: %v", issuer, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to discover the oidc issuer `%s`: status %d", issuer, resp.StatusCode)
+	}
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil || discovery.JWKSURI == "" {
+		return "", fmt.Errorf("the oidc issuer `%s` has no jwks_uri", issuer)
+	}
+	return discovery.JWKSURI, nil
+}
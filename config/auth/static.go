@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+)
+
+type staticAuthenticator struct {
+	tokens map[string]string
+}
+
+// NewStaticAuthenticator creates an authenticator of fixed tokens, each mapped to the ID of its user
+func NewStaticAuthenticator(tokens map[string]string) Authenticator {
+	return &staticAuthenticator{tokens: tokens}
+}
+
+// Authenticate looks the token up, comparing it with every known token in constant time
+func (a *staticAuthenticator) Authenticate(_ context.Context, token string) (string, error) {
+	var userId string
+	for known, id := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+			userId = id
+		}
+	}
+	if userId == "" {
+		return "", fmt.Errorf("unknown static token")
+	}
+	return userId, nil
+}
@@ -11,6 +11,7 @@ func GetDB() (*gorm.DB, error) {
 	DSN := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s", os.Getenv("DB_HOST"), os.Getenv("DB_USER"),
 		os.Getenv("DB_PASS"), os.Getenv("DB_NAME"), os.Getenv("DB_PORT"), os.Getenv("DB_SSL"))
 	return gorm.Open(postgres.Open(DSN), &gorm.Config{
-		PrepareStmt: true,
+		PrepareStmt:    true,
+		TranslateError: true,
 	})
 }
@@ -41,8 +41,14 @@ export async function generateMetadata({ params }: Props): Promise<Metadata> {
  */
 async function fechPageData(slug: string): Promise<LinksPage> {
   const headersList = headers();
-  const userAgent = headersList.get("user-agent");
-  return getPageBySlug(slug, userAgent!);
+  // The first address of x-forwarded-for is the visitor, the next ones are the proxies in front of the site
+  const ip = headersList.get("x-forwarded-for")?.split(",")[0].trim() || headersList.get("x-real-ip");
+  return getPageBySlug(slug, {
+    ip: ip ?? "",
+    userAgent: headersList.get("user-agent") ?? "",
+    language: headersList.get("accept-language") ?? "",
+    referer: headersList.get("referer") ?? "",
+  });
 }
 
 export default async function LinksPage({
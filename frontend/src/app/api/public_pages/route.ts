@@ -34,6 +34,8 @@ export async function GET(request: NextRequest) {
         "Content-Type": "application/json",
         Authorization: request.headers.get("Authorization")!,
         "User-Agent": request.headers.get("User-Agent")!,
+        // The page view is recorded for the visitor, the API key must be granted the visits:forward scope
+        ...forwardedVisitor(request),
       },
     }
   );
@@ -46,4 +48,18 @@ export async function GET(request: NextRequest) {
           "Content-Type": "application/json",
       },
   });
-}
\ No newline at end of file
+}
+
+/**
+ * Picks the headers describing the visitor of the page, set by the page requesting it.
+ */
+function forwardedVisitor(request: NextRequest): Record<string, string> {
+  const visitor: Record<string, string> = {};
+  ["X-Visitor-IP", "X-Visitor-User-Agent", "X-Visitor-Accept-Language", "X-Visitor-Referer"].forEach((header) => {
+    const value = request.headers.get(header);
+    if (value) {
+      visitor[header] = value;
+    }
+  });
+  return visitor;
+}
@@ -1,6 +1,7 @@
 import { NextRequest, NextResponse } from "next/server";
 import { authConfig } from "@/config/server-config";
 import { getTokens } from "next-firebase-auth-edge/lib/next/tokens";
+import { defaultApiKeyScopes } from "@/types/APIKey";
 
 export async function POST(request: NextRequest) {
   const tokens = await getTokens(request.cookies, authConfig);
@@ -26,9 +27,14 @@ export async function POST(request: NextRequest) {
       return new TextDecoder().decode(value);
     });
 
-  const parsedBody = JSON.parse(getBody?.toString() ?? "{}");
+  const parsedBody = JSON.parse(getBody || "{}");
+  // A request without a name and scopes creates a key like the tokens created before keys were scoped
   const apiTokenReq = {
     userId: parsedBody.userId ?? tokens.decodedToken.uid,
+    name: parsedBody.name || "Default",
+    scopes: parsedBody.scopes?.length ? parsedBody.scopes : defaultApiKeyScopes,
+    expiresAt: parsedBody.expiresAt || undefined,
+    allowedIps: parsedBody.allowedIps,
   };
 
   const apiResponse = await fetch(process.env.NEXT_BACKEND_API_URL + "apikeys", {
@@ -58,7 +64,7 @@ export async function GET(request: NextRequest) {
   if (!tokens) {
     return new NextResponse(
       JSON.stringify(
-        "Error: Cannot GET an API Token for an unauthenticated user"
+        "Error: Cannot GET the API Tokens of an unauthenticated user"
       ),
       {
         status: 401,
@@ -80,16 +86,77 @@ export async function GET(request: NextRequest) {
     }
   );
 
+  // The keys are listed without their tokens, identified by their prefix
   const data = await apiResponse.json();
 
-  var start = 4;
-  var end = data.length - 4;
+  const response = new NextResponse(JSON.stringify(data), {
+    status: apiResponse.status,
+    headers: {
+      "Content-Type": "application/json"
+    }
+  });
+
+  return response;
+}
+
+export async function DELETE(request: NextRequest) {
+  const tokens = await getTokens(request.cookies, authConfig);
+
+  if (!tokens) {
+    return new NextResponse(
+      JSON.stringify(
+        "Error: Cannot revoke an API Token of an unauthenticated user"
+      ),
+      {
+        status: 401,
+        headers: {
+          "Content-Type": "application/json",
+        },
+      }
+    );
+  }
+
+  const getBody = await request.body
+    ?.getReader()
+    .read()
+    .then(({ value }) => {
+      return new TextDecoder().decode(value);
+    });
+
+  const parsedBody = JSON.parse(getBody || "{}");
+
+  const apiResponse = await fetch(
+    process.env.NEXT_BACKEND_API_URL +
+      "apikeys/" +
+      tokens.decodedToken.uid +
+      "/" +
+      encodeURIComponent(parsedBody.id ?? ""),
+    {
+      method: "DELETE",
+      headers: {
+        "Content-Type": "application/json",
+        Authorization: "Bearer " + tokens.token,
+      },
+    }
+  );
 
-  let tokenProtected = data.toString().slice(start, end).replace(/./g, "*");
-  tokenProtected =
-    data.toString().slice(0, 4) + tokenProtected + data.toString().slice(-4);
+  if (apiResponse.status === 204) {
+    return new NextResponse(
+      JSON.stringify({
+        message: "API Token revoked successfully",
+      }),
+      {
+        status: 200,
+        headers: {
+          "Content-Type": "application/json",
+        },
+      }
+    );
+  }
 
-  const response = new NextResponse(JSON.stringify(tokenProtected), {
+  const data = await apiResponse.json();
+
+  const response = new NextResponse(JSON.stringify(data), {
     status: apiResponse.status,
     headers: {
       "Content-Type": "application/json"
@@ -19,6 +19,7 @@ import {
   linkWithProvider,
 } from "@/app/auth/login/firebase";
 import { useTokens } from "@/lib/hooks/useTokens";
+import APIKey, { apiKeyScopes } from "@/types/APIKey";
 
 declare global {
   interface Window {
@@ -35,8 +36,10 @@ interface ProfileFormProps {
 }
 
 interface APIFormProps {
-  apiToken?: string;
   userId: string;
+  name: string;
+  scopes: string[];
+  expiresAt: string;
 }
 
 let profileFormValidationSchema = object({
@@ -84,8 +87,11 @@ export default function ProfileForm() {
   } as ProfileFormProps);
   const [apiFormData, setApiFormData] = useState<APIFormProps>({
     userId: user?.uid || "",
-    apiToken: "",
+    name: "",
+    scopes: [],
+    expiresAt: "",
   } as APIFormProps);
+  const [apiKeys, setApiKeys] = useState<APIKey[]>([]);
   const [apiToken, setApiToken] = useState<string>("");
   const [apiTokenIsCopied, setApiTokenIsCopied] = useState<boolean>(false);
   const [dataIsEdited, setDataIsEdited] = useState<boolean>(false);
@@ -283,8 +289,32 @@ export default function ProfileForm() {
     }
   };
 
+  const handleApiFormChange = (e: React.ChangeEvent<HTMLInputElement>) => {
+    const { name, value, checked } = e.target;
+    if (name === "scopes") {
+      setApiFormData((prev) => ({
+        ...prev,
+        scopes: checked
+          ? [...prev.scopes, value]
+          : prev.scopes.filter((scope) => scope !== value),
+      }));
+      return;
+    }
+    setApiFormData((prev) => ({
+      ...prev,
+      [name]: value,
+    }));
+  };
+
   const handleGenerateToken = async (e: React.FormEvent<HTMLFormElement>) => {
     e.preventDefault();
+    if (apiFormData.name.trim() === "" || apiFormData.scopes.length === 0) {
+      setTokenError("Give your API Token a name and at least one scope");
+      setTimeout(() => {
+        setTokenError("");
+      }, 3000);
+      return;
+    }
     try {
       const response = await fetch("/api/tokens", {
         method: "POST",
@@ -293,11 +323,23 @@ export default function ProfileForm() {
         },
         body: JSON.stringify({
           userId: apiFormData.userId,
+          name: apiFormData.name.trim(),
+          scopes: apiFormData.scopes,
+          expiresAt: apiFormData.expiresAt
+            ? new Date(apiFormData.expiresAt).toISOString()
+            : undefined,
         }),
       });
 
       const data = await response.json();
-      setApiToken(data);
+      if (response.status !== 201) {
+        setTokenError(data.message ?? "An error occured while generating your token");
+        return;
+      }
+      setApiToken(data.token);
+      setApiTokenIsCopied(false);
+      setApiKeys((prev) => [{ ...data, token: undefined }, ...prev]);
+      setApiFormData((prev) => ({ ...prev, name: "", scopes: [], expiresAt: "" }));
     } catch (err) {
       if (err instanceof Error) {
         setTokenError(
@@ -313,8 +355,38 @@ export default function ProfileForm() {
     setApiTokenIsCopied(true);
   };
 
+  const handleRevokeToken = async (
+    e: React.MouseEvent<HTMLButtonElement>,
+    apiKey: APIKey
+  ) => {
+    e.preventDefault();
+    try {
+      const response = await fetch("/api/tokens", {
+        method: "DELETE",
+        headers: {
+          "Content-Type": "application/json",
+        },
+        body: JSON.stringify({
+          id: apiKey.id,
+        }),
+      });
+      if (response.status !== 200) {
+        const data = await response.json();
+        setTokenError(data.message ?? "An error occured while revoking your token");
+        return;
+      }
+      setApiKeys((prev) => prev.filter((key) => key.id !== apiKey.id));
+    } catch (err) {
+      if (err instanceof Error) {
+        setTokenError(
+          "An error occured while revoking your token: " + err.message
+        );
+      }
+    }
+  };
+
   useEffect(() => {
-    useTokens(setTokenError, setApiToken);
+    useTokens(setTokenError, setApiKeys);
     auth.authStateReady().then(() => auth.currentUser?.providerData).then((data) => {
       if (data) {
         data.forEach((provider) => {
@@ -565,53 +637,104 @@ export default function ProfileForm() {
           <form className="card-body" onSubmit={handleGenerateToken}>
             <div className="pb-8">
               <h2 className="text-base font-semibold leading-7 text-white-900">
-                API Token
+                API Tokens
               </h2>
               <p className="my-4 text-sm leading-6 ">
-                Generate or update your API token. To know more about our REST
-                API works <a href="/pages/api">click here</a>
-              </p>
-              <p className="my-2 text-xs leading-6 italic dark:text-warning">
-                Remember: When you generate a new API Token if you already have
-                one it's revoked automatically
+                Generate API tokens scoped to what they can do, and revoke the
+                ones you no longer use. To know more about our REST API works{" "}
+                <a href="/pages/api">click here</a>
               </p>
               <div className="form-control">
                 <label
-                  htmlFor="api-token"
+                  htmlFor="api-token-name"
                   className="label-text font-medium leading-6"
                 >
-                  API Token
+                  Name
                 </label>
-                <div className="my-2 flex flex-row justify-center text-center align-middle content-center">
+                <div className="my-2">
                   <input
                     type="text"
-                    name="api-token"
-                    id="api-token"
-                    value={apiToken}
-                    disabled={true}
+                    name="name"
+                    id="api-token-name"
+                    placeholder="e.g. Deploy script"
+                    value={apiFormData.name}
+                    onChange={handleApiFormChange}
                     className="input input-bordered w-full"
                   />
-                  <button
-                    className="btn btn-primary ml-2"
-                    id="copy-api-token"
-                    disabled={
-                      apiToken === "" ||
-                      apiTokenIsCopied ||
-                      apiToken.charAt(8) === "*"
-                    }
-                    onClick={handleCopyToken}
-                  >
-                    Copy
-                  </button>
                 </div>
-                {apiTokenIsCopied && (
-                  <div>
-                    <label className="label">
-                      <span className="label-text-alt italic dark:text-warning">
-                        This API Token will not be shown again. Please, save it
-                        in a secure place.
-                      </span>
+              </div>
+              <div className="form-control">
+                <span className="label-text font-medium leading-6">Scopes</span>
+                <div className="my-2 grid grid-cols-2 gap-2">
+                  {apiKeyScopes.map((scope) => (
+                    <label key={scope} className="label cursor-pointer justify-start gap-2">
+                      <input
+                        type="checkbox"
+                        name="scopes"
+                        value={scope}
+                        checked={apiFormData.scopes.includes(scope)}
+                        onChange={handleApiFormChange}
+                        className="checkbox checkbox-sm"
+                      />
+                      <span className="label-text">{scope}</span>
                     </label>
+                  ))}
+                </div>
+              </div>
+              <div className="form-control">
+                <label
+                  htmlFor="api-token-expires-at"
+                  className="label-text font-medium leading-6"
+                >
+                  Expiration date (optional)
+                </label>
+                <div className="my-2">
+                  <input
+                    type="date"
+                    name="expiresAt"
+                    id="api-token-expires-at"
+                    value={apiFormData.expiresAt}
+                    onChange={handleApiFormChange}
+                    className="input input-bordered w-full"
+                  />
+                </div>
+              </div>
+              <div className="form-control mt-4">
+                <button className="btn btn-primary">Generate new token</button>
+              </div>
+              {apiToken !== "" && (
+                <div className="form-control mt-4">
+                  <label
+                    htmlFor="api-token"
+                    className="label-text font-medium leading-6"
+                  >
+                    New API Token
+                  </label>
+                  <div className="my-2 flex flex-row justify-center text-center align-middle content-center">
+                    <input
+                      type="text"
+                      name="api-token"
+                      id="api-token"
+                      value={apiToken}
+                      disabled={true}
+                      className="input input-bordered w-full"
+                    />
+                    <button
+                      className="btn btn-primary ml-2"
+                      id="copy-api-token"
+                      disabled={apiTokenIsCopied}
+                      onClick={handleCopyToken}
+                    >
+                      Copy
+                    </button>
+                  </div>
+                  <label className="label">
+                    <span className="label-text-alt italic dark:text-warning">
+                      This API Token will not be shown again. Please, save it
+                      in a secure place.
+                    </span>
+                  </label>
+                  {apiTokenIsCopied && (
                     <div className="alert alert-success">
                       <svg
                         xmlns="http://www.w3.org/2000/svg"
@@ -628,17 +751,62 @@ export default function ProfileForm() {
                       </svg>
                       <span>Your API Token has been copied!</span>
                     </div>
-                  </div>
-                )}
-                {tokenError && (
-                  <span className="label-text-alt alert bg-red-800 text-white">
-                    {tokenError}
-                  </span>
+                  )}
+                </div>
+              )}
+              {tokenError && (
+                <span className="mt-4 label-text-alt alert bg-red-800 text-white">
+                  {tokenError}
+                </span>
+              )}
+              <div className="mt-8 overflow-x-auto">
+                {apiKeys.length === 0 ? (
+                  <p className="text-sm leading-6 italic">
+                    You have no API Tokens yet.
+                  </p>
+                ) : (
+                  <table className="table table-sm">
+                    <thead>
+                      <tr>
+                        <th>Name</th>
+                        <th>Token</th>
+                        <th>Scopes</th>
+                        <th>Last used</th>
+                        <th>Expires</th>
+                        <th></th>
+                      </tr>
+                    </thead>
+                    <tbody>
+                      {apiKeys.map((apiKey) => (
+                        <tr key={apiKey.id}>
+                          <td>{apiKey.name}</td>
+                          <td className="font-mono">{apiKey.prefix}…</td>
+                          <td>{apiKey.scopes.join(", ")}</td>
+                          <td>
+                            {apiKey.lastUsedAt
+                              ? new Date(apiKey.lastUsedAt).toLocaleDateString()
+                              : "Never"}
+                          </td>
+                          <td>
+                            {apiKey.expiresAt
+                              ? new Date(apiKey.expiresAt).toLocaleDateString()
+                              : "Never"}
+                          </td>
+                          <td>
+                            <button
+                              className="btn btn-ghost btn-xs hover:bg-red-500 hover:text-white"
+                              title="Revoke this API Token"
+                              onClick={(e) => handleRevokeToken(e, apiKey)}
+                            >
+                              Revoke
+                            </button>
+                          </td>
+                        </tr>
+                      ))}
+                    </tbody>
+                  </table>
                 )}
               </div>
-              <div className="form-control mt-8">
-                <button className="btn btn-primary">Generate new token</button>
-              </div>
             </div>
           </form>
         </div>
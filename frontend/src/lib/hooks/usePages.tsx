@@ -1,20 +1,34 @@
 import LinksPage from "@/types/LinksPage";
 
+/**
+ * The visitor of a links page, forwarded to the API so the page view is recorded for them.
+ */
+export type Visitor = {
+    ip: string;
+    userAgent: string;
+    language: string;
+    referer: string;
+};
+
 /**
  * Retrieves a page by its slug.
  * @param slug - The slug of the page to retrieve.
- * @param userAgent - The user agent string to include in the request headers.
+ * @param visitor - The visitor of the page, forwarded in the request headers.
  * @returns A promise that resolves to the LinksPage object representing the requested page.
  * @throws An error if the data cannot be fetched.
  */
-export const getPageBySlug = async (slug: string, userAgent: string): Promise<LinksPage> => {
+export const getPageBySlug = async (slug: string, visitor: Visitor): Promise<LinksPage> => {
     try {
         const response = await fetch(`${process.env.NEXT_PUBLIC_SITE_URL}/api/public_pages/?alias=${slug}`, {
             method: "GET",
             headers: {
                 "Content-Type": "application/json",
                 Authorization: `Bearer ${process.env.NEXT_FRONTEND_API_KEY}`,
-                "User-Agent": userAgent,
+                "User-Agent": visitor.userAgent,
+                "X-Visitor-IP": visitor.ip,
+                "X-Visitor-User-Agent": visitor.userAgent,
+                "X-Visitor-Accept-Language": visitor.language,
+                "X-Visitor-Referer": visitor.referer,
             },
             cache: "no-cache",
         });
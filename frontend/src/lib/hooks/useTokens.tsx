@@ -1,4 +1,6 @@
-export const useTokens = async (setTokenError: Function, setApiToken: Function) => {
+import APIKey from "@/types/APIKey";
+
+export const useTokens = async (setTokenError: Function, setApiKeys: Function) => {
     try {
         const response = await fetch("/api/tokens", {
             method: "GET",
@@ -8,16 +10,16 @@ export const useTokens = async (setTokenError: Function, setApiToken: Function)
         });
         const data = await response.json();
         if (response.status === 200) {
-            setApiToken(data);
+            setApiKeys((data ?? []) as APIKey[]);
         }
     } catch (error) {
         console.error(error);
         if (error instanceof Error) {
-            setApiToken("");
+            setApiKeys([]);
             setTokenError(error.message);
             setTimeout(() => {
                 setTokenError("");
             }, 1500);
         };
     };
-};
\ No newline at end of file
+};
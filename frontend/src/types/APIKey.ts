@@ -0,0 +1,26 @@
+// Define the APIKey type, its token is only returned when it is created
+export default interface APIKey {
+    id: string;
+    userId: string;
+    name: string;
+    prefix: string;
+    token?: string;
+    scopes: string[];
+    allowedIps?: string[];
+    expiresAt?: Date;
+    lastUsedAt?: Date;
+    createdAt: Date;
+}
+
+// Scopes an API key can be granted, a `*` action grants every action on the resource
+export const apiKeyScopes = [
+    "links:read",
+    "links:write",
+    "pages:read",
+    "pages:write",
+    "stats:read",
+    "visits:forward",
+];
+
+// Scopes of the keys created without choosing them, the ones the tokens had before keys were scoped
+export const defaultApiKeyScopes = ["links:*", "pages:*", "stats:*"];
@@ -0,0 +1,158 @@
+package alias
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// reservedWords can never be used as aliases, on top of the first segment of every route of the API.
+var reservedWords = []string{
+	"admin", "api", "app", "assets", "auth", "dashboard", "docs", "favicon", "health", "help", "login", "logout",
+	"pages", "profile", "register", "robots", "sitemap", "static", "status", "swagger", "www",
+}
+
+// profanityWords is the built-in list of words aliases must not contain, extended by ALIAS_BLOCKLIST_PATH.
+var profanityWords = []string{
+	"asshole", "bastard", "bitch", "bollocks", "cunt", "dickhead", "fuck", "motherfucker", "porn", "shit",
+	"slut", "whore",
+}
+
+// leetReplacer undoes the usual character substitutions before looking for blocked words.
+var leetReplacer = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "-", "", "_", "")
+
+// Policy validates the aliases chosen by users for their links and pages.
+type Policy struct {
+	minLength int
+	maxLength int
+	foldCase  bool
+	reserved  map[string]bool
+	blocked   []string
+}
+
+// NewPolicy creates a policy accepting aliases between minLength and maxLength characters. When foldCase is set,
+// aliases are stored in lower case.
+func NewPolicy(minLength, maxLength int, foldCase bool) *Policy {
+	p := &Policy{
+		minLength: minLength,
+		maxLength: maxLength,
+		foldCase:  foldCase,
+		reserved:  make(map[string]bool),
+		blocked:   append([]string{}, profanityWords...),
+	}
+	p.Reserve(reservedWords...)
+	return p
+}
+
+// NewPolicyFromEnv creates a policy from the ALIAS_* environment variables:
+//   - ALIAS_MIN_LENGTH and ALIAS_MAX_LENGTH: the length bounds, 3 and 50 when not set
+//   - ALIAS_FOLD_CASE: `true` to store aliases in lower case
+//   - ALIAS_RESERVED: extra comma separated reserved words
+//   - ALIAS_BLOCKLIST_PATH: a file with one blocked word per line
+func NewPolicyFromEnv() (*Policy, error) {
+	minLength, err := intFromEnv("ALIAS_MIN_LENGTH", 3)
+	if err != nil {
+		return nil, err
+	}
+	maxLength, err := intFromEnv("ALIAS_MAX_LENGTH", 50)
+	if err != nil {
+		return nil, err
+	}
+	if minLength < 1 || maxLength < minLength {
+		return nil, fmt.Errorf("invalid alias length bounds %d-%d", minLength, maxLength)
+	}
+
+	p := NewPolicy(minLength, maxLength, os.Getenv("ALIAS_FOLD_CASE") == "true")
+	p.Reserve(strings.Split(os.Getenv("ALIAS_RESERVED"), ",")...)
+	if path := os.Getenv("ALIAS_BLOCKLIST_PATH"); path != "" {
+		if err := p.loadBlocklist(path); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Reserve prevents the words from being used as aliases.
+func (p *Policy) Reserve(words ...string) {
+	for _, word := range words {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			p.reserved[word] = true
+		}
+	}
+}
+
+// Normalize trims the alias and applies the case folding rule.
+func (p *Policy) Normalize(alias string) string {
+	alias = strings.TrimSpace(alias)
+	if p.foldCase {
+		alias = strings.ToLower(alias)
+	}
+	return alias
+}
+
+// Validate checks the alias charset, length, reserved words and blocked words. Reserved and blocked words are
+// matched regardless of case.
+func (p *Policy) Validate(alias string) error {
+	if len(alias) < p.minLength || len(alias) > p.maxLength {
+		return fmt.Errorf("the alias must have between %d and %d characters", p.minLength, p.maxLength)
+	}
+	for _, c := range alias {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return fmt.Errorf("the alias `%s` must only contain letters, numbers, hyphens and underscores", alias)
+		}
+	}
+	if p.reserved[strings.ToLower(alias)] {
+		return fmt.Errorf("the alias `%s` is reserved", alias)
+	}
+	if p.containsBlockedWord(alias) {
+		return fmt.Errorf("the alias `%s` is not allowed", alias)
+	}
+	return nil
+}
+
+// Forbidden reports whether the alias is reserved or contains a blocked word, regardless of its charset and
+// length. Generated short codes are checked with it.
+func (p *Policy) Forbidden(alias string) bool {
+	return p.reserved[strings.ToLower(alias)] || p.containsBlockedWord(alias)
+}
+
+func (p *Policy) containsBlockedWord(alias string) bool {
+	plain := leetReplacer.Replace(strings.ToLower(alias))
+	for _, word := range p.blocked {
+		if strings.Contains(plain, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadBlocklist adds the words of the file to the blocked words, skipping blank lines and # comments.
+func (p *Policy) loadBlocklist(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open the alias blocklist: %v", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word != "" && !strings.HasPrefix(word, "#") {
+			p.blocked = append(p.blocked, word)
+		}
+	}
+	return scanner.Err()
+}
+
+func intFromEnv(name string, fallback int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value `%s`", name, value)
+	}
+	return n, nil
+}
@@ -0,0 +1,139 @@
+package alias
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	p := NewPolicy(3, 10, false)
+	p.Reserve("promo")
+	tests := []struct {
+		alias string
+		err   string
+	}{
+		{alias: "my-link_1"},
+		{alias: "MyLink"},
+		{alias: "ab", err: "between 3 and 10"},
+		{alias: "a-very-long-alias", err: "between 3 and 10"},
+		{alias: "my link", err: "must only contain"},
+		{alias: "my/link", err: "must only contain"},
+		{alias: "ação", err: "must only contain"},
+		{alias: "admin", err: "reserved"},
+		{alias: "Swagger", err: "reserved"},
+		{alias: "promo", err: "reserved"},
+		{alias: "fuckit", err: "not allowed"},
+		{alias: "sh1t-link", err: "not allowed"},
+		{alias: "P-0-R-N", err: "not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			err := p.Validate(tt.alias)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		foldCase bool
+		alias    string
+		want     string
+	}{
+		{name: "trimmed", alias: "  MyLink ", want: "MyLink"},
+		{name: "case folded", foldCase: true, alias: " MyLink", want: "mylink"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewPolicy(3, 50, tt.foldCase).Normalize(tt.alias); got != tt.want {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForbidden(t *testing.T) {
+	p := NewPolicy(3, 50, false)
+	tests := []struct {
+		alias string
+		want  bool
+	}{
+		{alias: "x7k2", want: false},
+		{alias: "a", want: false},
+		{alias: "API", want: true},
+		{alias: "b1tch", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			if got := p.Forbidden(tt.alias); got != tt.want {
+				t.Errorf("Forbidden() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPolicyFromEnv(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(blocklist, []byte("# extra words\n\nBanana\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		alias   string
+		wantErr bool
+	}{
+		{name: "defaults", alias: "abc"},
+		{name: "min length", env: map[string]string{"ALIAS_MIN_LENGTH": "5"}, alias: "abcd", wantErr: true},
+		{name: "max length", env: map[string]string{"ALIAS_MAX_LENGTH": "5"}, alias: "abcdef", wantErr: true},
+		{name: "reserved words", env: map[string]string{"ALIAS_RESERVED": "sale, Promo"}, alias: "promo", wantErr: true},
+		{name: "blocklist", env: map[string]string{"ALIAS_BLOCKLIST_PATH": blocklist}, alias: "my-banana", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"ALIAS_MIN_LENGTH", "ALIAS_MAX_LENGTH", "ALIAS_FOLD_CASE", "ALIAS_RESERVED", "ALIAS_BLOCKLIST_PATH"} {
+				t.Setenv(name, tt.env[name])
+			}
+			p, err := NewPolicyFromEnv()
+			if err != nil {
+				t.Fatalf("NewPolicyFromEnv() error = %v", err)
+			}
+			if err := p.Validate(tt.alias); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewPolicyFromEnvInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{name: "not a number", env: map[string]string{"ALIAS_MIN_LENGTH": "three"}},
+		{name: "min length below one", env: map[string]string{"ALIAS_MIN_LENGTH": "0"}},
+		{name: "max below min", env: map[string]string{"ALIAS_MIN_LENGTH": "8", "ALIAS_MAX_LENGTH": "4"}},
+		{name: "missing blocklist", env: map[string]string{"ALIAS_BLOCKLIST_PATH": filepath.Join(t.TempDir(), "missing.txt")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"ALIAS_MIN_LENGTH", "ALIAS_MAX_LENGTH", "ALIAS_BLOCKLIST_PATH"} {
+				t.Setenv(name, tt.env[name])
+			}
+			if _, err := NewPolicyFromEnv(); err == nil {
+				t.Error("NewPolicyFromEnv() error = nil, want an error")
+			}
+		})
+	}
+}
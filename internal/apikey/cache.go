@@ -0,0 +1,112 @@
+package apikey
+
+import (
+	"fmt"
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"log"
+	"os"
+	"time"
+)
+
+// cacheKeyPrefix namespaces the cached keys in a store shared with other entries.
+const cacheKeyPrefix = "apikey:"
+
+// cachedRepository caches the lookups of the keys by hash, sparing a round trip to the store on every request
+// authenticated by an API key. Keys revoked through it are evicted at once, other instances see the revocation
+// when their entries expire.
+type cachedRepository struct {
+	Repository
+	store persistence.CacheStore
+	ttl   time.Duration
+}
+
+// NewCachedRepository caches the lookups of the repository in the store for the ttl
+func NewCachedRepository(r Repository, store persistence.CacheStore, ttl time.Duration) Repository {
+	return &cachedRepository{Repository: r, store: store, ttl: ttl}
+}
+
+// NewCachedRepositoryFromEnv caches the lookups of the repository in memory for APIKEY_CACHE_TTL, one minute by
+// default. A zero ttl disables the cache.
+func NewCachedRepositoryFromEnv(r Repository) (Repository, error) {
+	ttl := time.Minute
+	if value := os.Getenv("APIKEY_CACHE_TTL"); value != "" {
+		var err error
+		ttl, err = time.ParseDuration(value)
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("invalid api key cache ttl `%s`", value)
+		}
+	}
+	if ttl == 0 {
+		return r, nil
+	}
+	return NewCachedRepository(r, persistence.NewInMemoryStore(ttl), ttl), nil
+}
+
+// FindByHash finds an api key by the hash of its token, from the cache when it was looked up recently
+func (r *cachedRepository) FindByHash(hash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := r.store.Get(cacheKeyPrefix+hash, &key); err == nil {
+		return &key, nil
+	}
+	found, err := r.Repository.FindByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	r.cache(*found)
+	return found, nil
+}
+
+// UpdateLastUsed saves the last use of an api key, keeping the cached key up to date so it isn't saved again
+func (r *cachedRepository) UpdateLastUsed(key *domain.APIKey) error {
+	if err := r.Repository.UpdateLastUsed(key); err != nil {
+		return err
+	}
+	r.cache(*key)
+	return nil
+}
+
+// Delete deletes an api key of a user and evicts it from the cache
+func (r *cachedRepository) Delete(userId string, keyId string) error {
+	keys, err := r.Repository.FindAllByUser(userId)
+	if err != nil {
+		return err
+	}
+	if err := r.Repository.Delete(userId, keyId); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if key.ID == keyId {
+			r.evict(key)
+		}
+	}
+	return nil
+}
+
+// DeleteAllByUser deletes all api keys of a user and evicts them from the cache
+func (r *cachedRepository) DeleteAllByUser(userId string) error {
+	keys, err := r.Repository.FindAllByUser(userId)
+	if err != nil {
+		return err
+	}
+	if err := r.Repository.DeleteAllByUser(userId); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		r.evict(key)
+	}
+	return nil
+}
+
+func (r *cachedRepository) cache(key domain.APIKey) {
+	key.Token = ""
+	if err := r.store.Set(cacheKeyPrefix+key.Hash, key, r.ttl); err != nil {
+		log.Printf("WARNING: unable to cache the api key `%s` due to %v", key.Prefix, err.Error())
+	}
+}
+
+func (r *cachedRepository) evict(key domain.APIKey) {
+	if err := r.store.Delete(cacheKeyPrefix + key.Hash); err != nil && err != persistence.ErrCacheMiss {
+		log.Printf("WARNING: unable to evict the api key `%s` from the cache due to %v", key.Prefix, err.Error())
+	}
+}
@@ -0,0 +1,206 @@
+package apikey
+
+import (
+	"errors"
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryRepository stores the keys in memory, counting the lookups by hash.
+type memoryRepository struct {
+	mu      sync.Mutex
+	keys    map[string]domain.APIKey
+	lookups int
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{keys: make(map[string]domain.APIKey)}
+}
+
+func (r *memoryRepository) Create(key *domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[key.ID] = *key
+	return nil
+}
+
+func (r *memoryRepository) FindByHash(hash string) (*domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+	for _, key := range r.keys {
+		if key.Hash == hash {
+			return &key, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (r *memoryRepository) FindAllByUser(userId string) ([]domain.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := make([]domain.APIKey, 0)
+	for _, key := range r.keys {
+		if key.UserId == userId {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *memoryRepository) UpdateLastUsed(key *domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.keys[key.ID]
+	stored.LastUsedAt = key.LastUsedAt
+	r.keys[key.ID] = stored
+	return nil
+}
+
+func (r *memoryRepository) Delete(userId string, keyId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if key, ok := r.keys[keyId]; !ok || key.UserId != userId {
+		return errNotFound
+	}
+	delete(r.keys, keyId)
+	return nil
+}
+
+func (r *memoryRepository) DeleteAllByUser(userId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, key := range r.keys {
+		if key.UserId == userId {
+			delete(r.keys, id)
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) lookupCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lookups
+}
+
+// newCachedService creates a service whose repository lookups are cached for the ttl.
+func newCachedService(t *testing.T, ttl time.Duration) (Service, *memoryRepository) {
+	t.Helper()
+	r := newMemoryRepository()
+	return NewApiKeyService(NewCachedRepository(r, persistence.NewInMemoryStore(ttl), ttl)), r
+}
+
+func createKey(t *testing.T, s Service, userId string) domain.APIKey {
+	t.Helper()
+	key, err := s.Create(userId, web.CreateAPIKey{Name: "test", Scopes: []string{ScopeLinksRead}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return key
+}
+
+func TestCachedRepositoryHit(t *testing.T) {
+	s, r := newCachedService(t, time.Minute)
+	key := createKey(t, s, "user")
+	for i := 0; i < 3; i++ {
+		if _, err := s.Authenticate(key.Token, "203.0.113.7"); err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+	}
+	if lookups := r.lookupCount(); lookups != 1 {
+		t.Errorf("looked up the key %d times, want 1", lookups)
+	}
+}
+
+func TestCachedRepositoryExpiry(t *testing.T) {
+	s, r := newCachedService(t, time.Millisecond*50)
+	key := createKey(t, s, "user")
+	if _, err := s.Authenticate(key.Token, "203.0.113.7"); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	time.Sleep(time.Millisecond * 100)
+	if _, err := s.Authenticate(key.Token, "203.0.113.7"); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if lookups := r.lookupCount(); lookups != 2 {
+		t.Errorf("looked up the key %d times, want 2 once the cached key expired", lookups)
+	}
+}
+
+func TestCachedRepositoryRevoke(t *testing.T) {
+	tests := []struct {
+		name   string
+		revoke func(s Service, key domain.APIKey) error
+	}{
+		{name: "revoke", revoke: func(s Service, key domain.APIKey) error { return s.Revoke(key.UserId, key.ID) }},
+		{name: "revoke all", revoke: func(s Service, key domain.APIKey) error { return s.RevokeAll(key.UserId) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newCachedService(t, time.Minute)
+			key := createKey(t, s, "user")
+			other := createKey(t, s, "other")
+			for _, k := range []domain.APIKey{key, other} {
+				if _, err := s.Authenticate(k.Token, "203.0.113.7"); err != nil {
+					t.Fatalf("Authenticate() error = %v", err)
+				}
+			}
+
+			if err := tt.revoke(s, key); err != nil {
+				t.Fatalf("revoking error = %v", err)
+			}
+			if _, err := s.Authenticate(key.Token, "203.0.113.7"); err == nil {
+				t.Error("Authenticate() succeeded with a revoked key")
+			}
+			if _, err := s.Authenticate(other.Token, "203.0.113.7"); err != nil {
+				t.Errorf("Authenticate() error = %v with the key of another user", err)
+			}
+		})
+	}
+}
+
+func TestCachedRepositoryRevokeOtherUser(t *testing.T) {
+	s, _ := newCachedService(t, time.Minute)
+	key := createKey(t, s, "user")
+	if _, err := s.Authenticate(key.Token, "203.0.113.7"); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if err := s.Revoke("other", key.ID); !errors.Is(err, errNotFound) {
+		t.Fatalf("Revoke() error = %v for the key of another user, want %v", err, errNotFound)
+	}
+	if _, err := s.Authenticate(key.Token, "203.0.113.7"); err != nil {
+		t.Errorf("Authenticate() error = %v after another user failed to revoke the key", err)
+	}
+}
+
+func TestNewCachedRepositoryFromEnv(t *testing.T) {
+	tests := []struct {
+		name       string
+		ttl        string
+		wantCached bool
+		wantErr    bool
+	}{
+		{name: "default", ttl: "", wantCached: true},
+		{name: "custom ttl", ttl: "30s", wantCached: true},
+		{name: "disabled", ttl: "0", wantCached: false},
+		{name: "negative", ttl: "-1s", wantErr: true},
+		{name: "invalid", ttl: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APIKEY_CACHE_TTL", tt.ttl)
+			r, err := NewCachedRepositoryFromEnv(newMemoryRepository())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCachedRepositoryFromEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, cached := r.(*cachedRepository); err == nil && cached != tt.wantCached {
+				t.Errorf("NewCachedRepositoryFromEnv() cached = %v, want %v", cached, tt.wantCached)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package apikey
+
+import (
+	"cloud.google.com/go/firestore"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"log"
+	"sort"
+	"time"
+)
+
+// collection is the Firestore collection of the keys, each document keyed by the key ID.
+const collection = "apiKeys"
+
+type firestoreRepository struct {
+	client *firestore.Client
+}
+
+// NewFirestoreRepository creates a new api key repository stored in Firestore, sharing the client between calls
+func NewFirestoreRepository(client *firestore.Client) Repository {
+	return &firestoreRepository{client: client}
+}
+
+// Create creates a new api key
+func (r *firestoreRepository) Create(key *domain.APIKey) error {
+	_, err := r.client.Collection(collection).Doc(key.ID).Create(context.Background(), key)
+	return err
+}
+
+// FindByHash finds an api key by the hash of its token
+func (r *firestoreRepository) FindByHash(hash string) (*domain.APIKey, error) {
+	docs, err := r.client.Collection(collection).Where("hash", "==", hash).Limit(1).Documents(context.Background()).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, errNotFound
+	}
+	var key domain.APIKey
+	if err := docs[0].DataTo(&key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// FindAllByUser finds all api keys of a user ordered by creation
+func (r *firestoreRepository) FindAllByUser(userId string) ([]domain.APIKey, error) {
+	docs, err := r.client.Collection(collection).Where("userId", "==", userId).Documents(context.Background()).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]domain.APIKey, 0, len(docs))
+	for _, doc := range docs {
+		var key domain.APIKey
+		if err := doc.DataTo(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	// Sorted here, as ordering the query would require a composite index
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// UpdateLastUsed saves the last use of an api key
+func (r *firestoreRepository) UpdateLastUsed(key *domain.APIKey) error {
+	_, err := r.client.Collection(collection).Doc(key.ID).Update(context.Background(), []firestore.Update{{Path: "lastUsedAt", Value: key.LastUsedAt}})
+	return err
+}
+
+// Delete deletes an api key of a user
+func (r *firestoreRepository) Delete(userId string, keyId string) error {
+	doc, err := r.client.Collection(collection).Doc(keyId).Get(context.Background())
+	if err != nil || doc.Data()["userId"] != userId {
+		return errNotFound
+	}
+	_, err = doc.Ref.Delete(context.Background())
+	return err
+}
+
+// DeleteAllByUser deletes all api keys of a user
+func (r *firestoreRepository) DeleteAllByUser(userId string) error {
+	docs, err := r.client.Collection(collection).Where("userId", "==", userId).Documents(context.Background()).GetAll()
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if _, err := doc.Ref.Delete(context.Background()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrationsCollection keeps a document per one-off migration, telling later boots it already ran.
+const migrationsCollection = "migrations"
+
+// legacyKeysMigration is the document of the legacy keys migration.
+const legacyKeysMigration = "legacyApiKeys"
+
+// migrateLegacyKeys replaces the plaintext keys, one per user and keyed by the user ID, by hashed keys granting
+// every scope. Their tokens keep working. Once done, the migration is marked so later boots skip it.
+func migrateLegacyKeys(client *firestore.Client) error {
+	marker := client.Collection(migrationsCollection).Doc(legacyKeysMigration)
+	if doc, err := marker.Get(context.Background()); err == nil && doc.Exists() {
+		return nil
+	}
+
+	// Only the legacy keys have a plaintext key field
+	docs, err := client.Collection(collection).Where("key", ">", "").Documents(context.Background()).GetAll()
+	if err != nil {
+		return fmt.Errorf("unable to read the api keys: %v", err)
+	}
+	for _, doc := range docs {
+		token, ok := doc.Data()["key"].(string)
+		if !ok {
+			continue
+		}
+		if len(token) != legacyTokenLength {
+			log.Printf("WARNING: skipping the api key `%s`, its token isn't a legacy token", doc.Ref.ID)
+			continue
+		}
+		userId, _ := doc.Data()["userId"].(string)
+		key := domain.APIKey{
+			ID:        uuid.NewString(),
+			UserId:    userId,
+			Name:      "Default",
+			Prefix:    token[:prefixLength-len(TokenPrefix)],
+			Hash:      hashToken(token),
+			Scopes:    []string{"links:*", "pages:*", "stats:*"},
+			CreatedAt: time.Now(),
+		}
+		if createdAt, ok := doc.Data()["createdAt"].(time.Time); ok {
+			key.CreatedAt = createdAt
+		}
+		err := client.RunTransaction(context.Background(), func(ctx context.Context, tx *firestore.Transaction) error {
+			if err := tx.Create(client.Collection(collection).Doc(key.ID), key); err != nil {
+				return err
+			}
+			return tx.Delete(doc.Ref)
+		})
+		if err != nil {
+			return fmt.Errorf("unable to migrate the api key of the user `%s`: %v", userId, err)
+		}
+		log.Printf("INFO: api key of the user `%s` migrated to `%s`", userId, key.Prefix)
+	}
+	if _, err := marker.Set(context.Background(), map[string]interface{}{"migratedAt": time.Now()}); err != nil {
+		return fmt.Errorf("unable to mark the api keys migration: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ronilsonalves/5lnk/config/auth"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"gorm.io/gorm"
+	"os"
+)
+
+// errNotFound is returned when a key doesn't exist or belongs to another user.
+var errNotFound = errors.New("record not found")
+
+// Repository stores the API keys, looked up by the hash of their token.
+type Repository interface {
+	Create(key *domain.APIKey) error
+	FindByHash(hash string) (*domain.APIKey, error)
+	FindAllByUser(userId string) ([]domain.APIKey, error)
+	UpdateLastUsed(key *domain.APIKey) error
+	Delete(userId string, keyId string) error
+	DeleteAllByUser(userId string) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewApiKeyRepository creates a new api key repository stored in Postgres
+func NewApiKeyRepository(db *gorm.DB) Repository {
+	return &apiKeyRepository{db: db}
+}
+
+// NewRepositoryFromEnv creates the api key repository of APIKEY_STORE, postgres or firestore (the default), with its
+// lookups cached for APIKEY_CACHE_TTL.
+func NewRepositoryFromEnv(ctx context.Context, db *gorm.DB) (Repository, error) {
+	var r Repository
+	switch store := os.Getenv("APIKEY_STORE"); store {
+	case "postgres":
+		r = NewApiKeyRepository(db)
+	case "", "firestore":
+		app, err := auth.InitializeFirebase(ctx)
+		if err != nil {
+			return nil, err
+		}
+		client, err := app.Firestore(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error getting Firestore client: %v", err)
+		}
+		if err := migrateLegacyKeys(client); err != nil {
+			return nil, err
+		}
+		r = NewFirestoreRepository(client)
+	default:
+		return nil, fmt.Errorf("unknown api key store `%s`, use postgres or firestore", store)
+	}
+	return NewCachedRepositoryFromEnv(r)
+}
+
+// Create creates a new api key
+func (r *apiKeyRepository) Create(key *domain.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+// FindByHash finds an api key by the hash of its token
+func (r *apiKeyRepository) FindByHash(hash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+	if err := r.db.Where("hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// FindAllByUser finds all api keys of a user ordered by creation
+func (r *apiKeyRepository) FindAllByUser(userId string) ([]domain.APIKey, error) {
+	keys := make([]domain.APIKey, 0)
+	if err := r.db.Where("user_id = ?", userId).Order("created_at").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// UpdateLastUsed saves the last use of an api key
+func (r *apiKeyRepository) UpdateLastUsed(key *domain.APIKey) error {
+	return r.db.Model(&domain.APIKey{}).Where("id = ?", key.ID).Update("last_used_at", key.LastUsedAt).Error
+}
+
+// Delete deletes an api key of a user
+func (r *apiKeyRepository) Delete(userId string, keyId string) error {
+	result := r.db.Where("id = ? AND user_id = ?", keyId, userId).Delete(&domain.APIKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errNotFound
+	}
+	return nil
+}
+
+// DeleteAllByUser deletes all api keys of a user
+func (r *apiKeyRepository) DeleteAllByUser(userId string) error {
+	return r.db.Where("user_id = ?", userId).Delete(&domain.APIKey{}).Error
+}
@@ -0,0 +1,74 @@
+package apikey
+
+import (
+	"errors"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/testdb"
+	"gorm.io/gorm"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApiKeyRepository(t *testing.T) {
+	r := NewApiKeyRepository(testdb.Open(t, &domain.APIKey{}))
+	createdAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Microsecond)
+	keys := []domain.APIKey{
+		{ID: "first", UserId: "user", Name: "first", Prefix: "5lnk_aaaa", Hash: "hash-first", Scopes: []string{"links:read", "stats:read"}, AllowedIPs: []string{"10.0.0.0/8"}, CreatedAt: createdAt},
+		{ID: "second", UserId: "user", Name: "second", Prefix: "5lnk_bbbb", Hash: "hash-second", Scopes: []string{"pages:*"}, CreatedAt: createdAt.Add(time.Minute)},
+		{ID: "other", UserId: "other", Name: "other", Prefix: "5lnk_cccc", Hash: "hash-other", Scopes: []string{"links:write"}, CreatedAt: createdAt},
+	}
+	for i := range keys {
+		if err := r.Create(&keys[i]); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	found, err := r.FindByHash("hash-first")
+	if err != nil {
+		t.Fatalf("FindByHash() error = %v", err)
+	}
+	if found.ID != "first" || !reflect.DeepEqual(found.Scopes, keys[0].Scopes) || !reflect.DeepEqual(found.AllowedIPs, keys[0].AllowedIPs) {
+		t.Errorf("FindByHash() = %+v, want %+v", *found, keys[0])
+	}
+	if _, err := r.FindByHash("unknown"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindByHash() error = %v for an unknown hash, want %v", err, gorm.ErrRecordNotFound)
+	}
+
+	userKeys, err := r.FindAllByUser("user")
+	if err != nil {
+		t.Fatalf("FindAllByUser() error = %v", err)
+	}
+	if len(userKeys) != 2 || userKeys[0].ID != "first" || userKeys[1].ID != "second" {
+		t.Errorf("FindAllByUser() = %+v, want the keys first and second in creation order", userKeys)
+	}
+
+	usedAt := time.Now().UTC().Truncate(time.Microsecond)
+	found.LastUsedAt = &usedAt
+	if err := r.UpdateLastUsed(found); err != nil {
+		t.Fatalf("UpdateLastUsed() error = %v", err)
+	}
+	if found, _ := r.FindByHash("hash-first"); found.LastUsedAt == nil || !found.LastUsedAt.Equal(usedAt) {
+		t.Errorf("LastUsedAt = %v after UpdateLastUsed(), want %v", found.LastUsedAt, usedAt)
+	}
+
+	if err := r.Delete("other", "first"); !errors.Is(err, errNotFound) {
+		t.Errorf("Delete() error = %v for the key of another user, want %v", err, errNotFound)
+	}
+	if err := r.Delete("user", "first"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := r.FindByHash("hash-first"); err == nil {
+		t.Error("FindByHash() found a deleted key")
+	}
+
+	if err := r.DeleteAllByUser("user"); err != nil {
+		t.Fatalf("DeleteAllByUser() error = %v", err)
+	}
+	if userKeys, _ := r.FindAllByUser("user"); len(userKeys) != 0 {
+		t.Errorf("FindAllByUser() = %+v after DeleteAllByUser(), want none", userKeys)
+	}
+	if _, err := r.FindByHash("hash-other"); err != nil {
+		t.Errorf("FindByHash() error = %v for the key of another user after DeleteAllByUser()", err)
+	}
+}
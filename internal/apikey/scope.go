@@ -0,0 +1,74 @@
+package apikey
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scopes granted to API keys. A scope with the `*` action, e.g. pages:*, grants every action on the resource.
+const (
+	ScopeLinksRead  = "links:read"
+	ScopeLinksWrite = "links:write"
+	ScopePagesRead  = "pages:read"
+	ScopePagesWrite = "pages:write"
+	ScopeStatsRead  = "stats:read"
+	// ScopeVisitsForward lets a frontend forward the visitor of the pages it requests on its behalf
+	ScopeVisitsForward = "visits:forward"
+)
+
+// actions are the actions of each resource keys can be scoped to.
+var actions = map[string][]string{
+	"links":  {"read", "write"},
+	"pages":  {"read", "write"},
+	"stats":  {"read"},
+	"visits": {"forward"},
+}
+
+// ParseScopes validates the scopes of a key, removing the repeated ones.
+func ParseScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	seen := make(map[string]bool)
+	var parsed []string
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !validScope(scope) {
+			return nil, fmt.Errorf("unknown scope `%s`", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			parsed = append(parsed, scope)
+		}
+	}
+	return parsed, nil
+}
+
+// Grants reports whether the scopes of a key grant the scope.
+func Grants(scopes []string, scope string) bool {
+	resource, _, _ := strings.Cut(scope, ":")
+	for _, granted := range scopes {
+		if granted == scope || granted == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
+
+// validScope reports whether the scope names a known resource and action.
+func validScope(scope string) bool {
+	resource, action, ok := strings.Cut(scope, ":")
+	if !ok {
+		return false
+	}
+	if action == "*" {
+		_, ok := actions[resource]
+		return ok
+	}
+	for _, known := range actions[resource] {
+		if action == known {
+			return true
+		}
+	}
+	return false
+}
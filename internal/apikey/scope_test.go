@@ -0,0 +1,62 @@
+package apikey
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		scopes  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "known scopes", scopes: []string{"links:read", "stats:read"}, want: []string{"links:read", "stats:read"}},
+		{name: "normalised", scopes: []string{" Links:READ "}, want: []string{"links:read"}},
+		{name: "repeated scopes removed", scopes: []string{"pages:write", "pages:write", "PAGES:write"}, want: []string{"pages:write"}},
+		{name: "every action", scopes: []string{"pages:*", "visits:*"}, want: []string{"pages:*", "visits:*"}},
+		{name: "visitor forwarding", scopes: []string{"visits:forward"}, want: []string{"visits:forward"}},
+		{name: "no scope", scopes: nil, wantErr: true},
+		{name: "unknown resource", scopes: []string{"users:read"}, wantErr: true},
+		{name: "unknown action", scopes: []string{"stats:write"}, wantErr: true},
+		{name: "every resource", scopes: []string{"*:*"}, wantErr: true},
+		{name: "without action", scopes: []string{"links"}, wantErr: true},
+		{name: "one unknown scope", scopes: []string{"links:read", "links:delete"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseScopes(tt.scopes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseScopes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseScopes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrants(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{name: "granted scope", scopes: []string{"links:read", "stats:read"}, scope: ScopeStatsRead, want: true},
+		{name: "every action of the resource", scopes: []string{"links:*"}, scope: ScopeLinksWrite, want: true},
+		{name: "other action", scopes: []string{"links:read"}, scope: ScopeLinksWrite, want: false},
+		{name: "other resource", scopes: []string{"links:*"}, scope: ScopePagesRead, want: false},
+		{name: "resource prefix", scopes: []string{"link:*"}, scope: ScopeLinksRead, want: false},
+		{name: "forwarding not granted by the other resources", scopes: []string{"links:*", "pages:*", "stats:*"}, scope: ScopeVisitsForward, want: false},
+		{name: "no scope", scopes: nil, scope: ScopeLinksRead, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Grants(tt.scopes, tt.scope); got != tt.want {
+				t.Errorf("Grants() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
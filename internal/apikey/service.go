@@ -1,114 +1,156 @@
 package apikey
 
 import (
-	"cloud.google.com/go/firestore"
-	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
-	firebase "firebase.google.com/go/v4"
 	"fmt"
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/pkg/web"
 	"log"
+	"net"
+	"strings"
+	"time"
 )
 
+// TokenPrefix starts the tokens of the API keys, telling them apart from the user ID tokens.
+const TokenPrefix = "5lnk_"
+
+// prefixLength is the length of the start of a token kept visible to identify its key.
+const prefixLength = len(TokenPrefix) + 8
+
+// legacyTokenLength is the length of the tokens of the keys created before they were hashed.
+const legacyTokenLength = 64
+
+// lastUsedInterval is how often the last use of a key is saved, sparing a write on every request.
+const lastUsedInterval = time.Minute
+
 type Service interface {
-	GenerateApiKey(userId string) (string, error)
-	RetrieveApiKey(userId string) (string, error)
-	RetrieveUserId(apiKey string) (string, error)
-	RevokesApiKey(userId string) error
+	Create(userId string, request web.CreateAPIKey) (domain.APIKey, error)
+	GetAllByUser(userId string) ([]domain.APIKey, error)
+	Authenticate(token string, address string) (domain.APIKey, error)
+	Revoke(userId string, keyId string) error
+	RevokeAll(userId string) error
 }
 
 type apiKeyService struct {
-	app *firebase.App
+	r Repository
 }
 
 // NewApiKeyService creates a new api key service
-func NewApiKeyService(app *firebase.App) Service {
-	return &apiKeyService{app: app}
+func NewApiKeyService(r Repository) Service {
+	return &apiKeyService{r: r}
 }
 
-// GenerateApiKey generates a new api key for the user
-func (s *apiKeyService) GenerateApiKey(userId string) (string, error) {
-	key := make([]byte, 32)
-	_, err := rand.Read(key)
-	if err != nil {
-		return "", fmt.Errorf("error generating api key: %v", err)
-	}
-
-	apiKey := hex.EncodeToString(key)
+// IsToken reports whether the bearer token is an API key token rather than a user ID token.
+func IsToken(token string) bool {
+	return strings.HasPrefix(token, TokenPrefix) || len(token) == legacyTokenLength
+}
 
-	var apiKeyDoc = map[string]interface{}{
-		"userId":    userId,
-		"key":       apiKey,
-		"createdAt": firestore.ServerTimestamp,
+// Create generates a new key for the user, returning its token once
+func (s *apiKeyService) Create(userId string, request web.CreateAPIKey) (domain.APIKey, error) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return domain.APIKey{}, fmt.Errorf("the name is required")
 	}
-
-	client, err := s.app.Firestore(context.Background())
+	scopes, err := ParseScopes(request.Scopes)
 	if err != nil {
-		return "", fmt.Errorf("error getting Firestore client: %v", err)
+		return domain.APIKey{}, err
 	}
-	defer func(client *firestore.Client) {
-		err := client.Close()
-		if err != nil {
-			log.Printf("error closing Firestore client: %v", err)
-		}
-	}(client)
-	_, err = client.Collection("apiKeys").Doc(userId).Set(context.Background(), apiKeyDoc)
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return domain.APIKey{}, fmt.Errorf("the expiration date must be in the future")
+	}
+	allowedIPs, err := parseAllowedIPs(request.AllowedIPs)
 	if err != nil {
-		return "", fmt.Errorf("error saving api key: %v", err)
+		return domain.APIKey{}, err
 	}
-	return apiKey, nil
-}
 
-// RetrieveApiKey retrieves the api key from the database
-func (s *apiKeyService) RetrieveApiKey(userId string) (string, error) {
-	client, err := s.app.Firestore(context.Background())
-	if err != nil {
-		return "", fmt.Errorf("error getting Firestore client: %v", err)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return domain.APIKey{}, fmt.Errorf("error generating api key: %v", err)
 	}
-	defer func(client *firestore.Client) {
-		err := client.Close()
-		if err != nil {
-			log.Printf("error closing Firestore client: %v", err)
-		}
-	}(client)
-	apiKeyDoc, err := client.Collection("apiKeys").Doc(userId).Get(context.Background())
+	token := TokenPrefix + hex.EncodeToString(secret)
+	key := domain.APIKey{
+		ID:         uuid.NewString(),
+		UserId:     userId,
+		Name:       name,
+		Prefix:     token[:prefixLength],
+		Hash:       hashToken(token),
+		Scopes:     scopes,
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  request.ExpiresAt,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.r.Create(&key); err != nil {
+		return domain.APIKey{}, fmt.Errorf("error saving api key: %v", err)
+	}
+	log.Printf("INFO: api key `%s` created for the user `%s`", key.Prefix, userId)
+	key.Token = token
+	return key, nil
+}
+
+// GetAllByUser returns the keys of the user, without their tokens
+func (s *apiKeyService) GetAllByUser(userId string) ([]domain.APIKey, error) {
+	keys, err := s.r.FindAllByUser(userId)
 	if err != nil {
-		return "", fmt.Errorf("error retrieving api key: %v", err)
+		return nil, fmt.Errorf("error retrieving api keys: %v", err)
 	}
-	return apiKeyDoc.Data()["key"].(string), nil
+	return keys, nil
 }
 
-// RetrieveUserId retrieves the user id from the api key
-func (s *apiKeyService) RetrieveUserId(apiKey string) (string, error) {
-	client, err := s.app.Firestore(context.Background())
+// Authenticate returns the key of the token when it isn't expired and can be used from the address
+func (s *apiKeyService) Authenticate(token string, address string) (domain.APIKey, error) {
+	key, err := s.r.FindByHash(hashToken(token))
 	if err != nil {
-		return "", fmt.Errorf("error getting Firestore client: %v", err)
+		return domain.APIKey{}, fmt.Errorf("error authenticating api key: %v", err)
+	}
+	now := time.Now()
+	if key.IsExpired(now) {
+		return domain.APIKey{}, fmt.Errorf("the api key `%s` expired", key.Prefix)
+	}
+	if !key.AllowsAddress(address) {
+		return domain.APIKey{}, fmt.Errorf("the api key `%s` can't be used from `%s`", key.Prefix, address)
 	}
-	defer func(client *firestore.Client) {
-		err := client.Close()
-		if err != nil {
-			log.Printf("error closing Firestore client: %v", err)
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedInterval {
+		key.LastUsedAt = &now
+		if err := s.r.UpdateLastUsed(key); err != nil {
+			log.Printf("WARNING: unable to save the last use of the api key `%s` due to %v", key.Prefix, err.Error())
 		}
-	}(client)
-	apiKeyDoc, err := client.Collection("apiKeys").Where("key", "==", apiKey).Documents(context.Background()).Next()
-	if err != nil {
-		return "", fmt.Errorf("error retrieving api key: %v", err)
 	}
-	return apiKeyDoc.Data()["userId"].(string), nil
+	return *key, nil
 }
 
-// RevokesApiKey revokes the api key
-func (s *apiKeyService) RevokesApiKey(userId string) error {
-	client, err := s.app.Firestore(context.Background())
-	if err != nil {
-		return fmt.Errorf("error getting Firestore client: %v", err)
-	}
-	defer func(client *firestore.Client) {
-		err := client.Close()
-		if err != nil {
-			log.Printf("error closing Firestore client: %v", err)
+// Revoke revokes a key of the user
+func (s *apiKeyService) Revoke(userId string, keyId string) error {
+	return s.r.Delete(userId, keyId)
+}
+
+// RevokeAll revokes every key of the user
+func (s *apiKeyService) RevokeAll(userId string) error {
+	return s.r.DeleteAllByUser(userId)
+}
+
+// hashToken returns the hash a token is stored and looked up by. Tokens are random, so a fast hash suffices.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseAllowedIPs validates the addresses and networks a key can be used from.
+func parseAllowedIPs(allowed []string) ([]string, error) {
+	var parsed []string
+	for _, value := range allowed {
+		value = strings.TrimSpace(value)
+		if _, network, err := net.ParseCIDR(value); err == nil {
+			parsed = append(parsed, network.String())
+			continue
+		}
+		if ip := net.ParseIP(value); ip != nil {
+			parsed = append(parsed, ip.String())
+			continue
 		}
-	}(client)
-	_, err = client.Collection("apiKeys").Doc(userId).Delete(context.Background())
-	return err
+		return nil, fmt.Errorf("invalid allowed address `%s`", value)
+	}
+	return parsed, nil
 }
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"net"
+	"time"
+)
+
+// APIKey struct is the representation of an API key of a user, stored in Postgres or Firestore. Only the hash of its
+// token is stored, the token is returned once when the key is created and the prefix identifies it afterwards.
+type APIKey struct {
+	ID         string     `gorm:"primaryKey" firestore:"id" json:"id"`
+	UserId     string     `gorm:"index" firestore:"userId" json:"userId"`
+	Name       string     `firestore:"name" json:"name"`
+	Prefix     string     `firestore:"prefix" json:"prefix"`
+	Hash       string     `gorm:"uniqueIndex" firestore:"hash" json:"-"`
+	Token      string     `gorm:"-" firestore:"-" json:"token,omitempty"`
+	Scopes     []string   `gorm:"serializer:json" firestore:"scopes" json:"scopes"`
+	AllowedIPs []string   `gorm:"serializer:json" firestore:"allowedIps" json:"allowedIps,omitempty"`
+	ExpiresAt  *time.Time `firestore:"expiresAt" json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `firestore:"lastUsedAt" json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `firestore:"createdAt" json:"createdAt"`
+}
+
+// IsExpired reports whether the key reached its expiration date
+func (k APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !k.ExpiresAt.After(now)
+}
+
+// AllowsAddress reports whether the key can be used from the address, any address when the allowlist is empty
+func (k APIKey) AllowsAddress(address string) bool {
+	if len(k.AllowedIPs) == 0 {
+		return true
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, allowed := range k.AllowedIPs {
+		if _, network, err := net.ParseCIDR(allowed); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if other := net.ParseIP(allowed); other != nil && other.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
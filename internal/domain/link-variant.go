@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LinkVariant struct is one of the weighted destinations a link rotates between.
+type LinkVariant struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	LinkRefer   uuid.UUID `gorm:"type:uuid;index" json:"linkRefer"`
+	Label       string    `json:"label"`
+	Destination string    `json:"destination"`
+	Weight      int       `json:"weight"`
+}
+
+// BeforeCreate initialize UUID, keeping the one of a variant being replaced so its stats are preserved.
+func (LinkVariant *LinkVariant) BeforeCreate(scope *gorm.DB) error {
+	if LinkVariant.ID != uuid.Nil {
+		return nil
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	scope.Statement.SetColumn("id", id)
+	return nil
+}
@@ -10,16 +10,51 @@ import (
 
 // Link struct is the representation of a shortened link.
 type Link struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	Original  string    `gorm:"index" json:"original"`
-	Title     string    `json:"title"`
-	Shortened string    `gorm:"uniqueIndex" json:"shortened"`
-	FinalURL  string    `json:"finalUrl"`
-	UserId    string    `gorm:"index" json:"userId"`
-	PageRefer string    `gorm:"type:text;index,unsigned" json:"pageRefer"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	Clicks    int       `json:"clicks"`
+	ID             uuid.UUID     `gorm:"type:uuid;primaryKey" json:"id"`
+	Original       string        `gorm:"index" json:"original"`
+	Title          string        `json:"title"`
+	Shortened      string        `gorm:"uniqueIndex" json:"shortened"`
+	FinalURL       string        `json:"finalUrl"`
+	UserId         string        `gorm:"index" json:"userId"`
+	WorkspaceId    *uuid.UUID    `gorm:"type:uuid;index" json:"workspaceId,omitempty"`
+	PageRefer      string        `gorm:"type:text;index,unsigned" json:"pageRefer"`
+	CreatedAt      time.Time     `json:"createdAt"`
+	UpdatedAt      time.Time     `json:"updatedAt"`
+	Clicks         int           `json:"clicks"`
+	ExpiresAt      *time.Time    `gorm:"index" json:"expiresAt,omitempty"`
+	MaxClicks      int           `json:"maxClicks"`
+	ArchivedAt     *time.Time    `gorm:"index" json:"archivedAt,omitempty"`
+	Password       string        `gorm:"-" json:"password,omitempty"`
+	PasswordHash   string        `json:"-"`
+	RemovePassword bool          `gorm:"-" json:"removePassword,omitempty"`
+	Variants       []LinkVariant `gorm:"foreignKey:LinkRefer;constraint:OnDelete:CASCADE" json:"variants,omitempty"`
+	Flagged        bool          `gorm:"index" json:"flagged"`
+	FlagReason     string        `json:"flagReason,omitempty"`
+	RedirectType   string        `json:"redirectType,omitempty"`
+	ForwardQuery   *bool         `json:"forwardQuery,omitempty"`
+	UTM            UTM           `gorm:"embedded;embeddedPrefix:utm_" json:"utm"`
+	App            AppLink       `gorm:"embedded;embeddedPrefix:app_" json:"app"`
+	OGTitle        string        `json:"ogTitle,omitempty"`
+	OGDescription  string        `json:"ogDescription,omitempty"`
+	OGImage        string        `json:"ogImage,omitempty"`
+}
+
+// UTM holds the campaign parameters appended to the destination of a link.
+type UTM struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// AppLink holds the mobile app URIs opened by a link, and the store pages used when the app is missing.
+type AppLink struct {
+	IOSURL          string `json:"iosUrl,omitempty"`
+	IOSStoreURL     string `json:"iosStoreUrl,omitempty"`
+	AndroidURL      string `json:"androidUrl,omitempty"`
+	AndroidPackage  string `json:"androidPackage,omitempty"`
+	AndroidStoreURL string `json:"androidStoreUrl,omitempty"`
 }
 
 // BeforeCreate initialize UUID and set 0 as initial value for links' click.
@@ -32,3 +67,31 @@ func (Link *Link) BeforeCreate(scope *gorm.DB) error {
 	scope.Statement.SetColumn("clicks", 0)
 	return nil
 }
+
+// IsExpired reports whether the link reached its expiration date or consumed its click budget.
+func (Link *Link) IsExpired(now time.Time) bool {
+	if Link.ExpiresAt != nil && !now.Before(*Link.ExpiresAt) {
+		return true
+	}
+	return Link.MaxClicks > 0 && Link.Clicks >= Link.MaxClicks
+}
+
+// IsArchived reports whether the link was archived by the expiration sweeper.
+func (Link *Link) IsArchived() bool {
+	return Link.ArchivedAt != nil
+}
+
+// IsProtected reports whether the link requires a password before redirecting.
+func (Link *Link) IsProtected() bool {
+	return Link.PasswordHash != ""
+}
+
+// HasApp reports whether the link opens a mobile app on any platform.
+func (Link *Link) HasApp() bool {
+	return Link.App.IOSURL != "" || Link.App.AndroidURL != ""
+}
+
+// ForwardsQuery reports whether the query parameters of the visitor are forwarded to the destination.
+func (Link *Link) ForwardsQuery() bool {
+	return Link.ForwardQuery != nil && *Link.ForwardQuery
+}
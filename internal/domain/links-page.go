@@ -7,18 +7,19 @@ import (
 )
 
 type LinksPage struct {
-	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	Links       []Link    `gorm:"foreignKey:PageRefer" json:"links"`
-	UserId      string    `gorm:"index" json:"userId"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	ImageURL    string    `json:"imageURL"`
-	Alias       string    `gorm:"uniqueIndex" json:"alias"`
-	Domain      string    `json:"domain"`
-	FinalURL    string    `json:"finalURL"`
-	Views       int       `json:"views"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Links       []Link     `gorm:"foreignKey:PageRefer" json:"links"`
+	UserId      string     `gorm:"index" json:"userId"`
+	WorkspaceId *uuid.UUID `gorm:"type:uuid;index" json:"workspaceId,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	ImageURL    string     `json:"imageURL"`
+	Alias       string     `gorm:"uniqueIndex" json:"alias"`
+	Domain      string     `json:"domain"`
+	FinalURL    string     `json:"finalURL"`
+	Views       int        `json:"views"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
 }
 
 // BeforeCreate initialize UUID.
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// RedirectRule struct is a conditional destination of a link, evaluated by priority before the default redirect.
+// Every condition left empty matches all visitors; list conditions accept comma separated values.
+type RedirectRule struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	LinkRefer   string    `gorm:"index" json:"linkRefer"`
+	Priority    int       `json:"priority"`
+	OS          string    `json:"os"`
+	Browser     string    `json:"browser"`
+	Language    string    `json:"language"`
+	Country     string    `json:"country"`
+	StartHour   *int      `json:"startHour,omitempty"`
+	EndHour     *int      `json:"endHour,omitempty"`
+	TimeZone    string    `json:"timeZone"`
+	Destination string    `json:"destination" binding:"required"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// BeforeCreate initialize UUID.
+func (RedirectRule *RedirectRule) BeforeCreate(scope *gorm.DB) error {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	scope.Statement.SetColumn("id", id)
+	return nil
+}
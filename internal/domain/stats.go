@@ -8,14 +8,29 @@ import (
 
 // Stats struct is the representation of a shortened link's or page stats.
 type Stats struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	LinkRefer string    `gorm:"index,unsigned" json:"linkRefer,omitempty"`
-	PageRefer string    `gorm:"index,unsigned" json:"pageRefer,omitempty"`
-	Timestamp time.Time `json:"timestamp"`
-	Os        string    `json:"os"`
-	Browser   string    `json:"browser"`
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	LinkRefer   string    `gorm:"index,unsigned" json:"linkRefer,omitempty"`
+	PageRefer   string    `gorm:"index,unsigned" json:"pageRefer,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	Os          string    `json:"os"`
+	Browser     string    `json:"browser"`
+	Variant     string    `gorm:"index" json:"variant,omitempty"`
+	Target      string    `gorm:"index" json:"target,omitempty"`
+	IsBot       bool      `gorm:"index;default:false;not null" json:"isBot"`
+	Referrer    string    `gorm:"index" json:"referrer,omitempty"`
+	Device      string    `gorm:"index" json:"device,omitempty"`
+	Language    string    `json:"language,omitempty"`
+	Country     string    `gorm:"index" json:"country,omitempty"`
+	Region      string    `json:"region,omitempty"`
+	VisitorHash string    `gorm:"index" json:"visitorHash,omitempty"`
 }
 
+// Destinations a click may be sent to.
+const (
+	TargetWeb = "web"
+	TargetApp = "app"
+)
+
 // BeforeCreate initialize UUID.
 func (Stats *Stats) BeforeCreate(scope *gorm.DB) error {
 	id, err := uuid.NewRandom()
@@ -25,3 +40,46 @@ func (Stats *Stats) BeforeCreate(scope *gorm.DB) error {
 	scope.Statement.SetColumn("id", id)
 	return nil
 }
+
+// VisitorSketch struct holds the HyperLogLog sketch of the unique visitors of a link, a page, the user links and
+// pages or the workspace links and pages on a day.
+type VisitorSketch struct {
+	Kind      string    `gorm:"primaryKey" json:"kind"`
+	Ref       string    `gorm:"primaryKey" json:"ref"`
+	Day       time.Time `gorm:"type:date;primaryKey" json:"day"`
+	Registers []byte    `json:"-"`
+}
+
+// Kinds of visitor sketches.
+const (
+	SketchLink           = "link"
+	SketchPage           = "page"
+	SketchUserLinks      = "user_links"
+	SketchUserPages      = "user_pages"
+	SketchWorkspaceLinks = "workspace_links"
+	SketchWorkspacePages = "workspace_pages"
+)
+
+// StatsRollup struct holds the number of visits of a link or a page in an hour sharing the same value of a
+// dimension. The dimension is empty for all the visits, and lists the dimensions of a grouping of several.
+type StatsRollup struct {
+	Kind      string    `gorm:"primaryKey" json:"kind"`
+	Ref       string    `gorm:"primaryKey" json:"ref"`
+	Bucket    time.Time `gorm:"primaryKey" json:"bucket"`
+	Dimension string    `gorm:"primaryKey" json:"dimension"`
+	Value     string    `gorm:"primaryKey" json:"value"`
+	IsBot     bool      `gorm:"primaryKey" json:"isBot"`
+	Count     int64     `json:"count"`
+}
+
+// StatsRollupCutover struct records when the stats started being rolled up as they are saved. The stats saved
+// before are rolled up by the backfill.
+type StatsRollupCutover struct {
+	ID int       `gorm:"primaryKey" json:"-"`
+	At time.Time `json:"at"`
+}
+
+// StatsRollupBackfill struct records an hour of the stats saved before the cutover whose rollups were backfilled.
+type StatsRollupBackfill struct {
+	Bucket time.Time `gorm:"primaryKey" json:"bucket"`
+}
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Roles of the members of a workspace. Each role is granted the permissions of the ones below it: viewers read the
+// links, pages and stats, editors also change the links and pages, admins also manage the members and invitations,
+// and owners also manage the admins and owners and delete the workspace.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// roleRanks orders the roles from the least to the most privileged.
+var roleRanks = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleAdmin: 3, RoleOwner: 4}
+
+// ValidRole reports whether the role is one of the workspace roles.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// Workspace struct is a team sharing its links and pages between its members.
+type Workspace struct {
+	ID        uuid.UUID         `gorm:"type:uuid;primaryKey" json:"id"`
+	Name      string            `json:"name"`
+	CreatedBy string            `json:"createdBy"`
+	Members   []WorkspaceMember `gorm:"foreignKey:WorkspaceId;constraint:OnDelete:CASCADE" json:"members,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// BeforeCreate initialize UUID.
+func (Workspace *Workspace) BeforeCreate(scope *gorm.DB) error {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	scope.Statement.SetColumn("id", id)
+	return nil
+}
+
+// WorkspaceMember struct is the role of a user in a workspace.
+type WorkspaceMember struct {
+	WorkspaceId uuid.UUID `gorm:"type:uuid;primaryKey" json:"workspaceId"`
+	UserId      string    `gorm:"primaryKey;index" json:"userId"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Can reports whether the role of the member grants the permissions of the role.
+func (m WorkspaceMember) Can(role string) bool {
+	return roleRanks[m.Role] >= roleRanks[role]
+}
+
+// CanManage reports whether the member can grant, change or remove the role: owners manage every role, the other
+// members only the roles below their own.
+func (m WorkspaceMember) CanManage(role string) bool {
+	return m.Role == RoleOwner || roleRanks[m.Role] > roleRanks[role]
+}
+
+// WorkspaceInvitation struct invites a user to join a workspace with a role. Whoever holds its ID can accept it,
+// once and before it expires; the email only tells the admins who it was sent to.
+type WorkspaceInvitation struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	WorkspaceId uuid.UUID  `gorm:"type:uuid;index" json:"workspaceId"`
+	Workspace   *Workspace `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Email       string     `json:"email,omitempty"`
+	Role        string     `json:"role"`
+	InvitedBy   string     `json:"invitedBy"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	AcceptedBy  string     `json:"acceptedBy,omitempty"`
+	AcceptedAt  *time.Time `json:"acceptedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// BeforeCreate initialize UUID.
+func (WorkspaceInvitation *WorkspaceInvitation) BeforeCreate(scope *gorm.DB) error {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	scope.Statement.SetColumn("id", id)
+	return nil
+}
+
+// IsPending reports whether the invitation can still be accepted
+func (i WorkspaceInvitation) IsPending(now time.Time) bool {
+	return i.AcceptedAt == nil && i.ExpiresAt.After(now)
+}
@@ -0,0 +1,240 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Format is the encoding of an export.
+type Format string
+
+const (
+	CSV    Format = "csv"
+	JSON   Format = "json"
+	NDJSON Format = "ndjson"
+)
+
+// ParseFormat returns the export format matching the name, JSON by default.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", JSON:
+		return JSON, nil
+	case CSV, NDJSON:
+		return Format(name), nil
+	}
+	return "", fmt.Errorf("invalid export format `%s` provided", name)
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case NDJSON:
+		return "application/x-ndjson"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// encoder writes the exported records one at a time, as they are read from the database.
+type encoder interface {
+	Begin() error
+	Section(name string) error
+	Link(link domain.Link) error
+	Page(linksPage domain.LinksPage) error
+	Stats(stats domain.Stats) error
+	End() error
+}
+
+func newEncoder(w io.Writer, format Format) (encoder, error) {
+	buffered := bufio.NewWriter(w)
+	switch format {
+	case CSV:
+		return &csvEncoder{w: csv.NewWriter(buffered), buffered: buffered}, nil
+	case NDJSON:
+		return &ndjsonEncoder{w: buffered, enc: json.NewEncoder(buffered)}, nil
+	case JSON:
+		return &jsonEncoder{w: buffered}, nil
+	}
+	return nil, fmt.Errorf("invalid export format `%s` provided", format)
+}
+
+// jsonEncoder writes a single object holding one array per section.
+type jsonEncoder struct {
+	w        *bufio.Writer
+	sections int
+	items    int
+}
+
+func (e *jsonEncoder) Begin() error {
+	return e.w.WriteByte('{')
+}
+
+func (e *jsonEncoder) Section(name string) error {
+	if e.sections > 0 {
+		if _, err := e.w.WriteString("],"); err != nil {
+			return err
+		}
+	}
+	e.sections++
+	e.items = 0
+	_, err := fmt.Fprintf(e.w, "%q:[", name)
+	return err
+}
+
+func (e *jsonEncoder) write(v interface{}) error {
+	if e.items > 0 {
+		if err := e.w.WriteByte(','); err != nil {
+			return err
+		}
+	}
+	e.items++
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonEncoder) Link(link domain.Link) error           { return e.write(link) }
+func (e *jsonEncoder) Page(linksPage domain.LinksPage) error { return e.write(linksPage) }
+func (e *jsonEncoder) Stats(stats domain.Stats) error        { return e.write(stats) }
+
+func (e *jsonEncoder) End() error {
+	if e.sections > 0 {
+		if err := e.w.WriteByte(']'); err != nil {
+			return err
+		}
+	}
+	if err := e.w.WriteByte('}'); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// ndjsonEncoder writes one object per line, tagged with the record type.
+type ndjsonEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+type ndjsonRecord struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+func (e *ndjsonEncoder) Begin() error              { return nil }
+func (e *ndjsonEncoder) Section(name string) error { return nil }
+func (e *ndjsonEncoder) Link(link domain.Link) error {
+	return e.enc.Encode(ndjsonRecord{Type: "link", Data: link})
+}
+func (e *ndjsonEncoder) Page(linksPage domain.LinksPage) error {
+	return e.enc.Encode(ndjsonRecord{Type: "page", Data: linksPage})
+}
+func (e *ndjsonEncoder) Stats(stats domain.Stats) error {
+	return e.enc.Encode(ndjsonRecord{Type: "stats", Data: stats})
+}
+func (e *ndjsonEncoder) End() error { return e.w.Flush() }
+
+// csvEncoder writes every record type in the same table, the type column tells which columns are filled.
+type csvEncoder struct {
+	w        *csv.Writer
+	buffered *bufio.Writer
+}
+
+var csvHeader = []string{
+	"type", "id", "user_id", "title", "original", "shortened", "final_url", "alias", "domain", "description",
+	"page_refer", "link_refer", "clicks", "views", "expires_at", "max_clicks", "archived_at", "timestamp", "os",
+	"browser", "device", "language", "referrer", "country", "region", "visitor_hash", "variant", "target", "is_bot",
+	"created_at",
+}
+
+// csvRecord holds the values of a record by column name
+type csvRecord map[string]string
+
+func (e *csvEncoder) write(record csvRecord) error {
+	row := make([]string, len(csvHeader))
+	for i, column := range csvHeader {
+		row[i] = record[column]
+	}
+	return e.w.Write(row)
+}
+
+func (e *csvEncoder) Begin() error              { return e.w.Write(csvHeader) }
+func (e *csvEncoder) Section(name string) error { return nil }
+
+func (e *csvEncoder) Link(link domain.Link) error {
+	return e.write(csvRecord{
+		"type":        "link",
+		"id":          link.ID.String(),
+		"user_id":     link.UserId,
+		"title":       link.Title,
+		"original":    link.Original,
+		"shortened":   link.Shortened,
+		"final_url":   link.FinalURL,
+		"page_refer":  link.PageRefer,
+		"clicks":      strconv.Itoa(link.Clicks),
+		"expires_at":  formatTime(link.ExpiresAt),
+		"max_clicks":  strconv.Itoa(link.MaxClicks),
+		"archived_at": formatTime(link.ArchivedAt),
+		"created_at":  link.CreatedAt.Format(time.RFC3339),
+	})
+}
+
+func (e *csvEncoder) Page(linksPage domain.LinksPage) error {
+	return e.write(csvRecord{
+		"type":        "page",
+		"id":          linksPage.ID.String(),
+		"user_id":     linksPage.UserId,
+		"title":       linksPage.Title,
+		"final_url":   linksPage.FinalURL,
+		"alias":       linksPage.Alias,
+		"domain":      linksPage.Domain,
+		"description": linksPage.Description,
+		"views":       strconv.Itoa(linksPage.Views),
+		"created_at":  linksPage.CreatedAt.Format(time.RFC3339),
+	})
+}
+
+func (e *csvEncoder) Stats(stats domain.Stats) error {
+	return e.write(csvRecord{
+		"type":         "stats",
+		"id":           stats.ID.String(),
+		"page_refer":   stats.PageRefer,
+		"link_refer":   stats.LinkRefer,
+		"timestamp":    stats.Timestamp.Format(time.RFC3339),
+		"os":           stats.Os,
+		"browser":      stats.Browser,
+		"device":       stats.Device,
+		"language":     stats.Language,
+		"referrer":     stats.Referrer,
+		"country":      stats.Country,
+		"region":       stats.Region,
+		"visitor_hash": stats.VisitorHash,
+		"variant":      stats.Variant,
+		"target":       stats.Target,
+		"is_bot":       strconv.FormatBool(stats.IsBot),
+	})
+}
+
+func (e *csvEncoder) End() error {
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return err
+	}
+	return e.buffered.Flush()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,66 @@
+package export
+
+import (
+	"fmt"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/link"
+	links_page "github.com/ronilsonalves/5lnk/internal/links-page"
+	"github.com/ronilsonalves/5lnk/internal/stats"
+	"io"
+	"log"
+)
+
+type Service interface {
+	Export(w io.Writer, userId string, format Format, withStats bool) error
+}
+
+type exportService struct {
+	lr  link.Repository
+	lpr links_page.Repository
+	sr  stats.Repository
+}
+
+// NewExportService creates a new export service
+func NewExportService(lr link.Repository, lpr links_page.Repository, sr stats.Repository) Service {
+	return &exportService{lr: lr, lpr: lpr, sr: sr}
+}
+
+// Export streams all links, links pages and optionally stats of a user to w in the given format
+func (s *exportService) Export(w io.Writer, userId string, format Format, withStats bool) error {
+	enc, err := newEncoder(w, format)
+	if err != nil {
+		return err
+	}
+	log.Printf("INFO: exporting data of user `%s` as %s...", userId, format)
+
+	if err := enc.Begin(); err != nil {
+		return err
+	}
+	if err := enc.Section("links"); err != nil {
+		return err
+	}
+	if err := s.lr.StreamAllByUser(userId, func(link domain.Link) error {
+		return enc.Link(link)
+	}); err != nil {
+		return fmt.Errorf("unable to export links: %v", err)
+	}
+	if err := enc.Section("pages"); err != nil {
+		return err
+	}
+	if err := s.lpr.StreamAllByUser(userId, func(linksPage domain.LinksPage) error {
+		return enc.Page(linksPage)
+	}); err != nil {
+		return fmt.Errorf("unable to export links pages: %v", err)
+	}
+	if withStats {
+		if err := enc.Section("stats"); err != nil {
+			return err
+		}
+		if err := s.sr.StreamStatsByUser(userId, func(stats domain.Stats) error {
+			return enc.Stats(stats)
+		}); err != nil {
+			return fmt.Errorf("unable to export stats: %v", err)
+		}
+	}
+	return enc.End()
+}
@@ -0,0 +1,103 @@
+package link
+
+import (
+	"fmt"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/urlsafety"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// unsafeAppSchemes are the schemes never accepted as app URIs.
+var unsafeAppSchemes = map[string]bool{"javascript": true, "data": true, "vbscript": true, "file": true, "blob": true, "intent": true}
+
+// androidPackage matches a valid Android application ID.
+var androidPackage = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(\.[A-Za-z][A-Za-z0-9_]*)+$`)
+
+// toAppLink validates the app URIs of a request and converts them to an app link.
+func toAppLink(request web.AppLink, urls *urlsafety.Checker) (domain.AppLink, error) {
+	app := domain.AppLink{
+		IOSURL:          strings.TrimSpace(request.IOSURL),
+		IOSStoreURL:     strings.TrimSpace(request.IOSStoreURL),
+		AndroidURL:      strings.TrimSpace(request.AndroidURL),
+		AndroidPackage:  strings.TrimSpace(request.AndroidPackage),
+		AndroidStoreURL: strings.TrimSpace(request.AndroidStoreURL),
+	}
+	return app, validateAppLink(&app, urls)
+}
+
+// validateAppLink checks the app URIs, the Android package and normalises the store URLs of an app link. App URIs
+// using http or https, e.g. universal links, are checked like the destination.
+func validateAppLink(app *domain.AppLink, urls *urlsafety.Checker) error {
+	for _, uri := range []*string{&app.IOSURL, &app.AndroidURL} {
+		if *uri == "" {
+			continue
+		}
+		parsed, err := url.Parse(*uri)
+		if err != nil || parsed.Scheme == "" || unsafeAppSchemes[strings.ToLower(parsed.Scheme)] {
+			return fmt.Errorf("invalid app uri `%s` provided", *uri)
+		}
+		if scheme := strings.ToLower(parsed.Scheme); scheme == "http" || scheme == "https" {
+			normalized, err := urls.Check(*uri)
+			if err != nil {
+				return fmt.Errorf("invalid app uri: %v", err)
+			}
+			*uri = normalized
+		}
+	}
+	if app.AndroidPackage != "" && !androidPackage.MatchString(app.AndroidPackage) {
+		return fmt.Errorf("invalid android package `%s` provided", app.AndroidPackage)
+	}
+	for _, store := range []*string{&app.IOSStoreURL, &app.AndroidStoreURL} {
+		if *store == "" {
+			continue
+		}
+		normalized, err := urls.Check(*store)
+		if err != nil {
+			return fmt.Errorf("invalid store url: %v", err)
+		}
+		*store = normalized
+	}
+	return nil
+}
+
+// AppDestination returns the URI opening the app of the link on the platform of the visitor, and the URL the
+// visitor is sent to when the app is not installed: the store page, or the web destination.
+func AppDestination(lnk domain.Link, platform, destination string) (string, string, bool) {
+	switch platform {
+	case "ios":
+		if lnk.App.IOSURL == "" {
+			return "", "", false
+		}
+		return lnk.App.IOSURL, fallback(lnk.App.IOSStoreURL, destination), true
+	case "android":
+		if lnk.App.AndroidURL == "" {
+			return "", "", false
+		}
+		fallbackURL := fallback(lnk.App.AndroidStoreURL, destination)
+		return intentURL(lnk.App.AndroidURL, lnk.App.AndroidPackage, fallbackURL), fallbackURL, true
+	}
+	return "", "", false
+}
+
+// intentURL wraps an app URI in an Android intent, letting the browser open the fallback by itself when the
+// app is not installed. Without package the app URI is used as is.
+func intentURL(uri, pkg, fallbackURL string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || pkg == "" {
+		return uri
+	}
+	target := strings.TrimPrefix(strings.TrimPrefix(uri, parsed.Scheme+":"), "//")
+	target, _, _ = strings.Cut(target, "#")
+	return "intent://" + target + "#Intent;scheme=" + parsed.Scheme + ";package=" + pkg +
+		";S.browser_fallback_url=" + url.QueryEscape(fallbackURL) + ";end"
+}
+
+func fallback(store, destination string) string {
+	if store != "" {
+		return store
+	}
+	return destination
+}
@@ -0,0 +1,69 @@
+package link
+
+import (
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/urlsafety"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateAppLink(t *testing.T) {
+	blocklist := filepath.Join(t.TempDir(), "blocklist.txt")
+	if err := os.WriteFile(blocklist, []byte("evil.example\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	urls := urlsafety.NewChecker(false)
+	if err := urls.LoadBlocklist(blocklist); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		app  domain.AppLink
+		want domain.AppLink
+		err  string
+	}{
+		{
+			name: "custom schemes",
+			app:  domain.AppLink{IOSURL: "myapp://item/1", AndroidURL: "myapp://item/1", AndroidPackage: "com.example.app"},
+			want: domain.AppLink{IOSURL: "myapp://item/1", AndroidURL: "myapp://item/1", AndroidPackage: "com.example.app"},
+		},
+		{
+			name: "universal links normalised",
+			app:  domain.AppLink{IOSURL: "HTTPS://Example.com/item/1", AndroidURL: "http://example.com:80/item"},
+			want: domain.AppLink{IOSURL: "https://example.com/item/1", AndroidURL: "http://example.com/item"},
+		},
+		{
+			name: "store urls normalised",
+			app:  domain.AppLink{IOSStoreURL: "apps.apple.com/app/id1", AndroidStoreURL: "https://play.google.com/store/apps/details?id=com.example.app"},
+			want: domain.AppLink{IOSStoreURL: "https://apps.apple.com/app/id1", AndroidStoreURL: "https://play.google.com/store/apps/details?id=com.example.app"},
+		},
+		{name: "private ios link", app: domain.AppLink{IOSURL: "http://192.168.0.1/admin"}, err: "private networks"},
+		{name: "private android link", app: domain.AppLink{AndroidURL: "https://localhost/item"}, err: "private networks"},
+		{name: "blocked android link", app: domain.AppLink{AndroidURL: "https://www.evil.example/item"}, err: "blocked"},
+		{name: "unsafe scheme", app: domain.AppLink{IOSURL: "javascript:alert(1)"}, err: "invalid app uri"},
+		{name: "intent scheme", app: domain.AppLink{AndroidURL: "intent://item#Intent;end"}, err: "invalid app uri"},
+		{name: "without scheme", app: domain.AppLink{IOSURL: "item/1"}, err: "invalid app uri"},
+		{name: "invalid package", app: domain.AppLink{AndroidURL: "myapp://item", AndroidPackage: "example"}, err: "invalid android package"},
+		{name: "private store url", app: domain.AppLink{IOSStoreURL: "http://10.0.0.1/store"}, err: "invalid store url"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := tt.app
+			err := validateAppLink(&app, urls)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("validateAppLink() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateAppLink() error = %v", err)
+			}
+			if app != tt.want {
+				t.Errorf("validateAppLink() = %+v, want %+v", app, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package link
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxChainHops is how many redirects are followed while resolving the destination of a link.
+const maxChainHops = 10
+
+// shortenerTimeout bounds the request made to resolve a link of another shortener.
+const shortenerTimeout = time.Second * 3
+
+// knownShorteners are the hosts of other URL shorteners resolved when enabled.
+var knownShorteners = map[string]bool{
+	"bit.ly": true, "bitly.com": true, "buff.ly": true, "cutt.ly": true, "goo.gl": true, "is.gd": true,
+	"ow.ly": true, "rb.gy": true, "rebrand.ly": true, "shorturl.at": true, "t.co": true, "t.ly": true,
+	"tiny.cc": true, "tinyurl.com": true, "v.gd": true,
+}
+
+// ChainPolicy decides what happens to destinations pointing at another shortened URL: they are either
+// flattened to their ultimate target or rejected. Loops are always rejected.
+type ChainPolicy struct {
+	domains   map[string]bool
+	flatten   bool
+	client    *http.Client
+	rules     func(linkId uuid.UUID) ([]string, error)
+	shortener func(host string) bool
+}
+
+// NewChainPolicy creates a chain policy for the given short domains. When resolveShorteners is set, links of
+// other known shorteners are resolved too. rules returns the destinations of the redirect rules of a link, links
+// with rules are never flattened since their destination depends on the visitor.
+func NewChainPolicy(domains []string, flatten, resolveShorteners bool, rules func(linkId uuid.UUID) ([]string, error)) *ChainPolicy {
+	p := &ChainPolicy{
+		domains:   make(map[string]bool),
+		flatten:   flatten,
+		rules:     rules,
+		shortener: func(string) bool { return false },
+	}
+	for _, d := range domains {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			p.domains[d] = true
+		}
+	}
+	if resolveShorteners {
+		p.shortener = func(host string) bool { return knownShorteners[host] }
+		p.client = &http.Client{
+			Timeout: shortenerTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	return p
+}
+
+// NewChainPolicyFromEnv creates a chain policy from the SHORT_DOMAINS, LINK_CHAIN_POLICY and
+// LINK_RESOLVE_SHORTENERS environment variables.
+func NewChainPolicyFromEnv(rules func(linkId uuid.UUID) ([]string, error)) (*ChainPolicy, error) {
+	var flatten bool
+	switch policy := os.Getenv("LINK_CHAIN_POLICY"); policy {
+	case "", "flatten":
+		flatten = true
+	case "reject":
+		flatten = false
+	default:
+		return nil, fmt.Errorf("unknown link chain policy `%s`, use flatten or reject", policy)
+	}
+	return NewChainPolicy(strings.Split(os.Getenv("SHORT_DOMAINS"), ","), flatten, os.Getenv("LINK_RESOLVE_SHORTENERS") == "true", rules), nil
+}
+
+// shortenedOf returns the shortened URL a destination points at when its host is one of our domains.
+func (p *ChainPolicy) shortenedOf(destination *url.URL, domains map[string]bool) (string, bool) {
+	host := strings.ToLower(destination.Hostname())
+	if !p.domains[host] && !domains[host] {
+		return "", false
+	}
+	shortened, _, _ := strings.Cut(strings.TrimPrefix(destination.Path, "/"), "/")
+	return shortened, shortened != ""
+}
+
+// canFlatten reports whether a link always redirects to its original URL, so pointing at it is the same
+// as pointing at its original URL.
+func (p *ChainPolicy) canFlatten(link *domain.Link) bool {
+	if link.IsProtected() || len(link.Variants) > 0 || link.ExpiresAt != nil || link.MaxClicks != 0 || link.Flagged ||
+		link.IsArchived() {
+		return false
+	}
+	rules, err := p.ruleDestinations(link.ID)
+	return err == nil && len(rules) == 0
+}
+
+// ruleDestinations returns the destinations of the redirect rules of a link.
+func (p *ChainPolicy) ruleDestinations(linkId uuid.UUID) ([]string, error) {
+	if p.rules == nil {
+		return nil, nil
+	}
+	return p.rules(linkId)
+}
+
+// destinationsOf returns every destination a link may redirect to: its original URL, its variants and the
+// destinations of its redirect rules.
+func (p *ChainPolicy) destinationsOf(link *domain.Link) []string {
+	destinations := []string{link.Original}
+	for _, v := range link.Variants {
+		destinations = append(destinations, v.Destination)
+	}
+	rules, _ := p.ruleDestinations(link.ID)
+	return append(destinations, rules...)
+}
+
+// resolveShortener returns the location a link of another shortener redirects to.
+func (p *ChainPolicy) resolveShortener(destination *url.URL) (string, bool) {
+	response, err := p.client.Head(destination.String())
+	if err != nil {
+		return "", false
+	}
+	defer response.Body.Close()
+	location, err := response.Location()
+	if err != nil || response.StatusCode < 300 || response.StatusCode > 399 {
+		return "", false
+	}
+	return location.String(), true
+}
+
+// resolveDestination follows the destination while it points at our own shortened URLs, or at other known
+// shorteners, and returns the ultimate target. self is the shortened URL of the link being saved, reaching
+// it again means the link would redirect to itself.
+func (s *linkService) resolveDestination(destination, shortDomain, self string) (string, error) {
+	domains := map[string]bool{strings.ToLower(shortDomain): true}
+	seen := map[string]bool{}
+	if self != "" {
+		seen[self] = true
+	}
+
+	requested, resolved := destination, destination
+	for hop := 0; ; hop++ {
+		if hop == maxChainHops {
+			return "", fmt.Errorf("the url `%s` redirects too many times", requested)
+		}
+		target, err := url.Parse(destination)
+		if err != nil {
+			return resolved, nil
+		}
+
+		shortened, own := s.chains.shortenedOf(target, domains)
+		if !own {
+			if !s.chains.shortener(strings.ToLower(target.Hostname())) {
+				return resolved, nil
+			}
+			if !s.chains.flatten {
+				return "", fmt.Errorf("the url `%s` is already a shortened URL", destination)
+			}
+			location, ok := s.chains.resolveShortener(target)
+			if !ok {
+				return resolved, nil
+			}
+			if destination, err = s.urls.Check(location); err != nil {
+				return "", err
+			}
+			resolved = destination
+			continue
+		}
+
+		if seen[shortened] {
+			return "", fmt.Errorf("the url `%s` creates a redirect loop", requested)
+		}
+		seen[shortened] = true
+		next, err := s.repo.FindByShortened(shortened)
+		if err != nil {
+			// Not a shortened URL, e.g. a links page
+			return resolved, nil
+		}
+		if !s.chains.flatten {
+			return "", fmt.Errorf("the url `%s` is already a shortened URL", destination)
+		}
+		if !s.chains.canFlatten(next) {
+			// The destination is kept, but every destination of the link is still followed to find loops
+			if err := s.followLoops(next, domains, seen, map[string]bool{}, hop+1, requested); err != nil {
+				return "", err
+			}
+			return resolved, nil
+		}
+		destination = next.Original
+		resolved = destination
+	}
+}
+
+// followLoops walks the original URL, variants and redirect rules of a link, failing when a walk reaches a link of
+// the path again. Links already walked without a loop are skipped.
+func (s *linkService) followLoops(link *domain.Link, domains, path, walked map[string]bool, hop int, requested string) error {
+	for _, destination := range s.chains.destinationsOf(link) {
+		target, err := url.Parse(destination)
+		if err != nil {
+			continue
+		}
+		shortened, own := s.chains.shortenedOf(target, domains)
+		if !own || walked[shortened] {
+			continue
+		}
+		if path[shortened] {
+			return fmt.Errorf("the url `%s` creates a redirect loop", requested)
+		}
+		if hop+1 >= maxChainHops {
+			return fmt.Errorf("the url `%s` redirects too many times", requested)
+		}
+		next, err := s.repo.FindByShortened(shortened)
+		if err != nil {
+			continue
+		}
+		path[shortened] = true
+		err = s.followLoops(next, domains, path, walked, hop+1, requested)
+		delete(path, shortened)
+		if err != nil {
+			return err
+		}
+		walked[shortened] = true
+	}
+	return nil
+}
+
+// ResolveDestination checks a variant or redirect rule destination of a link, resolving it like the original URL
+// so it can't point back at the link.
+func (s *linkService) ResolveDestination(linkId uuid.UUID, destination string) (string, error) {
+	link, err := s.repo.FindByID(linkId)
+	if err != nil {
+		return "", err
+	}
+	if destination, err = s.urls.Check(destination); err != nil {
+		return "", err
+	}
+	return s.resolveDestination(destination, shortDomainOf(link), link.Shortened)
+}
+
+// shortDomainOf returns the domain of the shortened URL of a link.
+func shortDomainOf(link *domain.Link) string {
+	if finalURL, err := url.Parse(link.FinalURL); err == nil {
+		return finalURL.Hostname()
+	}
+	return ""
+}
+
+// DetectCycles finds the links redirecting to each other in a loop, through their original URLs, variants or
+// redirect rules. Each loop is reported once with the shortened URLs in redirect order.
+func (s *linkService) DetectCycles() ([][]string, error) {
+	domains, err := s.repo.FindDomains()
+	if err != nil {
+		return nil, err
+	}
+	own := make(map[string]bool)
+	for _, d := range domains {
+		own[strings.ToLower(d)] = true
+	}
+	for d := range s.chains.domains {
+		domains = append(domains, d)
+	}
+	redirects, err := s.repo.FindRedirectsToHosts(domains)
+	if err != nil {
+		return nil, err
+	}
+
+	next := make(map[string][]string)
+	for _, r := range redirects {
+		if target, err := url.Parse(r.Destination); err == nil {
+			if shortened, ok := s.chains.shortenedOf(target, own); ok && !contains(next[r.Shortened], shortened) {
+				next[r.Shortened] = append(next[r.Shortened], shortened)
+			}
+		}
+	}
+	return findCycles(next), nil
+}
+
+// findCycles walks the redirects between shortened URLs depth first, every redirect back to a link of the walk
+// closes a loop.
+func findCycles(next map[string][]string) [][]string {
+	starts := make([]string, 0, len(next))
+	for start := range next {
+		starts = append(starts, start)
+	}
+	sort.Strings(starts)
+
+	cycles := make([][]string, 0)
+	visited := make(map[string]bool, len(next))
+	position := make(map[string]int)
+	var order []string
+	var walk func(current string)
+	walk = func(current string) {
+		visited[current] = true
+		position[current] = len(order)
+		order = append(order, current)
+		for _, target := range next[current] {
+			if i, ok := position[target]; ok {
+				cycles = append(cycles, append([]string(nil), order[i:]...))
+			} else if !visited[target] {
+				walk(target)
+			}
+		}
+		delete(position, current)
+		order = order[:len(order)-1]
+	}
+	for _, start := range starts {
+		if !visited[start] {
+			walk(start)
+		}
+	}
+	return cycles
+}
+
+// contains reports whether the shortened URL is in the list.
+func contains(list []string, shortened string) bool {
+	for _, s := range list {
+		if s == shortened {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package link
+
+import (
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/urlsafety"
+	"gorm.io/gorm"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// chainRepository holds the links by shortened URL, the other methods of the repository aren't used by chains.
+type chainRepository struct {
+	Repository
+	links     map[string]*domain.Link
+	redirects []Redirect
+}
+
+func (r *chainRepository) FindByShortened(shortened string) (*domain.Link, error) {
+	if link, ok := r.links[shortened]; ok {
+		return link, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *chainRepository) FindByID(id uuid.UUID) (*domain.Link, error) {
+	for _, link := range r.links {
+		if link.ID == id {
+			return link, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *chainRepository) FindDomains() ([]string, error) {
+	return []string{"5lnk.live"}, nil
+}
+
+func (r *chainRepository) FindRedirectsToHosts([]string) ([]Redirect, error) {
+	return r.redirects, nil
+}
+
+// links creates links keyed by their shortened URL from their original URLs.
+func links(originals map[string]string) map[string]*domain.Link {
+	result := make(map[string]*domain.Link, len(originals))
+	for shortened, original := range originals {
+		result[shortened] = &domain.Link{ID: uuid.New(), Shortened: shortened, Original: original, FinalURL: "https://5lnk.live/" + shortened}
+	}
+	return result
+}
+
+func TestResolveDestination(t *testing.T) {
+	chain := make(map[string]string)
+	for i := 0; i < maxChainHops+1; i++ {
+		chain["hop"+strconv.Itoa(i)] = "https://5lnk.live/hop" + strconv.Itoa(i+1)
+	}
+	chain["hop"+strconv.Itoa(maxChainHops+1)] = "https://example.com"
+
+	tests := []struct {
+		name        string
+		links       map[string]*domain.Link
+		rules       map[string][]string
+		variants    map[string][]string
+		reject      bool
+		destination string
+		self        string
+		want        string
+		err         string
+	}{
+		{
+			name:        "external destination",
+			links:       links(map[string]string{"a": "https://example.com"}),
+			destination: "https://example.com/page",
+			want:        "https://example.com/page",
+		},
+		{
+			name:        "flattened to the original of the link",
+			links:       links(map[string]string{"a": "https://example.com"}),
+			destination: "https://5lnk.live/a",
+			want:        "https://example.com",
+		},
+		{
+			name:        "flattened through a chain",
+			links:       links(map[string]string{"a": "https://5lnk.live/b", "b": "https://example.com"}),
+			destination: "https://5LNK.live/a",
+			want:        "https://example.com",
+		},
+		{
+			name:        "path of another resource",
+			links:       links(map[string]string{"a": "https://example.com"}),
+			destination: "https://5lnk.live/my-page",
+			want:        "https://5lnk.live/my-page",
+		},
+		{
+			name:        "rejected by the policy",
+			links:       links(map[string]string{"a": "https://example.com"}),
+			reject:      true,
+			destination: "https://5lnk.live/a",
+			err:         "already a shortened URL",
+		},
+		{
+			name:        "pointing at itself",
+			links:       links(map[string]string{"self": "https://example.com"}),
+			destination: "https://5lnk.live/self",
+			self:        "self",
+			err:         "redirect loop",
+		},
+		{
+			name:        "loop through another link",
+			links:       links(map[string]string{"self": "https://example.com", "a": "https://5lnk.live/b", "b": "https://5lnk.live/self"}),
+			destination: "https://5lnk.live/a",
+			self:        "self",
+			err:         "redirect loop",
+		},
+		{
+			name:        "loop through a variant",
+			links:       links(map[string]string{"self": "https://example.com", "a": "https://example.com"}),
+			variants:    map[string][]string{"a": {"https://example.org", "https://5lnk.live/self"}},
+			destination: "https://5lnk.live/a",
+			self:        "self",
+			err:         "redirect loop",
+		},
+		{
+			name:        "loop through a redirect rule",
+			links:       links(map[string]string{"self": "https://example.com", "a": "https://example.com"}),
+			rules:       map[string][]string{"a": {"https://5lnk.live/self"}},
+			destination: "https://5lnk.live/a",
+			self:        "self",
+			err:         "redirect loop",
+		},
+		{
+			name:        "link with variants kept",
+			links:       links(map[string]string{"self": "https://example.com", "a": "https://example.com"}),
+			variants:    map[string][]string{"a": {"https://example.org"}},
+			destination: "https://5lnk.live/a",
+			self:        "self",
+			want:        "https://5lnk.live/a",
+		},
+		{
+			name:        "too many hops",
+			links:       links(chain),
+			destination: "https://5lnk.live/hop0",
+			err:         "too many times",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for shortened, destinations := range tt.variants {
+				for _, destination := range destinations {
+					tt.links[shortened].Variants = append(tt.links[shortened].Variants, domain.LinkVariant{Destination: destination, Weight: 1})
+				}
+			}
+			rules := make(map[string][]string)
+			for shortened, destinations := range tt.rules {
+				rules[tt.links[shortened].ID.String()] = destinations
+			}
+			chains := NewChainPolicy([]string{"5lnk.live"}, !tt.reject, false, func(linkId uuid.UUID) ([]string, error) {
+				return rules[linkId.String()], nil
+			})
+			s := &linkService{repo: &chainRepository{links: tt.links}, urls: urlsafety.NewChecker(false), chains: chains}
+
+			got, err := s.resolveDestination(tt.destination, "5lnk.live", tt.self)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("resolveDestination() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveDestination() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveDestination() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindCycles(t *testing.T) {
+	tests := []struct {
+		name string
+		next map[string][]string
+		want [][]string
+	}{
+		{
+			name: "no redirects",
+			next: map[string][]string{},
+			want: [][]string{},
+		},
+		{
+			name: "chain without loop",
+			next: map[string][]string{"a": {"b"}, "b": {"c"}},
+			want: [][]string{},
+		},
+		{
+			name: "link redirecting to itself",
+			next: map[string][]string{"a": {"a"}},
+			want: [][]string{{"a"}},
+		},
+		{
+			name: "two links",
+			next: map[string][]string{"b": {"a"}, "a": {"b"}},
+			want: [][]string{{"a", "b"}},
+		},
+		{
+			name: "loop after a chain",
+			next: map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"d"}, "d": {"b"}},
+			want: [][]string{{"b", "c", "d"}},
+		},
+		{
+			name: "separate loops",
+			next: map[string][]string{"a": {"b"}, "b": {"a"}, "c": {"d"}, "d": {"c"}},
+			want: [][]string{{"a", "b"}, {"c", "d"}},
+		},
+		{
+			name: "loops sharing a link",
+			next: map[string][]string{"a": {"b", "c"}, "b": {"a"}, "c": {"a"}},
+			want: [][]string{{"a", "b"}, {"a", "c"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findCycles(tt.next); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findCycles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectCycles(t *testing.T) {
+	repo := &chainRepository{redirects: []Redirect{
+		{Shortened: "a", Destination: "https://5lnk.live/b"},
+		{Shortened: "a", Destination: "https://5lnk.live/b?utm_source=x"},
+		{Shortened: "b", Destination: "https://go.example.com/a"},
+		{Shortened: "c", Destination: "https://other.example.com/c"},
+		{Shortened: "d", Destination: "https://5lnk.live/d"},
+	}}
+	chains := NewChainPolicy([]string{"go.example.com"}, true, false, nil)
+	s := &linkService{repo: repo, chains: chains}
+
+	got, err := s.DetectCycles()
+	if err != nil {
+		t.Fatalf("DetectCycles() error = %v", err)
+	}
+	want := [][]string{{"a", "b"}, {"d"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DetectCycles() = %v, want %v", got, want)
+	}
+}
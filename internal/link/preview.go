@@ -0,0 +1,32 @@
+package link
+
+import (
+	"fmt"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/urlsafety"
+	"unicode/utf8"
+)
+
+// Maximum lengths of the social preview texts of a link.
+const (
+	maxOGTitleLength       = 200
+	maxOGDescriptionLength = 1000
+)
+
+// validatePreview checks the social preview of a link, normalising its image URL.
+func validatePreview(link *domain.Link, urls *urlsafety.Checker) error {
+	if utf8.RuneCountInString(link.OGTitle) > maxOGTitleLength {
+		return fmt.Errorf("the ogTitle must be at most %d characters", maxOGTitleLength)
+	}
+	if utf8.RuneCountInString(link.OGDescription) > maxOGDescriptionLength {
+		return fmt.Errorf("the ogDescription must be at most %d characters", maxOGDescriptionLength)
+	}
+	if link.OGImage != "" {
+		image, err := urls.Check(link.OGImage)
+		if err != nil {
+			return fmt.Errorf("invalid ogImage: %v", err)
+		}
+		link.OGImage = image
+	}
+	return nil
+}
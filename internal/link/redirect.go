@@ -0,0 +1,79 @@
+package link
+
+import (
+	"fmt"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"net/http"
+	"net/url"
+)
+
+// redirectStatuses maps the redirect types of a link to the status code answered to visitors. The meta and
+// js types serve an interstitial page instead of an HTTP redirect.
+var redirectStatuses = map[string]int{
+	"":     http.StatusFound,
+	"301":  http.StatusMovedPermanently,
+	"302":  http.StatusFound,
+	"307":  http.StatusTemporaryRedirect,
+	"308":  http.StatusPermanentRedirect,
+	"meta": http.StatusOK,
+	"js":   http.StatusOK,
+}
+
+// internalParams are the query parameters used by the redirect itself, never forwarded to destinations.
+var internalParams = map[string]bool{"unlock": true, "proceed": true}
+
+// validateRedirectType checks the redirect type of a link is supported.
+func validateRedirectType(redirectType string) error {
+	if _, ok := redirectStatuses[redirectType]; !ok {
+		return fmt.Errorf("unknown redirect type `%s`, use 301, 302, 307, 308, meta or js", redirectType)
+	}
+	return nil
+}
+
+// RedirectStatus returns the status code answered to the visitors of a link, and whether it is an HTTP redirect.
+func RedirectStatus(lnk domain.Link) (int, bool) {
+	status, ok := redirectStatuses[lnk.RedirectType]
+	if !ok {
+		return http.StatusFound, true
+	}
+	return status, status != http.StatusOK
+}
+
+// BuildDestination appends the UTM template of the link and, when enabled, the query parameters of the visitor
+// to the destination. Parameters of the visitor take precedence over the template, which takes precedence over
+// the parameters already in the destination.
+func BuildDestination(destination string, lnk domain.Link, visitor url.Values) string {
+	params := url.Values{}
+	for key, value := range map[string]string{
+		"utm_source":   lnk.UTM.Source,
+		"utm_medium":   lnk.UTM.Medium,
+		"utm_campaign": lnk.UTM.Campaign,
+		"utm_term":     lnk.UTM.Term,
+		"utm_content":  lnk.UTM.Content,
+	} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	if lnk.ForwardsQuery() {
+		for key, values := range visitor {
+			if !internalParams[key] {
+				params[key] = values
+			}
+		}
+	}
+	if len(params) == 0 {
+		return destination
+	}
+
+	target, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	target.RawQuery = query.Encode()
+	return target.String()
+}
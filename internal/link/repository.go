@@ -3,20 +3,52 @@ package link
 import (
 	"github.com/google/uuid"
 	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/utils"
 	"gorm.io/gorm"
 	"log"
+	"strings"
+	"time"
 )
 
 type Repository interface {
 	FindByID(id uuid.UUID) (*domain.Link, error)
-	FindByOriginal(original string) (*domain.Link, error)
+	FindByOriginal(original string, userId string, workspaceId *uuid.UUID) (*domain.Link, error)
+	FindReusable(original string, userId string, workspaceId *uuid.UUID) (*domain.Link, error)
 	FindByShortened(shortened string) (*domain.Link, error)
+	ExistsByShortened(shortened string) (bool, error)
+	NextSequence() (uint64, error)
 	FindAllByUser(userId string) (*[]domain.Link, error)
+	FindAllByWorkspace(workspaceId uuid.UUID) (*[]domain.Link, error)
+	StreamAllByUser(userId string, fn func(link domain.Link) error) error
 	Create(link *domain.Link) error
 	Update(link *domain.Link) error
 	Delete(link *domain.Link) error
+	ArchiveExpired(now time.Time) (int64, error)
+	Unarchive(id uuid.UUID) error
+	ReplaceVariants(id uuid.UUID, variants []domain.LinkVariant) error
+	SetFlag(id uuid.UUID, flagged bool, reason string) error
+	FindDomains() ([]string, error)
+	FindRedirectsToHosts(hosts []string) ([]Redirect, error)
 }
 
+// Redirect is a destination of a link: its original URL, a variant or a redirect rule.
+type Redirect struct {
+	Shortened   string
+	Destination string
+}
+
+// updatableColumns are the columns of a link written by an update, zero values included so options can be cleared.
+// The owner, counters, lifecycle and moderation columns are left to their own methods.
+var updatableColumns = []string{
+	"original", "title", "shortened", "final_url", "expires_at", "max_clicks", "password_hash", "redirect_type",
+	"forward_query", "utm_source", "utm_medium", "utm_campaign", "utm_term", "utm_content", "app_ios_url",
+	"app_ios_store_url", "app_android_url", "app_android_package", "app_android_store_url", "og_title",
+	"og_description", "og_image", "updated_at",
+}
+
+// streamBatchSize is the number of links loaded at once while streaming.
+const streamBatchSize = 500
+
 type linkRepository struct {
 	db *gorm.DB
 }
@@ -29,16 +61,47 @@ func NewLinkRepository(db *gorm.DB) Repository {
 // FindByID finds a link by the ID
 func (r *linkRepository) FindByID(id uuid.UUID) (*domain.Link, error) {
 	var link domain.Link
-	if err := r.db.Where("id = ?", id).First(&link).Error; err != nil {
+	if err := r.db.Preload("Variants").Where("id = ?", id).First(&link).Error; err != nil {
 		return nil, err
 	}
 	return &link, nil
 }
 
-// FindByOriginal finds a link by the original URL
-func (r *linkRepository) FindByOriginal(original string) (*domain.Link, error) {
+// ownedBy scopes a query to the personal links of the user, or to the links of the workspace when provided
+func ownedBy(userId string, workspaceId *uuid.UUID) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if workspaceId != nil {
+			return db.Where("workspace_id = ?", *workspaceId)
+		}
+		return db.Where("user_id = ? AND workspace_id IS NULL", userId)
+	}
+}
+
+// FindByOriginal finds a link of the user, or of the workspace, by the original URL
+func (r *linkRepository) FindByOriginal(original string, userId string, workspaceId *uuid.UUID) (*domain.Link, error) {
 	var link domain.Link
-	if err := r.db.Where("original = ?", original).First(&link).Error; err != nil {
+	if err := r.db.Preload("Variants").Scopes(ownedBy(userId, workspaceId)).Where("original = ?", original).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// reusableConditions matches the links without options beyond their destination, still active and unflagged.
+// Columns added after a link was created are NULL, so they are read through COALESCE and concat, which skips NULLs.
+const reusableConditions = "COALESCE(password_hash, '') = '' AND expires_at IS NULL AND COALESCE(max_clicks, 0) = 0" +
+	" AND archived_at IS NULL AND NOT COALESCE(flagged, FALSE) AND NOT COALESCE(forward_query, FALSE)" +
+	" AND concat(redirect_type, utm_source, utm_medium, utm_campaign, utm_term, utm_content) = ''" +
+	" AND concat(app_ios_url, app_ios_store_url, app_android_url, app_android_package, app_android_store_url) = ''" +
+	" AND concat(og_title, og_description, og_image) = ''" +
+	" AND NOT EXISTS (SELECT 1 FROM link_variants WHERE link_variants.link_refer = links.id)" +
+	" AND NOT EXISTS (SELECT 1 FROM redirect_rules WHERE redirect_rules.link_refer = links.id::text)"
+
+// FindReusable finds a link of the user, or of the workspace, to the original URL that a plain shortening can return
+// instead of creating a new one
+func (r *linkRepository) FindReusable(original string, userId string, workspaceId *uuid.UUID) (*domain.Link, error) {
+	var link domain.Link
+	if err := r.db.Scopes(ownedBy(userId, workspaceId)).Where("original = ?", original).Where(reusableConditions).
+		Order("created_at").First(&link).Error; err != nil {
 		return nil, err
 	}
 	return &link, nil
@@ -47,22 +110,69 @@ func (r *linkRepository) FindByOriginal(original string) (*domain.Link, error) {
 // FindByShortened finds a link by the shortened URL
 func (r *linkRepository) FindByShortened(shortened string) (*domain.Link, error) {
 	var link domain.Link
-	if err := r.db.Where("shortened = ?", shortened).First(&link).Error; err != nil {
+	if err := r.db.Preload("Variants").Where("shortened = ?", shortened).First(&link).Error; err != nil {
 		return nil, err
 	}
-	go r.db.Model(&link).Update("clicks", link.Clicks+1)
 	return &link, nil
 }
 
-// FindAllByUser finds all links by user
+// ExistsByShortened checks if a shortened URL is already taken
+func (r *linkRepository) ExistsByShortened(shortened string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&domain.Link{}).Where("shortened = ?", shortened).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// NextSequence returns the next value of the short code sequence
+func (r *linkRepository) NextSequence() (uint64, error) {
+	var next uint64
+	if err := r.db.Raw("SELECT nextval('links_shortcode_seq')").Scan(&next).Error; err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+// FindAllByUser finds all personal links by user, leaving out the ones shared in workspaces
 func (r *linkRepository) FindAllByUser(userId string) (*[]domain.Link, error) {
 	var links []domain.Link
-	if err := r.db.Where("user_id = ?", userId).Find(&links).Error; err != nil {
+	if err := r.db.Preload("Variants").Where("user_id = ? AND workspace_id IS NULL", userId).Find(&links).Error; err != nil {
 		return nil, err
 	}
 	return &links, nil
 }
 
+// FindAllByWorkspace finds all links by workspace
+func (r *linkRepository) FindAllByWorkspace(workspaceId uuid.UUID) (*[]domain.Link, error) {
+	var links []domain.Link
+	if err := r.db.Preload("Variants").Where("workspace_id = ?", workspaceId).Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return &links, nil
+}
+
+// StreamAllByUser calls fn for every personal link of the user by creation, loading them in batches
+func (r *linkRepository) StreamAllByUser(userId string, fn func(link domain.Link) error) error {
+	var createdAt time.Time
+	var id uuid.UUID
+	for {
+		var links []domain.Link
+		if err := r.db.Preload("Variants").Where("user_id = ? AND workspace_id IS NULL", userId).Scopes(utils.CreatedAfter(createdAt, id, streamBatchSize)).Find(&links).Error; err != nil {
+			return err
+		}
+		for _, link := range links {
+			if err := fn(link); err != nil {
+				return err
+			}
+		}
+		if len(links) < streamBatchSize {
+			return nil
+		}
+		createdAt, id = links[len(links)-1].CreatedAt, links[len(links)-1].ID
+	}
+}
+
 // Update updates a link
 func (r *linkRepository) Update(link *domain.Link) error {
 	_, err := r.FindByID(link.ID)
@@ -70,7 +180,7 @@ func (r *linkRepository) Update(link *domain.Link) error {
 		log.Printf("ERROR: unable to find link due to %v", err)
 		return err
 	}
-	return r.db.Updates(link).Error
+	return r.db.Model(link).Select(updatableColumns).Updates(link).Error
 }
 
 // Create creates a new shortened URL
@@ -80,5 +190,72 @@ func (r *linkRepository) Create(link *domain.Link) error {
 
 // Delete deletes a link
 func (r *linkRepository) Delete(link *domain.Link) error {
-	return r.db.Where("id = ?", link.ID).Delete(link).Error
+	return r.db.Select("Variants").Where("id = ?", link.ID).Delete(link).Error
+}
+
+// ArchiveExpired archives every link that reached its expiration date or its click budget
+func (r *linkRepository) ArchiveExpired(now time.Time) (int64, error) {
+	result := r.db.Model(&domain.Link{}).
+		Where("archived_at IS NULL").
+		Where("(expires_at IS NOT NULL AND expires_at <= ?) OR (max_clicks > 0 AND clicks >= max_clicks)", now).
+		Update("archived_at", now)
+	return result.RowsAffected, result.Error
+}
+
+// Unarchive restores an archived link
+func (r *linkRepository) Unarchive(id uuid.UUID) error {
+	return r.db.Model(&domain.Link{}).Where("id = ?", id).Update("archived_at", nil).Error
+}
+
+// ReplaceVariants replaces all the weighted destinations of a link, variants keep their ID when provided
+func (r *linkRepository) ReplaceVariants(id uuid.UUID, variants []domain.LinkVariant) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("link_refer = ?", id).Delete(&domain.LinkVariant{}).Error; err != nil {
+			return err
+		}
+		if len(variants) == 0 {
+			return nil
+		}
+		for i := range variants {
+			variants[i].LinkRefer = id
+		}
+		return tx.Create(&variants).Error
+	})
+}
+
+// SetFlag flags or unflags a link as unsafe
+func (r *linkRepository) SetFlag(id uuid.UUID, flagged bool, reason string) error {
+	result := r.db.Model(&domain.Link{}).Where("id = ?", id).Updates(map[string]interface{}{"flagged": flagged, "flag_reason": reason})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// FindDomains returns the distinct domains of the shortened URLs
+func (r *linkRepository) FindDomains() ([]string, error) {
+	var domains []string
+	if err := r.db.Model(&domain.Link{}).Distinct().Pluck("split_part(final_url, '/', 3)", &domains).Error; err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+// FindRedirectsToHosts finds the original URLs, variants and redirect rules of the links pointing at one of the hosts
+func (r *linkRepository) FindRedirectsToHosts(hosts []string) ([]Redirect, error) {
+	var redirects []Redirect
+	if len(hosts) == 0 {
+		return redirects, nil
+	}
+	query := r.db.Where("1 = 0")
+	for _, host := range hosts {
+		query = query.Or("lower(destination) LIKE ? OR lower(destination) LIKE ?", "https://"+strings.ToLower(host)+"/%", "http://"+strings.ToLower(host)+"/%")
+	}
+	destinations := r.db.Raw("SELECT shortened, original AS destination FROM links" +
+		" UNION ALL SELECT links.shortened, link_variants.destination FROM link_variants JOIN links ON links.id = link_variants.link_refer" +
+		" UNION ALL SELECT links.shortened, redirect_rules.destination FROM redirect_rules JOIN links ON redirect_rules.link_refer = links.id::text")
+	if err := r.db.Table("(?) AS redirects", destinations).Where(query).Find(&redirects).Error; err != nil {
+		return nil, err
+	}
+	return redirects, nil
 }
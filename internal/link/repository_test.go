@@ -0,0 +1,58 @@
+package link
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/testdb"
+	"testing"
+	"time"
+)
+
+func TestStreamAllByUser(t *testing.T) {
+	db := testdb.Open(t, &domain.Link{}, &domain.LinkVariant{})
+	r := NewLinkRepository(db)
+
+	// More than two batches, links created at the same instant are ordered by their ID
+	created := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	workspaceId := uuid.New()
+	var links []domain.Link
+	for i := 0; i < streamBatchSize*2+10; i++ {
+		links = append(links, domain.Link{
+			Original:  "https://example.com/" + fmt.Sprint(i),
+			Shortened: fmt.Sprintf("stream%d", i),
+			UserId:    "streamer",
+			CreatedAt: created.Add(time.Duration(i/3) * time.Second),
+		})
+	}
+	links = append(links,
+		domain.Link{Original: "https://example.com/other", Shortened: "other-user", UserId: "someone-else", CreatedAt: created},
+		domain.Link{Original: "https://example.com/shared", Shortened: "workspace", UserId: "streamer", WorkspaceId: &workspaceId, CreatedAt: created},
+	)
+	if err := db.CreateInBatches(&links, 100).Error; err != nil {
+		t.Fatalf("unable to create the links: %v", err)
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var previous *domain.Link
+	err := r.StreamAllByUser("streamer", func(link domain.Link) error {
+		if seen[link.ID] {
+			return fmt.Errorf("the link `%s` was streamed twice", link.Shortened)
+		}
+		seen[link.ID] = true
+		if link.UserId != "streamer" || link.WorkspaceId != nil {
+			return fmt.Errorf("the link `%s` isn't a personal link of the user", link.Shortened)
+		}
+		if previous != nil && link.CreatedAt.Before(previous.CreatedAt) {
+			return fmt.Errorf("the link `%s` was streamed before `%s`", previous.Shortened, link.Shortened)
+		}
+		previous = &link
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamAllByUser() error = %v", err)
+	}
+	if want := streamBatchSize*2 + 10; len(seen) != want {
+		t.Errorf("StreamAllByUser() streamed %d links, want %d", len(seen), want)
+	}
+}
@@ -1,34 +1,63 @@
 package link
 
 import (
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/alias"
 	"github.com/ronilsonalves/5lnk/internal/domain"
-	"github.com/ronilsonalves/5lnk/internal/utils"
+	"github.com/ronilsonalves/5lnk/internal/shortcode"
+	"github.com/ronilsonalves/5lnk/internal/urlsafety"
+	"github.com/ronilsonalves/5lnk/internal/workspace"
 	"github.com/ronilsonalves/5lnk/pkg/web"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 	"log"
+	"net/url"
 	"strings"
 	"time"
 )
 
 type Service interface {
 	ShortenURL(request web.CreateShortenURL) (domain.Link, error)
+	ShortenURLs(requests []web.CreateShortenURL) web.BulkLinkReport
+	GenerateShortened(domain string) (string, error)
 	GetLink(linkId uuid.UUID) (*domain.Link, error)
-	Update(shortened domain.Link) (domain.Link, error)
+	GetAuthorizedLink(userId string, linkId uuid.UUID, role string) (*domain.Link, error)
+	Update(userId string, shortened domain.Link) (domain.Link, error)
 	GetOriginalURL(shortened string) (string, error)
 	GetLinkByShortened(shortened string) (*domain.Link, error)
-	GetShortenedByOriginal(original string) (*domain.Link, error)
+	GetShortenedByOriginal(original string, userId string) (*domain.Link, error)
 	GetAllByUser(userId string) (*[]domain.Link, error)
-	Delete(shortened domain.Link) error
+	GetAllByWorkspace(workspaceId uuid.UUID) (*[]domain.Link, error)
+	Delete(userId string, shortened domain.Link) error
+	ArchiveExpiredLinks() (int64, error)
+	CheckPassword(link domain.Link, password string) error
+	Flag(linkId uuid.UUID, reason string) error
+	Unflag(linkId uuid.UUID) error
+	DetectCycles() ([][]string, error)
+	ResolveDestination(linkId uuid.UUID, destination string) (string, error)
 }
 
 type linkService struct {
-	repo Repository
+	repo       Repository
+	codes      *shortcode.Issuer
+	aliases    *alias.Policy
+	urls       *urlsafety.Checker
+	chains     *ChainPolicy
+	workspaces workspace.Service
 }
 
+// maxCreateAttempts is how many times creating a link with a generated short code is retried when
+// another link takes the same code concurrently.
+const maxCreateAttempts = 3
+
+// bulkResolveTimeout bounds the lookups of the destination hosts of a bulk request.
+const bulkResolveTimeout = time.Second * 30
+
 // NewLinkService creates a new link service
-func NewLinkService(repo Repository) Service {
-	return &linkService{repo: repo}
+func NewLinkService(repo Repository, codes *shortcode.Issuer, aliases *alias.Policy, urls *urlsafety.Checker, chains *ChainPolicy, workspaces workspace.Service) Service {
+	return &linkService{repo: repo, codes: codes, aliases: aliases, urls: urls, chains: chains, workspaces: workspaces}
 }
 
 // GetLink returns a link by the ID
@@ -36,6 +65,19 @@ func (s *linkService) GetLink(linkId uuid.UUID) (*domain.Link, error) {
 	return s.repo.FindByID(linkId)
 }
 
+// GetAuthorizedLink returns a link by the ID when the user owns it, or has the role in its workspace. Links the
+// user can't see are reported as not found, so their existence isn't disclosed.
+func (s *linkService) GetAuthorizedLink(userId string, linkId uuid.UUID, role string) (*domain.Link, error) {
+	link, err := s.repo.FindByID(linkId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.workspaces.AuthorizeResource(link.UserId, link.WorkspaceId, userId, role); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
 // GetLinkByShortened returns a link by the shortened URL
 func (s *linkService) GetLinkByShortened(shortened string) (*domain.Link, error) {
 	return s.repo.FindByShortened(shortened)
@@ -43,31 +85,194 @@ func (s *linkService) GetLinkByShortened(shortened string) (*domain.Link, error)
 
 // ShortenURL creates a new shortened URL
 func (s *linkService) ShortenURL(request web.CreateShortenURL) (domain.Link, error) {
-	if request.UserId == "" || !strings.HasPrefix(request.UserId, "CREATED_BY_SYSTEM_") {
-		link, err := s.repo.FindByOriginal(request.URL)
+	link, _, err := s.shorten(request, s.urls)
+	return link, err
+}
+
+// ShortenURLs creates shortened URLs in bulk, reporting the outcome of each request.
+// URLs the user already shortened, or repeated in the batch, are reported as deduplicated.
+func (s *linkService) ShortenURLs(requests []web.CreateShortenURL) web.BulkLinkReport {
+	report := web.BulkLinkReport{Results: make([]web.BulkLinkResult, 0, len(requests))}
+	// The hosts of the destinations are resolved once and concurrently, within a bound for the whole request
+	urls := s.urls.ForBatch(bulkResolveTimeout)
+	destinations := make([]string, 0, len(requests))
+	for _, request := range requests {
+		destinations = append(destinations, request.URL)
+	}
+	urls.Prepare(destinations)
+
+	batch := make(map[string]domain.Link)
+	for i, request := range requests {
+		result := web.BulkLinkResult{Row: i + 1, URL: request.URL}
+		key := request.URL
+		if request.WorkspaceId != nil {
+			key = request.WorkspaceId.String() + " " + request.URL
+		}
+		link, seen := batch[key]
+		var created bool
+		var err error
+		if !seen || hasOptions(request) {
+			link, created, err = s.shorten(request, urls)
+		}
+
+		switch {
+		case err != nil:
+			result.Status = "failed"
+			result.Error = err.Error()
+			report.Failed++
+		case created:
+			result.Status = "created"
+			report.Created++
+		default:
+			result.Status = "deduplicated"
+			report.Deduplicated++
+		}
+		if err == nil && !hasOptions(request) {
+			batch[key] = link
+		}
 		if err == nil {
-			return *link, nil
+			result.ID = link.ID.String()
+			result.Shortened = link.Shortened
+			result.FinalURL = link.FinalURL
 		}
+		report.Results = append(report.Results, result)
 	}
+	log.Printf("INFO: bulk shortening finished with %d created, %d deduplicated and %d failed", report.Created, report.Deduplicated, report.Failed)
+	return report
+}
 
-	// Generate a new shortened URL
-	shortened := utils.GenerateRandomAlias(request.Alias)
+// shorten creates a new shortened URL, or returns the existing one for the original URL without creating it. The
+// destinations are checked by urls.
+func (s *linkService) shorten(request web.CreateShortenURL, urls *urlsafety.Checker) (domain.Link, bool, error) {
+	if request.URL == "" {
+		return domain.Link{}, false, fmt.Errorf("the url is required")
+	}
+	if request.ShortDomain == "" {
+		return domain.Link{}, false, fmt.Errorf("the domain is required")
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return domain.Link{}, false, fmt.Errorf("the expiration date must be in the future")
+	}
+	if request.MaxClicks < 0 {
+		return domain.Link{}, false, fmt.Errorf("the max clicks must be a positive number")
+	}
+	if request.WorkspaceId != nil {
+		if _, err := s.workspaces.Authorize(*request.WorkspaceId, request.UserId, domain.RoleEditor); err != nil {
+			return domain.Link{}, false, err
+		}
+	}
+	// Links created by the system point to our own pages, every other destination must be safe
+	if !isSystemUser(request.UserId) {
+		destination, err := urls.Check(request.URL)
+		if err != nil {
+			return domain.Link{}, false, err
+		}
+		if request.URL, err = s.resolveDestination(destination, request.ShortDomain, request.Alias); err != nil {
+			return domain.Link{}, false, err
+		}
+	}
+	variants, err := toVariants(request.Variants, urls)
+	if err != nil {
+		return domain.Link{}, false, err
+	}
+	for i := range variants {
+		if variants[i].Destination, err = s.resolveDestination(variants[i].Destination, request.ShortDomain, request.Alias); err != nil {
+			return domain.Link{}, false, err
+		}
+	}
+	if err := validateRedirectType(request.RedirectType); err != nil {
+		return domain.Link{}, false, err
+	}
+	app, err := toAppLink(request.App, urls)
+	if err != nil {
+		return domain.Link{}, false, err
+	}
+	if request.Alias != "" {
+		request.Alias = s.aliases.Normalize(request.Alias)
+		if err := s.aliases.Validate(request.Alias); err != nil {
+			return domain.Link{}, false, err
+		}
+	}
+
+	// A plain shortening returns the active link of the user to the same destination, when it has no options either
+	if !hasOptions(request) && !isSystemUser(request.UserId) {
+		link, err := s.repo.FindReusable(request.URL, request.UserId, request.WorkspaceId)
+		if err == nil {
+			return *link, false, nil
+		}
+	}
 
 	// Create a new Link object
 	var link = &domain.Link{
-		Original:  request.URL,
-		Shortened: shortened,
-		FinalURL:  "https://" + request.ShortDomain + "/" + shortened,
-		UserId:    request.UserId,
-		CreatedAt: time.Now(),
+		Original:     request.URL,
+		Title:        request.Title,
+		UserId:       request.UserId,
+		WorkspaceId:  request.WorkspaceId,
+		CreatedAt:    time.Now(),
+		ExpiresAt:    request.ExpiresAt,
+		MaxClicks:    request.MaxClicks,
+		Variants:     variants,
+		RedirectType: request.RedirectType,
+		ForwardQuery: &request.ForwardQuery,
+		UTM: domain.UTM{
+			Source:   request.UTM.Source,
+			Medium:   request.UTM.Medium,
+			Campaign: request.UTM.Campaign,
+			Term:     request.UTM.Term,
+			Content:  request.UTM.Content,
+		},
+		App:           app,
+		OGTitle:       request.OGTitle,
+		OGDescription: request.OGDescription,
+		OGImage:       request.OGImage,
+	}
+	if err := validatePreview(link, urls); err != nil {
+		return domain.Link{}, false, err
 	}
 
-	// Insert the link into the database
-	if err := s.repo.Create(link); err != nil {
-		return domain.Link{}, err
+	if request.Password != "" {
+		hash, err := hashPassword(request.Password)
+		if err != nil {
+			return domain.Link{}, false, err
+		}
+		link.PasswordHash = hash
 	}
 
-	return *link, nil
+	// Insert the link into the database, generating a new shortened URL when no alias was provided
+	for attempt := 1; ; attempt++ {
+		shortened := request.Alias
+		if shortened == "" {
+			if shortened, err = s.GenerateShortened(request.ShortDomain); err != nil {
+				return domain.Link{}, false, err
+			}
+		}
+		link.Shortened = shortened
+		link.FinalURL = "https://" + request.ShortDomain + "/" + shortened
+
+		err = s.repo.Create(link)
+		if err == nil {
+			return *link, true, nil
+		}
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return domain.Link{}, false, err
+		}
+		if request.Alias != "" {
+			return domain.Link{}, false, fmt.Errorf("the alias `%s` is already in use", request.Alias)
+		}
+		if attempt == maxCreateAttempts {
+			return domain.Link{}, false, fmt.Errorf("unable to create the shortened URL, try again")
+		}
+	}
+}
+
+// GenerateShortened returns a shortened URL not used by any link yet, nor forbidden by the alias policy
+func (s *linkService) GenerateShortened(domain string) (string, error) {
+	return s.codes.Issue(domain, func(code string) (bool, error) {
+		if s.aliases.Forbidden(code) {
+			return true, nil
+		}
+		return s.repo.ExistsByShortened(code)
+	})
 }
 
 // GetOriginalURL returns the original URL from the shortened URL
@@ -79,17 +284,14 @@ func (s *linkService) GetOriginalURL(shortened string) (string, error) {
 	return link.Original, nil
 }
 
-// GetShortenedByOriginal returns the shortened URL from the original URL
-func (s *linkService) GetShortenedByOriginal(original string) (*domain.Link, error) {
-	link, err := s.repo.FindByOriginal(original)
+// GetShortenedByOriginal returns the personal shortened URL of the user from the original URL
+func (s *linkService) GetShortenedByOriginal(original string, userId string) (*domain.Link, error) {
+	link, err := s.repo.FindByOriginal(original, userId, nil)
 	if err != nil {
 		log.Printf("ERROR: unable to find the shortened URL for the original URL `%s` due to %v", original, err.Error())
 		return &domain.Link{}, err
 	}
-	if strings.HasPrefix(link.UserId, "CREATED_BY_SYSTEM_") {
-		return link, nil
-	}
-	return &domain.Link{}, fmt.Errorf("the original URL `%s` already been used by a shortened URL", original)
+	return link, nil
 }
 
 // GetAllByUser returns all shortened links by user
@@ -98,15 +300,186 @@ func (s *linkService) GetAllByUser(userId string) (*[]domain.Link, error) {
 	return s.repo.FindAllByUser(userId)
 }
 
-// Update updates a link
-func (s *linkService) Update(request domain.Link) (domain.Link, error) {
+// GetAllByWorkspace returns all shortened links by workspace
+func (s *linkService) GetAllByWorkspace(workspaceId uuid.UUID) (*[]domain.Link, error) {
+	log.Printf("INFO: getting all links by workspaceId `%v`...", workspaceId)
+	return s.repo.FindAllByWorkspace(workspaceId)
+}
+
+// Update updates a link of the user, or of a workspace the user edits
+func (s *linkService) Update(userId string, request domain.Link) (domain.Link, error) {
+	if request.MaxClicks < 0 {
+		return domain.Link{}, fmt.Errorf("the max clicks must be a positive number")
+	}
+	if err := validateVariants(request.Variants, s.urls); err != nil {
+		return domain.Link{}, err
+	}
+	if err := validateRedirectType(request.RedirectType); err != nil {
+		return domain.Link{}, err
+	}
+	if err := validateAppLink(&request.App, s.urls); err != nil {
+		return domain.Link{}, err
+	}
+	if err := validatePreview(&request, s.urls); err != nil {
+		return domain.Link{}, err
+	}
+	if request.RemovePassword && request.Password != "" {
+		return domain.Link{}, fmt.Errorf("a new password can't be set while removing the password")
+	}
+	current, err := s.GetAuthorizedLink(userId, request.ID, domain.RoleEditor)
+	if err != nil {
+		return domain.Link{}, err
+	}
+	// A link can't be handed over to another user or workspace
+	request.UserId = current.UserId
+	request.WorkspaceId = current.WorkspaceId
+	// The address is rebuilt from the alias and the domain, a new domain is read from the final URL provided
+	self, shortDomain := current.Shortened, shortDomainOf(current)
+	if request.Shortened != "" {
+		self = s.aliases.Normalize(request.Shortened)
+	}
+	if request.FinalURL != "" {
+		finalURL, err := url.Parse(request.FinalURL)
+		if err != nil || finalURL.Hostname() == "" {
+			return domain.Link{}, fmt.Errorf("invalid final url `%s` provided", request.FinalURL)
+		}
+		shortDomain = strings.ToLower(finalURL.Hostname())
+	}
+	if request.Original != "" && !isSystemUser(current.UserId) {
+		destination, err := s.urls.Check(request.Original)
+		if err != nil {
+			return domain.Link{}, err
+		}
+		if request.Original, err = s.resolveDestination(destination, shortDomain, self); err != nil {
+			return domain.Link{}, err
+		}
+	}
+	for i := range request.Variants {
+		if request.Variants[i].Destination, err = s.resolveDestination(request.Variants[i].Destination, shortDomain, self); err != nil {
+			return domain.Link{}, err
+		}
+	}
+	if request.Shortened != "" {
+		request.Shortened = s.aliases.Normalize(request.Shortened)
+	}
+	if request.Shortened != "" && request.Shortened != current.Shortened {
+		if err := s.aliases.Validate(request.Shortened); err != nil {
+			return domain.Link{}, err
+		}
+	}
+	// Every option is replaced, while the destination, address and password are kept unless new ones are provided,
+	// or the password is removed
+	if request.Original == "" {
+		request.Original = current.Original
+	}
+	if request.Shortened == "" {
+		request.Shortened = current.Shortened
+	}
+	request.FinalURL = "https://" + shortDomain + "/" + request.Shortened
+	request.PasswordHash = current.PasswordHash
+	if request.RemovePassword {
+		request.PasswordHash = ""
+	}
+	if request.Password != "" {
+		hash, err := hashPassword(request.Password)
+		if err != nil {
+			return domain.Link{}, err
+		}
+		request.PasswordHash = hash
+		request.Password = ""
+	}
 	if err := s.repo.Update(&request); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return domain.Link{}, fmt.Errorf("the alias `%s` is already in use", request.Shortened)
+		}
+		return domain.Link{}, err
+	}
+
+	// Variants are only replaced when provided, an empty list removes them
+	if request.Variants != nil {
+		if err := s.repo.ReplaceVariants(request.ID, request.Variants); err != nil {
+			log.Printf("ERROR: unable to update the variants of link `%s` due to %v", request.ID, err.Error())
+			return domain.Link{}, err
+		}
+	}
+
+	// A link archived by the sweeper becomes active again when its new lifecycle allows it
+	updated, err := s.repo.FindByID(request.ID)
+	if err != nil {
 		return domain.Link{}, err
 	}
-	return request, nil
+	if updated.IsArchived() && !updated.IsExpired(time.Now()) {
+		if err := s.repo.Unarchive(updated.ID); err != nil {
+			return domain.Link{}, err
+		}
+		updated.ArchivedAt = nil
+	}
+	return *updated, nil
 }
 
-// Delete deletes a link
-func (s *linkService) Delete(request domain.Link) error {
-	return s.repo.Delete(&request)
+// Delete deletes a link of the user, or of a workspace the user edits
+func (s *linkService) Delete(userId string, request domain.Link) error {
+	link, err := s.GetAuthorizedLink(userId, request.ID, domain.RoleEditor)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(link)
+}
+
+// ArchiveExpiredLinks archives the links that reached their expiration date or click budget
+func (s *linkService) ArchiveExpiredLinks() (int64, error) {
+	return s.repo.ArchiveExpired(time.Now())
+}
+
+// CheckPassword verifies the password provided to unlock a protected link
+func (s *linkService) CheckPassword(link domain.Link, password string) error {
+	if !link.IsProtected() {
+		return nil
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)); err != nil {
+		return fmt.Errorf("invalid password provided")
+	}
+	return nil
+}
+
+// Flag marks a link as unsafe, its visitors are warned before being redirected
+func (s *linkService) Flag(linkId uuid.UUID, reason string) error {
+	if err := s.repo.SetFlag(linkId, true, reason); err != nil {
+		log.Printf("ERROR: unable to flag the link `%s` due to %v", linkId, err.Error())
+		return err
+	}
+	log.Printf("INFO: the link `%s` was flagged: %s", linkId, reason)
+	return nil
+}
+
+// Unflag removes the unsafe mark of a link
+func (s *linkService) Unflag(linkId uuid.UUID) error {
+	if err := s.repo.SetFlag(linkId, false, ""); err != nil {
+		log.Printf("ERROR: unable to unflag the link `%s` due to %v", linkId, err.Error())
+		return err
+	}
+	log.Printf("INFO: the link `%s` was unflagged", linkId)
+	return nil
+}
+
+// hasOptions reports whether the request configures the link beyond its destination
+func hasOptions(request web.CreateShortenURL) bool {
+	return request.Password != "" || len(request.Variants) > 0 || request.ExpiresAt != nil || request.MaxClicks != 0 ||
+		request.RedirectType != "" || request.ForwardQuery || request.UTM != (web.UTM{}) || request.App != (web.AppLink{}) ||
+		request.OGTitle != "" || request.OGDescription != "" || request.OGImage != ""
+}
+
+// isSystemUser reports whether the link was created by the system, e.g. for a links page
+func isSystemUser(userId string) bool {
+	return strings.HasPrefix(userId, "CREATED_BY_SYSTEM_")
+}
+
+// hashPassword returns the bcrypt hash of a link password
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("ERROR: unable to hash the link password due to %v", err.Error())
+		return "", fmt.Errorf("unable to protect the link with the provided password")
+	}
+	return string(hash), nil
 }
@@ -0,0 +1,85 @@
+package link
+
+import (
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/urlsafety"
+	"github.com/ronilsonalves/5lnk/internal/workspace"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+)
+
+// updateRepository holds a single link, the other methods of the repository aren't used by updates.
+type updateRepository struct {
+	Repository
+	link domain.Link
+}
+
+func (r *updateRepository) FindByID(id uuid.UUID) (*domain.Link, error) {
+	if id != r.link.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	link := r.link
+	return &link, nil
+}
+
+func (r *updateRepository) Update(link *domain.Link) error {
+	r.link = *link
+	return nil
+}
+
+func TestUpdatePassword(t *testing.T) {
+	current, err := hashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		request  domain.Link
+		password string
+		err      string
+	}{
+		{name: "kept without a new password", request: domain.Link{}, password: "secret"},
+		{name: "replaced by a new password", request: domain.Link{Password: "other"}, password: "other"},
+		{name: "removed", request: domain.Link{RemovePassword: true}, password: ""},
+		{name: "removed and replaced", request: domain.Link{RemovePassword: true, Password: "other"}, err: "can't be set while removing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &updateRepository{link: domain.Link{
+				ID:           uuid.New(),
+				Original:     "https://example.com",
+				Shortened:    "abc",
+				FinalURL:     "https://5lnk.live/abc",
+				UserId:       "user",
+				PasswordHash: current,
+			}}
+			s := &linkService{repo: repo, urls: urlsafety.NewChecker(false), workspaces: workspace.NewWorkspaceService(nil)}
+			tt.request.ID = repo.link.ID
+
+			updated, err := s.Update("user", tt.request)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Update() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if tt.password == "" {
+				if updated.IsProtected() {
+					t.Error("Update() kept the password, want it removed")
+				}
+				return
+			}
+			if err := s.CheckPassword(updated, tt.password); err != nil {
+				t.Errorf("CheckPassword(%q) error = %v", tt.password, err)
+			}
+			if updated.Password != "" {
+				t.Error("Update() returned the plain password")
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package link
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// StartExpirationSweeper archives expired links every interval until the context is done
+func StartExpirationSweeper(ctx context.Context, s Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				archived, err := s.ArchiveExpiredLinks()
+				if err != nil {
+					log.Printf("ERROR: unable to archive expired links due to %v", err.Error())
+					continue
+				}
+				if archived > 0 {
+					log.Printf("INFO: %d expired links archived", archived)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,64 @@
+package link
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unlockSecret signs the tokens handed to visitors after unlocking a protected link.
+var (
+	unlockSecret     []byte
+	unlockSecretOnce sync.Once
+)
+
+// getUnlockSecret reads the signing secret from LINK_UNLOCK_SECRET. When it is not set a random one is generated,
+// so issued tokens are only valid until the server restarts.
+func getUnlockSecret() []byte {
+	unlockSecretOnce.Do(func() {
+		if secret := os.Getenv("LINK_UNLOCK_SECRET"); secret != "" {
+			unlockSecret = []byte(secret)
+			return
+		}
+		unlockSecret = make([]byte, 32)
+		if _, err := rand.Read(unlockSecret); err != nil {
+			log.Fatalf("error generating unlock secret: %v", err)
+		}
+		log.Println("WARNING: LINK_UNLOCK_SECRET not set, using a random secret")
+	})
+	return unlockSecret
+}
+
+// SignUnlockToken returns a token proving the visitor unlocked the link, valid until expiresAt.
+// Changing the link password invalidates every token issued before.
+func SignUnlockToken(link domain.Link, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + unlockSignature(link, expiry)
+}
+
+// VerifyUnlockToken reports whether the token was issued for the link and is still valid.
+func VerifyUnlockToken(link domain.Link, token string) bool {
+	expiry, signature, found := strings.Cut(token, ".")
+	if !found {
+		return false
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(unlockSignature(link, expiry)))
+}
+
+func unlockSignature(link domain.Link, expiry string) string {
+	mac := hmac.New(sha256.New, getUnlockSecret())
+	mac.Write([]byte(link.ID.String() + "|" + expiry + "|" + link.PasswordHash))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,67 @@
+package link
+
+import (
+	"fmt"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/urlsafety"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"math/rand"
+)
+
+// maxVariants is the maximum number of weighted destinations of a link.
+const maxVariants = 10
+
+// maxVariantWeight is the maximum weight of a variant, so the total weight of a link can't overflow.
+const maxVariantWeight = 10000
+
+// toVariants validates the weighted destinations of a request and converts them to variants.
+func toVariants(request []web.Variant, urls *urlsafety.Checker) ([]domain.LinkVariant, error) {
+	variants := make([]domain.LinkVariant, 0, len(request))
+	for _, v := range request {
+		variants = append(variants, domain.LinkVariant{
+			Label:       v.Label,
+			Destination: v.Destination,
+			Weight:      v.Weight,
+		})
+	}
+	return variants, validateVariants(variants, urls)
+}
+
+// validateVariants checks the number, destinations and weights of the variants of a link, normalising
+// their destinations.
+func validateVariants(variants []domain.LinkVariant, urls *urlsafety.Checker) error {
+	if len(variants) > maxVariants {
+		return fmt.Errorf("a link supports at most %d variants", maxVariants)
+	}
+	for i, v := range variants {
+		destination, err := urls.Check(v.Destination)
+		if err != nil {
+			return fmt.Errorf("invalid variant destination: %v", err)
+		}
+		variants[i].Destination = destination
+		if v.Weight <= 0 || v.Weight > maxVariantWeight {
+			return fmt.Errorf("the weight of the variant `%s` must be between 1 and %d", v.Destination, maxVariantWeight)
+		}
+	}
+	return nil
+}
+
+// ChooseVariant picks one of the variants with a probability proportional to its weight.
+func ChooseVariant(variants []domain.LinkVariant) domain.LinkVariant {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+	// Only weights saved before they were capped can make the total overflow
+	if total <= 0 {
+		return variants[0]
+	}
+	pick := rand.Intn(total)
+	for _, v := range variants {
+		if pick < v.Weight {
+			return v
+		}
+		pick -= v.Weight
+	}
+	return variants[len(variants)-1]
+}
@@ -0,0 +1,123 @@
+package link
+
+import (
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/urlsafety"
+	"strings"
+	"testing"
+)
+
+func TestChooseVariant(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []domain.LinkVariant
+		want     map[string]bool
+	}{
+		{
+			name:     "single variant",
+			variants: []domain.LinkVariant{{Label: "a", Weight: 1}},
+			want:     map[string]bool{"a": true},
+		},
+		{
+			name:     "every weighted variant can be chosen",
+			variants: []domain.LinkVariant{{Label: "a", Weight: 1}, {Label: "b", Weight: 1}},
+			want:     map[string]bool{"a": true, "b": true},
+		},
+		{
+			name:     "variants without weight are never chosen",
+			variants: []domain.LinkVariant{{Label: "a", Weight: 0}, {Label: "b", Weight: 3}, {Label: "c", Weight: 0}},
+			want:     map[string]bool{"b": true},
+		},
+		{
+			name:     "the first variant when there is no weight",
+			variants: []domain.LinkVariant{{Label: "a", Weight: 0}, {Label: "b", Weight: 0}},
+			want:     map[string]bool{"a": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chosen := make(map[string]bool)
+			for i := 0; i < 1000; i++ {
+				chosen[ChooseVariant(tt.variants).Label] = true
+			}
+			for label := range chosen {
+				if !tt.want[label] {
+					t.Errorf("ChooseVariant() chose %q, want one of %v", label, tt.want)
+				}
+			}
+			for label := range tt.want {
+				if !chosen[label] {
+					t.Errorf("ChooseVariant() never chose %q", label)
+				}
+			}
+		})
+	}
+}
+
+func TestChooseVariantProportions(t *testing.T) {
+	variants := []domain.LinkVariant{{Label: "a", Weight: 1}, {Label: "b", Weight: 3}}
+	counts := make(map[string]int)
+	const picks = 20000
+	for i := 0; i < picks; i++ {
+		counts[ChooseVariant(variants).Label]++
+	}
+	// b is expected three quarters of the time, 15000 picks, with a margin far above the deviation
+	if counts["b"] < 14400 || counts["b"] > 15600 {
+		t.Errorf("ChooseVariant() chose b %d times out of %d, want about 15000", counts["b"], picks)
+	}
+}
+
+func TestValidateVariants(t *testing.T) {
+	urls := urlsafety.NewChecker(false)
+	tests := []struct {
+		name     string
+		variants []domain.LinkVariant
+		want     []string
+		err      string
+	}{
+		{
+			name:     "destinations are normalised",
+			variants: []domain.LinkVariant{{Destination: "Example.com/a", Weight: 1}, {Destination: "http://example.com:80/b", Weight: maxVariantWeight}},
+			want:     []string{"https://example.com/a", "http://example.com/b"},
+		},
+		{
+			name:     "unsafe destination",
+			variants: []domain.LinkVariant{{Destination: "http://127.0.0.1/admin", Weight: 1}},
+			err:      "invalid variant destination",
+		},
+		{
+			name:     "zero weight",
+			variants: []domain.LinkVariant{{Destination: "https://example.com", Weight: 0}},
+			err:      "must be between 1 and",
+		},
+		{
+			name:     "weight above the maximum",
+			variants: []domain.LinkVariant{{Destination: "https://example.com", Weight: maxVariantWeight + 1}},
+			err:      "must be between 1 and",
+		},
+		{
+			name:     "too many variants",
+			variants: make([]domain.LinkVariant, maxVariants+1),
+			err:      "at most",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateVariants(tt.variants, urls)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("validateVariants() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateVariants() error = %v", err)
+			}
+			for i, v := range tt.variants {
+				if v.Destination != tt.want[i] {
+					t.Errorf("variant %d destination = %q, want %q", i, v.Destination, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -3,8 +3,10 @@ package links_page
 import (
 	"github.com/google/uuid"
 	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/utils"
 	"gorm.io/gorm"
 	"log"
+	"time"
 )
 
 type Repository interface {
@@ -12,11 +14,16 @@ type Repository interface {
 	FindByAddress(address string) (*domain.LinksPage, error)
 	FindByAlias(alias string) (*domain.LinksPage, error)
 	FindAllByUser(userId string) (*[]domain.LinksPage, error)
+	FindAllByWorkspace(workspaceId uuid.UUID) (*[]domain.LinksPage, error)
+	StreamAllByUser(userId string, fn func(linksPage domain.LinksPage) error) error
 	Create(linksPage *domain.LinksPage) error
 	Update(linksPage *domain.LinksPage) error
 	Delete(linksPage *domain.LinksPage) error
 }
 
+// streamBatchSize is the number of links pages loaded at once while streaming.
+const streamBatchSize = 100
+
 type linksPageRepository struct {
 	db *gorm.DB
 }
@@ -43,8 +50,6 @@ func (r *linksPageRepository) FindByAlias(alias string) (*domain.LinksPage, erro
 		log.Printf("ERROR: unable to find the links page by address due to %v", err.Error())
 		return nil, err
 	}
-	log.Printf("INFO: updating views for links page: %v", linksPage.ID)
-	go r.db.Raw("UPDATE links_pages SET views = views + 1 WHERE id = ?", linksPage.ID).Scan(&linksPage)
 	return &linksPage, nil
 }
 
@@ -58,16 +63,47 @@ func (r *linksPageRepository) FindByAddress(finalURL string) (*domain.LinksPage,
 	return &linksPage, nil
 }
 
-// FindAllByUser finds all linksPage by user
+// FindAllByUser finds all personal linksPage by user, leaving out the ones shared in workspaces
 func (r *linksPageRepository) FindAllByUser(userId string) (*[]domain.LinksPage, error) {
 	var linksPage []domain.LinksPage
-	if err := r.db.Where("user_id = ?", userId).Preload("Links").Find(&linksPage).Error; err != nil {
+	if err := r.db.Where("user_id = ? AND workspace_id IS NULL", userId).Preload("Links").Find(&linksPage).Error; err != nil {
 		log.Printf("unable to find the links page by user: %v", err.Error())
 		return nil, err
 	}
 	return &linksPage, nil
 }
 
+// FindAllByWorkspace finds all linksPage by workspace
+func (r *linksPageRepository) FindAllByWorkspace(workspaceId uuid.UUID) (*[]domain.LinksPage, error) {
+	var linksPage []domain.LinksPage
+	if err := r.db.Where("workspace_id = ?", workspaceId).Preload("Links").Find(&linksPage).Error; err != nil {
+		log.Printf("unable to find the links page by workspace: %v", err.Error())
+		return nil, err
+	}
+	return &linksPage, nil
+}
+
+// StreamAllByUser calls fn for every personal linksPage of the user by creation, loading them in batches
+func (r *linksPageRepository) StreamAllByUser(userId string, fn func(linksPage domain.LinksPage) error) error {
+	var createdAt time.Time
+	var id uuid.UUID
+	for {
+		var linksPages []domain.LinksPage
+		if err := r.db.Where("user_id = ? AND workspace_id IS NULL", userId).Preload("Links").Scopes(utils.CreatedAfter(createdAt, id, streamBatchSize)).Find(&linksPages).Error; err != nil {
+			return err
+		}
+		for _, linksPage := range linksPages {
+			if err := fn(linksPage); err != nil {
+				return err
+			}
+		}
+		if len(linksPages) < streamBatchSize {
+			return nil
+		}
+		createdAt, id = linksPages[len(linksPages)-1].CreatedAt, linksPages[len(linksPages)-1].ID
+	}
+}
+
 // Create creates a new linksPage
 func (r *linksPageRepository) Create(linksPage *domain.LinksPage) error {
 	return r.db.Create(linksPage).Error
@@ -0,0 +1,49 @@
+package links_page
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/testdb"
+	"testing"
+	"time"
+)
+
+func TestStreamAllByUser(t *testing.T) {
+	db := testdb.Open(t, &domain.Link{}, &domain.LinksPage{})
+	r := NewLinksPageRepository(db)
+
+	// More than two batches, pages created at the same instant are ordered by their ID
+	created := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	var pages []domain.LinksPage
+	for i := 0; i < streamBatchSize*2+10; i++ {
+		pages = append(pages, domain.LinksPage{
+			Alias:     fmt.Sprintf("stream%d", i),
+			Domain:    "5lnk.live",
+			UserId:    "streamer",
+			CreatedAt: created.Add(time.Duration(i/3) * time.Second),
+		})
+	}
+	pages = append(pages, domain.LinksPage{Alias: "other-user", Domain: "5lnk.live", UserId: "someone-else", CreatedAt: created})
+	if err := db.CreateInBatches(&pages, 100).Error; err != nil {
+		t.Fatalf("unable to create the pages: %v", err)
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	err := r.StreamAllByUser("streamer", func(linksPage domain.LinksPage) error {
+		if seen[linksPage.ID] {
+			return fmt.Errorf("the page `%s` was streamed twice", linksPage.Alias)
+		}
+		seen[linksPage.ID] = true
+		if linksPage.UserId != "streamer" {
+			return fmt.Errorf("the page `%s` isn't a page of the user", linksPage.Alias)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamAllByUser() error = %v", err)
+	}
+	if want := streamBatchSize*2 + 10; len(seen) != want {
+		t.Errorf("StreamAllByUser() streamed %d pages, want %d", len(seen), want)
+	}
+}
@@ -2,9 +2,12 @@ package links_page
 
 import (
 	"fmt"
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/alias"
 	"github.com/ronilsonalves/5lnk/internal/domain"
 	"github.com/ronilsonalves/5lnk/internal/link"
-	"github.com/ronilsonalves/5lnk/internal/utils"
+	"github.com/ronilsonalves/5lnk/internal/urlsafety"
+	"github.com/ronilsonalves/5lnk/internal/workspace"
 	"github.com/ronilsonalves/5lnk/pkg/web"
 	"log"
 	"os"
@@ -16,18 +19,23 @@ type Service interface {
 	Create(request web.CreateLinksPage) (domain.LinksPage, error)
 	GetLinksPageByAlias(address string) (*domain.LinksPage, error)
 	GetAllByUser(userId string) (*[]domain.LinksPage, error)
-	Update(request domain.LinksPage) (domain.LinksPage, error)
-	Delete(request domain.LinksPage) error
+	GetAllByWorkspace(workspaceId uuid.UUID) (*[]domain.LinksPage, error)
+	GetAuthorizedPage(userId string, pageId uuid.UUID, role string) (domain.LinksPage, error)
+	Update(userId string, request domain.LinksPage) (domain.LinksPage, error)
+	Delete(userId string, request domain.LinksPage) error
 }
 
 type linksPageService struct {
-	r  Repository
-	ls link.Service
+	r          Repository
+	ls         link.Service
+	aliases    *alias.Policy
+	urls       *urlsafety.Checker
+	workspaces workspace.Service
 }
 
 // NewLinksPageService creates a new linksPage service
-func NewLinksPageService(r Repository, ls link.Service) Service {
-	return &linksPageService{r: r, ls: ls}
+func NewLinksPageService(r Repository, ls link.Service, aliases *alias.Policy, urls *urlsafety.Checker, workspaces workspace.Service) Service {
+	return &linksPageService{r: r, ls: ls, aliases: aliases, urls: urls, workspaces: workspaces}
 }
 
 // GetLinksPageByAlias returns a linksPage by the alias
@@ -40,9 +48,38 @@ func (s *linksPageService) GetAllByUser(userId string) (*[]domain.LinksPage, err
 	return s.r.FindAllByUser(userId)
 }
 
+// GetAllByWorkspace finds all linksPage by workspace
+func (s *linksPageService) GetAllByWorkspace(workspaceId uuid.UUID) (*[]domain.LinksPage, error) {
+	return s.r.FindAllByWorkspace(workspaceId)
+}
+
+// GetAuthorizedPage returns a linksPage by the ID when the user owns it, or has the role in its workspace. Pages the
+// user can't see are reported as not found, so their existence isn't disclosed.
+func (s *linksPageService) GetAuthorizedPage(userId string, pageId uuid.UUID, role string) (domain.LinksPage, error) {
+	linksPage, err := s.r.FindById(pageId)
+	if err != nil {
+		return domain.LinksPage{}, err
+	}
+	if err := s.workspaces.AuthorizeResource(linksPage.UserId, linksPage.WorkspaceId, userId, role); err != nil {
+		return domain.LinksPage{}, err
+	}
+	return linksPage, nil
+}
+
 // Create creates a new linksPage
 func (s *linksPageService) Create(request web.CreateLinksPage) (domain.LinksPage, error) {
 	log.Printf("INFO: validating data for linksPage: %v", request.Alias)
+	if request.WorkspaceId != nil {
+		if _, err := s.workspaces.Authorize(*request.WorkspaceId, request.UserId, domain.RoleEditor); err != nil {
+			return domain.LinksPage{}, err
+		}
+	}
+	request.Alias = s.aliases.Normalize(request.Alias)
+	if err := s.aliases.Validate(request.Alias); err != nil {
+		log.Printf("ERROR: the alias `%s` is not valid: %v", request.Alias, err.Error())
+		return domain.LinksPage{}, err
+	}
+
 	// Before create a new linksPage, check if the address already exists
 	if _, err := s.r.FindByAddress("https://" + request.Domain + "/" + request.Alias); err == nil {
 		log.Printf("ERROR: the alias address `%s` already exists.", request.Alias)
@@ -50,25 +87,32 @@ func (s *linksPageService) Create(request web.CreateLinksPage) (domain.LinksPage
 	}
 
 	// Also, check if the links page alias already been used as a shortened URL
-	_, err := s.ls.GetOriginalURL(request.Alias)
-	if err != nil {
-		if err.Error() != "record not found" {
-			log.Printf("ERROR: the alias address `%s` already exists and being used as a shortened URL.", request.Alias)
-			return domain.LinksPage{}, fmt.Errorf("the address `%s` already been used by a shortened URL", request.Alias)
-		}
+	if _, err := s.ls.GetOriginalURL(request.Alias); err == nil {
+		log.Printf("ERROR: the alias address `%s` already exists and being used as a shortened URL.", request.Alias)
+		return domain.LinksPage{}, fmt.Errorf("the address `%s` already been used by a shortened URL", request.Alias)
 	}
 
 	// Create short URLs for each link
 	var links []domain.Link
 	for _, lnkReq := range request.Links {
-		shortURL := utils.GenerateRandomAlias("")
+		original, err := s.urls.Check(lnkReq.Original)
+		if err != nil {
+			log.Printf("ERROR: the link `%s` is not valid: %v", lnkReq.Original, err.Error())
+			return domain.LinksPage{}, err
+		}
+		shortURL, err := s.ls.GenerateShortened(request.Domain)
+		if err != nil {
+			log.Printf("ERROR: unable to generate the shortened URL for the link `%s` due to %v", lnkReq.Original, err.Error())
+			return domain.LinksPage{}, err
+		}
 		lnk := domain.Link{
-			Original:  lnkReq.Original,
-			Title:     lnkReq.Title,
-			Shortened: shortURL,
-			FinalURL:  "https://" + request.Domain + "/" + shortURL,
-			UserId:    request.UserId,
-			CreatedAt: time.Now(),
+			Original:    original,
+			Title:       lnkReq.Title,
+			Shortened:   shortURL,
+			FinalURL:    "https://" + request.Domain + "/" + shortURL,
+			UserId:      request.UserId,
+			WorkspaceId: request.WorkspaceId,
+			CreatedAt:   time.Now(),
 		}
 		links = append(links, lnk)
 	}
@@ -80,6 +124,7 @@ func (s *linksPageService) Create(request web.CreateLinksPage) (domain.LinksPage
 		FinalURL:    "https://" + request.Domain + "/" + request.Alias,
 		Links:       links,
 		UserId:      request.UserId,
+		WorkspaceId: request.WorkspaceId,
 		Title:       request.Title,
 		Description: request.Description,
 		ImageURL:    request.ImageURL,
@@ -98,7 +143,7 @@ func (s *linksPageService) Create(request web.CreateLinksPage) (domain.LinksPage
 	if _, err := s.ls.ShortenURL(web.CreateShortenURL{
 		URL:         os.Getenv("URL_SVC") + "/" + linkPage.Alias,
 		ShortDomain: request.Domain,
-		UserId:      "CREATED_BY_SYSTEM_" + linkPage.ID.String(),
+		UserId:      systemUser(*linkPage),
 		Alias:       linkPage.Alias,
 	}); err != nil {
 		log.Printf("ERROR: unable to create the shortened URL for the linksPage: %v", err.Error())
@@ -111,16 +156,27 @@ func (s *linksPageService) Create(request web.CreateLinksPage) (domain.LinksPage
 	return *linkPage, nil
 }
 
-// Update updates a linksPage
-func (s *linksPageService) Update(request domain.LinksPage) (domain.LinksPage, error) {
-	pageUpdate, err := s.r.FindById(request.ID)
+// Update updates a linksPage of the user, or of a workspace the user edits
+func (s *linksPageService) Update(userId string, request domain.LinksPage) (domain.LinksPage, error) {
+	pageUpdate, err := s.GetAuthorizedPage(userId, request.ID, domain.RoleEditor)
 	if err != nil {
 		log.Printf("ERROR: the linksPage `%s` not found", request.Alias)
 		return domain.LinksPage{}, err
 	}
+	// A linksPage can't be handed over to another user or workspace
+	request.UserId = pageUpdate.UserId
+	request.WorkspaceId = pageUpdate.WorkspaceId
+
+	if request.Alias != pageUpdate.Alias {
+		request.Alias = s.aliases.Normalize(request.Alias)
+		if err := s.aliases.Validate(request.Alias); err != nil {
+			log.Printf("ERROR: the alias `%s` is not valid: %v", request.Alias, err.Error())
+			return domain.LinksPage{}, err
+		}
+	}
 
 	// pageShortened get the original shortened URL for the linksPage to be updated later
-	pageShortened, err := s.ls.GetShortenedByOriginal(os.Getenv("URL_SVC") + "/" + pageUpdate.Alias)
+	pageShortened, err := s.ls.GetShortenedByOriginal(os.Getenv("URL_SVC")+"/"+pageUpdate.Alias, systemUser(pageUpdate))
 	if err != nil {
 		log.Printf("ERROR: the shortened URL for links page `%s` not found... %v", request.Alias, err.Error())
 		return domain.LinksPage{}, err
@@ -129,15 +185,25 @@ func (s *linksPageService) Update(request domain.LinksPage) (domain.LinksPage, e
 	var linksToCreate []domain.Link
 	for _, lnkReq := range request.Links {
 		if strings.Compare(lnkReq.ID.String(), "00000000-0000-0000-0000-000000000000") == 0 {
-			short := utils.GenerateRandomAlias("")
+			original, err := s.urls.Check(lnkReq.Original)
+			if err != nil {
+				log.Printf("ERROR: the link `%s` is not valid: %v", lnkReq.Original, err.Error())
+				return domain.LinksPage{}, err
+			}
+			short, err := s.ls.GenerateShortened(request.Domain)
+			if err != nil {
+				log.Printf("ERROR: unable to generate the shortened URL for the link `%s` due to %v", lnkReq.Original, err.Error())
+				return domain.LinksPage{}, err
+			}
 			lnk := domain.Link{
-				Original:  lnkReq.Original,
-				Title:     lnkReq.Title,
-				Shortened: short,
-				FinalURL:  "https://" + request.Domain + "/" + short,
-				UserId:    request.UserId,
-				CreatedAt: time.Now(),
-				PageRefer: pageUpdate.ID.String(),
+				Original:    original,
+				Title:       lnkReq.Title,
+				Shortened:   short,
+				FinalURL:    "https://" + request.Domain + "/" + short,
+				UserId:      request.UserId,
+				WorkspaceId: request.WorkspaceId,
+				CreatedAt:   time.Now(),
+				PageRefer:   pageUpdate.ID.String(),
 			}
 			linksToCreate = append(linksToCreate, lnk)
 		}
@@ -145,7 +211,7 @@ func (s *linksPageService) Update(request domain.LinksPage) (domain.LinksPage, e
 
 	for _, lnk := range request.Links {
 		if strings.Compare(lnk.ID.String(), "00000000-0000-0000-0000-000000000000") != 0 {
-			if _, err := s.ls.Update(lnk); err != nil {
+			if _, err := s.ls.Update(userId, lnk); err != nil {
 				log.Printf("ERROR: unable to update the link `%v` due to %v", lnk, err.Error())
 			}
 		}
@@ -156,7 +222,7 @@ func (s *linksPageService) Update(request domain.LinksPage) (domain.LinksPage, e
 	pageShortened.Original = os.Getenv("URL_SVC") + "/" + request.Alias
 	pageShortened.Title = request.Title
 	pageShortened.FinalURL = "https://" + request.Domain + "/" + request.Alias
-	_, err = s.ls.Update(*pageShortened)
+	_, err = s.ls.Update(pageShortened.UserId, *pageShortened)
 
 	if err != nil {
 		log.Printf("ERROR: unable to update the shortened URL for the linksPage: %v", err.Error())
@@ -171,17 +237,22 @@ func (s *linksPageService) Update(request domain.LinksPage) (domain.LinksPage, e
 	return domain.LinksPage{}, fmt.Errorf("unable to update the linksPage %v", request.Alias)
 }
 
-// Delete deletes a linksPage
-func (s *linksPageService) Delete(request domain.LinksPage) error {
+// Delete deletes a linksPage of the user, or of a workspace the user edits
+func (s *linksPageService) Delete(userId string, request domain.LinksPage) error {
+	linksPage, err := s.GetAuthorizedPage(userId, request.ID, domain.RoleEditor)
+	if err != nil {
+		return err
+	}
+	request = linksPage
 	if err := s.r.Delete(&request); err == nil {
 		log.Printf("INFO: the linksPage `%s` deleted", request.Alias)
-		shortened, err := s.ls.GetShortenedByOriginal(os.Getenv("URL_SVC") + "/" + request.Alias)
+		shortened, err := s.ls.GetShortenedByOriginal(os.Getenv("URL_SVC")+"/"+request.Alias, systemUser(request))
 		if err != nil {
 			log.Printf("WARNING: the links page was deleted successfully but their shortened URL `%s` not found", request.Alias)
 			return nil
 		}
 		log.Printf("INFO: deleting the shortened URL for the linksPage: %v", request.Alias)
-		err = s.ls.Delete(*shortened)
+		err = s.ls.Delete(shortened.UserId, *shortened)
 		if err != nil {
 			log.Printf("WARNING: the shortened URL for links page `%s` not found", request.Alias)
 			return nil
@@ -192,3 +263,8 @@ func (s *linksPageService) Delete(request domain.LinksPage) error {
 	log.Printf("ERROR: unable to delete the linksPage `%s`", request.Alias)
 	return fmt.Errorf("unable to delete the linksPage %v", request.Alias)
 }
+
+// systemUser returns the owner of the shortened URL created by the system for a linksPage
+func systemUser(linksPage domain.LinksPage) string {
+	return "CREATED_BY_SYSTEM_" + linksPage.ID.String()
+}
@@ -0,0 +1,110 @@
+package rules
+
+import (
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"strings"
+	"time"
+)
+
+// Visitor holds the request attributes redirect rules are matched against.
+type Visitor struct {
+	OS        string
+	Browser   string
+	Languages []string
+	Country   string
+	Time      time.Time
+}
+
+// ParseAcceptLanguage returns the language tags of an Accept-Language header, ignoring the ones with zero quality.
+func ParseAcceptLanguage(header string) []string {
+	var languages []string
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" || strings.ReplaceAll(params, " ", "") == "q=0" {
+			continue
+		}
+		languages = append(languages, strings.ToLower(tag))
+	}
+	return languages
+}
+
+// Match reports whether all the conditions of the rule match the visitor.
+func Match(rule domain.RedirectRule, visitor Visitor) bool {
+	return matchPrefix(rule.OS, visitor.OS) &&
+		matchPrefix(rule.Browser, visitor.Browser) &&
+		matchCountry(rule.Country, visitor.Country) &&
+		matchLanguage(rule.Language, visitor.Languages) &&
+		matchHour(rule, visitor.Time)
+}
+
+// values splits a comma separated condition into lower-cased values.
+func values(condition string) []string {
+	var result []string
+	for _, value := range strings.Split(condition, ",") {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// matchPrefix matches the user agent OS or browser, e.g. `iOS` matches `iOS 17.1`.
+func matchPrefix(condition, attribute string) bool {
+	expected := values(condition)
+	if len(expected) == 0 {
+		return true
+	}
+	attribute = strings.ToLower(attribute)
+	for _, value := range expected {
+		if strings.HasPrefix(attribute, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchCountry(condition, country string) bool {
+	expected := values(condition)
+	if len(expected) == 0 {
+		return true
+	}
+	country = strings.ToLower(country)
+	for _, value := range expected {
+		if value == country {
+			return true
+		}
+	}
+	return false
+}
+
+// matchLanguage matches any of the visitor languages, e.g. `pt` matches `pt-BR`.
+func matchLanguage(condition string, languages []string) bool {
+	expected := values(condition)
+	if len(expected) == 0 {
+		return true
+	}
+	for _, value := range expected {
+		for _, language := range languages {
+			if language == value || strings.HasPrefix(language, value+"-") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchHour checks the visitor time against the rule hours, which may wrap around midnight (e.g. 22 to 6).
+func matchHour(rule domain.RedirectRule, now time.Time) bool {
+	if rule.StartHour == nil || rule.EndHour == nil {
+		return true
+	}
+	location, err := time.LoadLocation(rule.TimeZone)
+	if err != nil {
+		return false
+	}
+	hour := now.In(location).Hour()
+	if *rule.StartHour <= *rule.EndHour {
+		return hour >= *rule.StartHour && hour <= *rule.EndHour
+	}
+	return hour >= *rule.StartHour || hour <= *rule.EndHour
+}
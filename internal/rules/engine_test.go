@@ -0,0 +1,75 @@
+package rules
+
+import (
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func hour(h int) *int {
+	return &h
+}
+
+func TestMatch(t *testing.T) {
+	visitor := Visitor{
+		OS:        "iOS 17.1",
+		Browser:   "Safari 17",
+		Languages: []string{"pt-br", "en"},
+		Country:   "BR",
+		Time:      time.Date(2023, 10, 5, 23, 30, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name string
+		rule domain.RedirectRule
+		want bool
+	}{
+		{name: "rule without conditions", rule: domain.RedirectRule{}, want: true},
+		{name: "os prefix", rule: domain.RedirectRule{OS: "ios"}, want: true},
+		{name: "one of the os", rule: domain.RedirectRule{OS: "Android, iOS"}, want: true},
+		{name: "other os", rule: domain.RedirectRule{OS: "Android"}, want: false},
+		{name: "browser prefix", rule: domain.RedirectRule{Browser: "safari"}, want: true},
+		{name: "other browser", rule: domain.RedirectRule{Browser: "Chrome"}, want: false},
+		{name: "country", rule: domain.RedirectRule{Country: "us,br"}, want: true},
+		{name: "country is not a prefix", rule: domain.RedirectRule{Country: "b"}, want: false},
+		{name: "language matches its region", rule: domain.RedirectRule{Language: "pt"}, want: true},
+		{name: "language with region", rule: domain.RedirectRule{Language: "pt-BR"}, want: true},
+		{name: "language is not a prefix", rule: domain.RedirectRule{Language: "p"}, want: false},
+		{name: "other language", rule: domain.RedirectRule{Language: "es"}, want: false},
+		{name: "within the hours", rule: domain.RedirectRule{StartHour: hour(22), EndHour: hour(23)}, want: true},
+		{name: "outside the hours", rule: domain.RedirectRule{StartHour: hour(8), EndHour: hour(18)}, want: false},
+		{name: "hours wrapping around midnight", rule: domain.RedirectRule{StartHour: hour(22), EndHour: hour(6)}, want: true},
+		{name: "hours in the rule time zone", rule: domain.RedirectRule{StartHour: hour(20), EndHour: hour(20), TimeZone: "America/Sao_Paulo"}, want: true},
+		{name: "unknown time zone", rule: domain.RedirectRule{StartHour: hour(0), EndHour: hour(23), TimeZone: "Nowhere/City"}, want: false},
+		{name: "only a start hour", rule: domain.RedirectRule{StartHour: hour(8)}, want: true},
+		{name: "every condition", rule: domain.RedirectRule{OS: "iOS", Browser: "Safari", Country: "BR", Language: "en"}, want: true},
+		{name: "one condition failing", rule: domain.RedirectRule{OS: "iOS", Browser: "Safari", Country: "PT"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Match(tt.rule, visitor); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{header: "", want: nil},
+		{header: "pt-BR", want: []string{"pt-br"}},
+		{header: "pt-BR,pt;q=0.9,en-US;q=0.8", want: []string{"pt-br", "pt", "en-us"}},
+		{header: "en, fr;q=0, *;q=0.1", want: []string{"en"}},
+		{header: "de; q=0 ,es", want: []string{"es"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := ParseAcceptLanguage(tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAcceptLanguage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package rules
+
+import (
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"gorm.io/gorm"
+	"log"
+)
+
+type Repository interface {
+	FindByID(id uuid.UUID) (*domain.RedirectRule, error)
+	FindAllByLink(linkId string) (*[]domain.RedirectRule, error)
+	Create(rule *domain.RedirectRule) error
+	Update(rule *domain.RedirectRule) error
+	Delete(rule *domain.RedirectRule) error
+}
+
+type rulesRepository struct {
+	db *gorm.DB
+}
+
+// NewRulesRepository creates a new redirect rules repository
+func NewRulesRepository(db *gorm.DB) Repository {
+	return &rulesRepository{db: db}
+}
+
+// FindByID finds a redirect rule by the ID
+func (r *rulesRepository) FindByID(id uuid.UUID) (*domain.RedirectRule, error) {
+	var rule domain.RedirectRule
+	if err := r.db.Where("id = ?", id).First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// FindAllByLink finds all redirect rules of a link ordered by priority
+func (r *rulesRepository) FindAllByLink(linkId string) (*[]domain.RedirectRule, error) {
+	var rules []domain.RedirectRule
+	if err := r.db.Where("link_refer = ?", linkId).Order("priority asc, created_at asc").Find(&rules).Error; err != nil {
+		log.Printf("ERROR: unable to find the redirect rules by link due to %v", err.Error())
+		return nil, err
+	}
+	return &rules, nil
+}
+
+// Create creates a new redirect rule
+func (r *rulesRepository) Create(rule *domain.RedirectRule) error {
+	return r.db.Create(rule).Error
+}
+
+// Update updates a redirect rule, including the conditions being cleared
+func (r *rulesRepository) Update(rule *domain.RedirectRule) error {
+	return r.db.Model(rule).Select("*").Omit("id", "link_refer", "created_at").Updates(rule).Error
+}
+
+// Delete deletes a redirect rule
+func (r *rulesRepository) Delete(rule *domain.RedirectRule) error {
+	return r.db.Where("id = ?", rule.ID).Delete(rule).Error
+}
@@ -0,0 +1,129 @@
+package rules
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/urlsafety"
+	"log"
+	"time"
+)
+
+type Service interface {
+	GetRulesByLink(linkId uuid.UUID) (*[]domain.RedirectRule, error)
+	GetDestinationsByLink(linkId uuid.UUID) ([]string, error)
+	Create(linkId uuid.UUID, rule domain.RedirectRule) (domain.RedirectRule, error)
+	Update(linkId uuid.UUID, rule domain.RedirectRule) (domain.RedirectRule, error)
+	Delete(linkId, ruleId uuid.UUID) error
+	Resolve(linkId uuid.UUID, visitor Visitor) (string, bool)
+}
+
+// Resolver checks a destination of a link, following it like the link destinations so it can't loop back.
+type Resolver func(linkId uuid.UUID, destination string) (string, error)
+
+type rulesService struct {
+	r       Repository
+	urls    *urlsafety.Checker
+	resolve Resolver
+}
+
+// NewRulesService creates a new redirect rules service. The rule destinations are checked by resolve, or only by the
+// url safety checker when it is nil.
+func NewRulesService(r Repository, urls *urlsafety.Checker, resolve Resolver) Service {
+	return &rulesService{r: r, urls: urls, resolve: resolve}
+}
+
+// GetRulesByLink returns the redirect rules of a link ordered by priority
+func (s *rulesService) GetRulesByLink(linkId uuid.UUID) (*[]domain.RedirectRule, error) {
+	return s.r.FindAllByLink(linkId.String())
+}
+
+// GetDestinationsByLink returns the destinations of the redirect rules of a link
+func (s *rulesService) GetDestinationsByLink(linkId uuid.UUID) ([]string, error) {
+	rules, err := s.r.FindAllByLink(linkId.String())
+	if err != nil {
+		return nil, err
+	}
+	destinations := make([]string, 0, len(*rules))
+	for _, rule := range *rules {
+		destinations = append(destinations, rule.Destination)
+	}
+	return destinations, nil
+}
+
+// Create creates a new redirect rule for a link
+func (s *rulesService) Create(linkId uuid.UUID, rule domain.RedirectRule) (domain.RedirectRule, error) {
+	if err := s.validate(linkId, &rule); err != nil {
+		return domain.RedirectRule{}, err
+	}
+	rule.LinkRefer = linkId.String()
+	if err := s.r.Create(&rule); err != nil {
+		log.Printf("ERROR: unable to create the redirect rule due to %v", err.Error())
+		return domain.RedirectRule{}, fmt.Errorf("unable to create the redirect rule")
+	}
+	return rule, nil
+}
+
+// Update updates a redirect rule of a link
+func (s *rulesService) Update(linkId uuid.UUID, rule domain.RedirectRule) (domain.RedirectRule, error) {
+	current, err := s.r.FindByID(rule.ID)
+	if err != nil || current.LinkRefer != linkId.String() {
+		return domain.RedirectRule{}, fmt.Errorf("record not found")
+	}
+	if err := s.validate(linkId, &rule); err != nil {
+		return domain.RedirectRule{}, err
+	}
+	rule.LinkRefer = current.LinkRefer
+	rule.CreatedAt = current.CreatedAt
+	if err := s.r.Update(&rule); err != nil {
+		log.Printf("ERROR: unable to update the redirect rule due to %v", err.Error())
+		return domain.RedirectRule{}, fmt.Errorf("unable to update the redirect rule")
+	}
+	return rule, nil
+}
+
+// Delete deletes a redirect rule of a link
+func (s *rulesService) Delete(linkId, ruleId uuid.UUID) error {
+	rule, err := s.r.FindByID(ruleId)
+	if err != nil || rule.LinkRefer != linkId.String() {
+		return fmt.Errorf("record not found")
+	}
+	return s.r.Delete(rule)
+}
+
+// Resolve returns the destination of the first rule of the link matching the visitor
+func (s *rulesService) Resolve(linkId uuid.UUID, visitor Visitor) (string, bool) {
+	rules, err := s.r.FindAllByLink(linkId.String())
+	if err != nil {
+		return "", false
+	}
+	for _, rule := range *rules {
+		if Match(rule, visitor) {
+			return rule.Destination, true
+		}
+	}
+	return "", false
+}
+
+// validate checks the destination and the time conditions of a rule of the link, normalising its destination
+func (s *rulesService) validate(linkId uuid.UUID, rule *domain.RedirectRule) error {
+	check := func(destination string) (string, error) { return s.urls.Check(destination) }
+	if s.resolve != nil {
+		check = func(destination string) (string, error) { return s.resolve(linkId, destination) }
+	}
+	destination, err := check(rule.Destination)
+	if err != nil {
+		return fmt.Errorf("invalid destination: %v", err)
+	}
+	rule.Destination = destination
+	if (rule.StartHour == nil) != (rule.EndHour == nil) {
+		return fmt.Errorf("both startHour and endHour must be provided")
+	}
+	if rule.StartHour != nil && (*rule.StartHour < 0 || *rule.StartHour > 23 || *rule.EndHour < 0 || *rule.EndHour > 23) {
+		return fmt.Errorf("startHour and endHour must be between 0 and 23")
+	}
+	if _, err := time.LoadLocation(rule.TimeZone); err != nil {
+		return fmt.Errorf("invalid time zone `%s` provided", rule.TimeZone)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package shortcode
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Alphabet is the default set of characters of the short codes. Characters easily mistaken for one another
+// (0/O/o, 1/l/I) are excluded.
+const Alphabet = "23456789abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// Generator creates candidate short codes. Candidates are not guaranteed to be unique, the Issuer is
+// responsible for retrying on collisions.
+type Generator interface {
+	Generate(length int) (string, error)
+}
+
+// Sequence returns the next value of a monotonic counter, e.g. a database sequence.
+type Sequence func() (uint64, error)
+
+// randomGenerator picks every character with a cryptographically secure source.
+type randomGenerator struct {
+	alphabet string
+}
+
+// NewRandomGenerator creates a generator of random codes.
+func NewRandomGenerator(alphabet string) Generator {
+	return &randomGenerator{alphabet: alphabet}
+}
+
+func (g *randomGenerator) Generate(length int) (string, error) {
+	max := big.NewInt(int64(len(g.alphabet)))
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("unable to generate a random short code: %v", err)
+		}
+		code[i] = g.alphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// sequenceGenerator encodes the next value of a sequence in the alphabet base, e.g. base62.
+type sequenceGenerator struct {
+	alphabet string
+	next     Sequence
+}
+
+// NewSequenceGenerator creates a generator of codes encoding a sequence, padded to the requested length.
+func NewSequenceGenerator(alphabet string, next Sequence) Generator {
+	return &sequenceGenerator{alphabet: alphabet, next: next}
+}
+
+func (g *sequenceGenerator) Generate(length int) (string, error) {
+	n, err := g.next()
+	if err != nil {
+		return "", fmt.Errorf("unable to read the short code sequence: %v", err)
+	}
+	code := encode(n, g.alphabet)
+	if len(code) < length {
+		code = strings.Repeat(g.alphabet[:1], length-len(code)) + code
+	}
+	return code, nil
+}
+
+// hashidsGenerator encodes the next value of a sequence with an alphabet shuffled by a secret salt, so
+// codes do not reveal how many links exist nor can be enumerated.
+type hashidsGenerator struct {
+	alphabet string
+	salt     string
+	next     Sequence
+}
+
+// NewHashidsGenerator creates a generator of hashids-style codes.
+func NewHashidsGenerator(alphabet, salt string, next Sequence) Generator {
+	return &hashidsGenerator{alphabet: shuffle(alphabet, salt), salt: salt, next: next}
+}
+
+func (g *hashidsGenerator) Generate(length int) (string, error) {
+	n, err := g.next()
+	if err != nil {
+		return "", fmt.Errorf("unable to read the short code sequence: %v", err)
+	}
+
+	// The lottery character selects a per-code alphabet, as hashids does
+	lottery := g.alphabet[n%uint64(len(g.alphabet))]
+	alphabet := shuffle(g.alphabet, string(lottery)+g.salt)
+	code := string(lottery) + encode(n, alphabet)
+
+	// Pad with guard characters derived from the code itself
+	for i := 0; len(code) < length; i++ {
+		code += string(alphabet[(int(code[i%len(code)])+i)%len(alphabet)])
+	}
+	return code, nil
+}
+
+// wordsGenerator combines words into human-readable codes such as `brave-otter-42`.
+type wordsGenerator struct {
+	adjectives []string
+	nouns      []string
+}
+
+// NewWordsGenerator creates a generator of human-readable codes. The length is ignored.
+func NewWordsGenerator() Generator {
+	return &wordsGenerator{adjectives: adjectives, nouns: nouns}
+}
+
+func (g *wordsGenerator) Generate(int) (string, error) {
+	adjective, err := rand.Int(rand.Reader, big.NewInt(int64(len(g.adjectives))))
+	if err != nil {
+		return "", fmt.Errorf("unable to generate a short code: %v", err)
+	}
+	noun, err := rand.Int(rand.Reader, big.NewInt(int64(len(g.nouns))))
+	if err != nil {
+		return "", fmt.Errorf("unable to generate a short code: %v", err)
+	}
+	number, err := rand.Int(rand.Reader, big.NewInt(90))
+	if err != nil {
+		return "", fmt.Errorf("unable to generate a short code: %v", err)
+	}
+	return fmt.Sprintf("%s-%s-%d", g.adjectives[adjective.Int64()], g.nouns[noun.Int64()], number.Int64()+10), nil
+}
+
+// encode writes n in the base of the alphabet length.
+func encode(n uint64, alphabet string) string {
+	base := uint64(len(alphabet))
+	if n == 0 {
+		return alphabet[:1]
+	}
+	var code []byte
+	for ; n > 0; n /= base {
+		code = append([]byte{alphabet[n%base]}, code...)
+	}
+	return string(code)
+}
+
+// shuffle reorders the alphabet deterministically from the salt, following the hashids consistent shuffle.
+func shuffle(alphabet, salt string) string {
+	if salt == "" {
+		return alphabet
+	}
+	result := []byte(alphabet)
+	for i, v, p := len(result)-1, 0, 0; i > 0; i, v = i-1, v+1 {
+		v %= len(salt)
+		p += int(salt[v])
+		j := (int(salt[v]) + v + p) % i
+		result[i], result[j] = result[j], result[i]
+	}
+	return string(result)
+}
@@ -0,0 +1,131 @@
+package shortcode
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// counter returns a sequence starting at start.
+func counter(start uint64) Sequence {
+	n := start
+	return func() (uint64, error) {
+		n++
+		return n - 1, nil
+	}
+}
+
+func TestRandomGenerator(t *testing.T) {
+	g := NewRandomGenerator(Alphabet)
+	for _, length := range []int{1, 6, 12} {
+		code, err := g.Generate(length)
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if len(code) != length {
+			t.Errorf("Generate(%d) = %q, want %d characters", length, code, length)
+		}
+		if strings.Trim(code, Alphabet) != "" {
+			t.Errorf("Generate(%d) = %q, want only characters of the alphabet", length, code)
+		}
+	}
+}
+
+func TestSequenceGenerator(t *testing.T) {
+	tests := []struct {
+		name   string
+		n      uint64
+		length int
+		want   string
+	}{
+		{name: "zero", n: 0, length: 1, want: "2"},
+		{name: "padded", n: 1, length: 4, want: "2223"},
+		{name: "last digit", n: 55, length: 1, want: "Z"},
+		{name: "second digit", n: 56, length: 1, want: "32"},
+		{name: "longer than the length", n: 56 * 56, length: 2, want: "322"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := NewSequenceGenerator(Alphabet, counter(tt.n)).Generate(tt.length)
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if code != tt.want {
+				t.Errorf("Generate() = %q, want %q", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashidsGenerator(t *testing.T) {
+	g := NewHashidsGenerator(Alphabet, "secret", counter(0))
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		code, err := g.Generate(6)
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if len(code) < 6 || strings.Trim(code, Alphabet) != "" {
+			t.Fatalf("Generate() = %q, want at least 6 characters of the alphabet", code)
+		}
+		if seen[code] {
+			t.Fatalf("Generate() = %q twice", code)
+		}
+		seen[code] = true
+	}
+
+	first, _ := NewHashidsGenerator(Alphabet, "secret", counter(42)).Generate(6)
+	again, _ := NewHashidsGenerator(Alphabet, "secret", counter(42)).Generate(6)
+	other, _ := NewHashidsGenerator(Alphabet, "other", counter(42)).Generate(6)
+	if first != again {
+		t.Errorf("Generate() = %q and %q for the same value and salt", first, again)
+	}
+	if first == other {
+		t.Errorf("Generate() = %q for different salts", first)
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	tests := []struct {
+		name string
+		salt string
+	}{
+		{name: "without salt", salt: ""},
+		{name: "short salt", salt: "a"},
+		{name: "long salt", salt: "a salt longer than the alphabet itself, used to shuffle it"},
+	}
+	sorted := func(s string) string {
+		b := []byte(s)
+		sort.Slice(b, func(i, j int) bool { return b[i] < b[j] })
+		return string(b)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shuffled := shuffle(Alphabet, tt.salt)
+			if sorted(shuffled) != sorted(Alphabet) {
+				t.Errorf("shuffle() = %q, want a permutation of the alphabet", shuffled)
+			}
+			if shuffled != shuffle(Alphabet, tt.salt) {
+				t.Errorf("shuffle() isn't deterministic")
+			}
+			if (tt.salt == "") != (shuffled == Alphabet) {
+				t.Errorf("shuffle() = %q, want the alphabet shuffled only with a salt", shuffled)
+			}
+		})
+	}
+}
+
+func TestWordsGenerator(t *testing.T) {
+	pattern := regexp.MustCompile(`^[a-z]+-[a-z]+-[1-9][0-9]$`)
+	g := NewWordsGenerator()
+	for i := 0; i < 100; i++ {
+		code, err := g.Generate(6)
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if !pattern.MatchString(code) {
+			t.Fatalf("Generate() = %q, want adjective-noun-number", code)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package shortcode
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxAttempts is how many candidates are generated before giving up on a collision-free code.
+const maxAttempts = 10
+
+// Issuer generates short codes not used yet, with a configurable length per domain.
+type Issuer struct {
+	generator     Generator
+	defaultLength int
+	domainLengths map[string]int
+}
+
+// NewIssuer creates an issuer using the generator, the default length and the lengths of specific domains.
+func NewIssuer(generator Generator, defaultLength int, domainLengths map[string]int) *Issuer {
+	return &Issuer{generator: generator, defaultLength: defaultLength, domainLengths: domainLengths}
+}
+
+// NewIssuerFromEnv creates an issuer from the SHORTCODE_* environment variables:
+//   - SHORTCODE_STRATEGY: random (default), sequence, hashids or words
+//   - SHORTCODE_LENGTH: the default length, 6 when not set
+//   - SHORTCODE_DOMAIN_LENGTHS: lengths of specific domains, e.g. `5lnk.live=5,go.example.com=8`
+//   - SHORTCODE_SALT: the secret salt of the hashids strategy
+func NewIssuerFromEnv(next Sequence) (*Issuer, error) {
+	var generator Generator
+	switch strategy := os.Getenv("SHORTCODE_STRATEGY"); strategy {
+	case "", "random":
+		generator = NewRandomGenerator(Alphabet)
+	case "sequence":
+		generator = NewSequenceGenerator(Alphabet, next)
+	case "hashids":
+		generator = NewHashidsGenerator(Alphabet, os.Getenv("SHORTCODE_SALT"), next)
+	case "words":
+		generator = NewWordsGenerator()
+	default:
+		return nil, fmt.Errorf("invalid short code strategy `%s`", strategy)
+	}
+
+	defaultLength := 6
+	if value := os.Getenv("SHORTCODE_LENGTH"); value != "" {
+		length, err := strconv.Atoi(value)
+		if err != nil || length < 1 {
+			return nil, fmt.Errorf("invalid short code length `%s`", value)
+		}
+		defaultLength = length
+	}
+
+	domainLengths := make(map[string]int)
+	for _, entry := range strings.Split(os.Getenv("SHORTCODE_DOMAIN_LENGTHS"), ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		domain, value, _ := strings.Cut(entry, "=")
+		length, err := strconv.Atoi(value)
+		if err != nil || length < 1 {
+			return nil, fmt.Errorf("invalid short code length for domain `%s`", domain)
+		}
+		domainLengths[strings.ToLower(domain)] = length
+	}
+	return NewIssuer(generator, defaultLength, domainLengths), nil
+}
+
+// Length returns the length of the codes of a domain.
+func (i *Issuer) Length(domain string) int {
+	if length, ok := i.domainLengths[strings.ToLower(domain)]; ok {
+		return length
+	}
+	return i.defaultLength
+}
+
+// Issue returns a code for the domain for which taken reports false.
+func (i *Issuer) Issue(domain string, taken func(code string) (bool, error)) (string, error) {
+	length := i.Length(domain)
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		code, err := i.generator.Generate(length)
+		if err != nil {
+			return "", err
+		}
+		exists, err := taken(code)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return code, nil
+		}
+		log.Printf("WARNING: short code collision on attempt %d for domain `%s`", attempt, domain)
+	}
+	return "", fmt.Errorf("unable to generate an unused short code after %d attempts", maxAttempts)
+}
@@ -0,0 +1,156 @@
+package shortcode
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fixedGenerator returns the codes in order, then fails.
+type fixedGenerator struct {
+	codes   []string
+	lengths []int
+}
+
+func (g *fixedGenerator) Generate(length int) (string, error) {
+	g.lengths = append(g.lengths, length)
+	if len(g.codes) == 0 {
+		return "", errors.New("no more codes")
+	}
+	code := g.codes[0]
+	g.codes = g.codes[1:]
+	return code, nil
+}
+
+// repeat returns n times the code.
+func repeat(code string, n int) []string {
+	codes := make([]string, n)
+	for i := range codes {
+		codes[i] = code
+	}
+	return codes
+}
+
+func TestIssuerLength(t *testing.T) {
+	i := NewIssuer(NewRandomGenerator(Alphabet), 6, map[string]int{"5lnk.live": 5})
+	tests := []struct {
+		domain string
+		want   int
+	}{
+		{domain: "5lnk.live", want: 5},
+		{domain: "5LNK.live", want: 5},
+		{domain: "go.example.com", want: 6},
+		{domain: "", want: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := i.Length(tt.domain); got != tt.want {
+				t.Errorf("Length() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIssue(t *testing.T) {
+	used := map[string]bool{"taken1": true, "taken2": true}
+	tests := []struct {
+		name  string
+		codes []string
+		taken func(code string) (bool, error)
+		want  string
+		err   string
+	}{
+		{
+			name:  "first candidate free",
+			codes: []string{"free"},
+			want:  "free",
+		},
+		{
+			name:  "retried on collisions",
+			codes: []string{"taken1", "taken2", "free"},
+			want:  "free",
+		},
+		{
+			name:  "gives up after the maximum attempts",
+			codes: repeat("taken1", maxAttempts+1),
+			err:   "after 10 attempts",
+		},
+		{
+			name:  "generator error",
+			codes: nil,
+			err:   "no more codes",
+		},
+		{
+			name:  "lookup error",
+			codes: []string{"free"},
+			taken: func(string) (bool, error) { return false, errors.New("database down") },
+			err:   "database down",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taken := tt.taken
+			if taken == nil {
+				taken = func(code string) (bool, error) { return used[code], nil }
+			}
+			g := &fixedGenerator{codes: tt.codes}
+			code, err := NewIssuer(g, 6, map[string]int{"5lnk.live": 4}).Issue("5lnk.live", taken)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Issue() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+			if code != tt.want {
+				t.Errorf("Issue() = %q, want %q", code, tt.want)
+			}
+			for _, length := range g.lengths {
+				if length != 4 {
+					t.Errorf("Generate() called with length %d, want the domain length 4", length)
+				}
+			}
+		})
+	}
+}
+
+func TestNewIssuerFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		length   string
+		domains  string
+		want     map[string]int
+		err      bool
+	}{
+		{name: "defaults", want: map[string]int{"": 6}},
+		{name: "lengths", strategy: "sequence", length: "8", domains: "5lnk.live=5, Go.Example.com=7", want: map[string]int{"": 8, "5lnk.live": 5, "go.example.com": 7}},
+		{name: "unknown strategy", strategy: "uuid", err: true},
+		{name: "invalid length", length: "0", err: true},
+		{name: "invalid domain length", domains: "5lnk.live", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SHORTCODE_STRATEGY", tt.strategy)
+			t.Setenv("SHORTCODE_LENGTH", tt.length)
+			t.Setenv("SHORTCODE_DOMAIN_LENGTHS", tt.domains)
+			i, err := NewIssuerFromEnv(counter(0))
+			if tt.err {
+				if err == nil {
+					t.Fatal("NewIssuerFromEnv() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewIssuerFromEnv() error = %v", err)
+			}
+			for domain, want := range tt.want {
+				if got := i.Length(domain); got != want {
+					t.Errorf("Length(%q) = %d, want %d", domain, got, want)
+				}
+			}
+		})
+	}
+}
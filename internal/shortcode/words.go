@@ -0,0 +1,21 @@
+package shortcode
+
+// adjectives and nouns are the words combined by the human-readable generator.
+var adjectives = []string{
+	"amber", "bold", "brave", "bright", "calm", "clever", "cosmic", "crisp", "curious", "daring",
+	"eager", "early", "fancy", "fast", "fierce", "gentle", "giant", "glad", "golden", "grand",
+	"happy", "hidden", "honest", "humble", "jolly", "keen", "kind", "lively", "lucky", "mellow",
+	"merry", "mighty", "misty", "modern", "neat", "nimble", "noble", "polite", "proud", "quick",
+	"quiet", "rapid", "rare", "ready", "royal", "rustic", "shiny", "silent", "silver", "simple",
+	"smart", "snowy", "solar", "spicy", "steady", "sunny", "swift", "tidy", "tiny", "vivid",
+	"warm", "wild", "wise", "witty", "young", "zesty",
+}
+
+var nouns = []string{
+	"apple", "badger", "beacon", "bison", "breeze", "canyon", "cedar", "comet", "coral", "crane",
+	"delta", "dolphin", "eagle", "ember", "falcon", "fern", "forest", "fox", "galaxy", "garden",
+	"harbor", "hawk", "island", "jaguar", "koala", "lagoon", "lantern", "lemon", "lotus", "maple",
+	"meadow", "meteor", "moose", "nebula", "ocean", "orbit", "otter", "owl", "panda", "pebble",
+	"pepper", "planet", "prairie", "quartz", "rabbit", "raven", "river", "rocket", "sparrow", "summit",
+	"thunder", "tiger", "tulip", "valley", "violet", "walrus", "willow", "wolf", "zebra", "zephyr",
+}
@@ -0,0 +1,144 @@
+package stats
+
+import (
+	"fmt"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Granularities of the buckets stats are counted by.
+const (
+	GranularityHour  = "hour"
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// bucketLabels are the formats of the bucket dates of each granularity, in Go and in PostgreSQL. Buckets are
+// labeled by their first day, weeks starting on Monday.
+var bucketLabels = map[string]struct{ layout, sql string }{
+	GranularityHour:  {"2006-01-02T15:00", `YYYY-MM-DD"T"HH24:00`},
+	GranularityDay:   {time.DateOnly, "YYYY-MM-DD"},
+	GranularityWeek:  {time.DateOnly, "YYYY-MM-DD"},
+	GranularityMonth: {time.DateOnly, "YYYY-MM-DD"},
+}
+
+// ParseGranularity validates the granularity of the buckets, by default a day.
+func ParseGranularity(raw string) (string, error) {
+	granularity := strings.ToLower(strings.TrimSpace(raw))
+	if granularity == "" {
+		return GranularityDay, nil
+	}
+	if _, ok := bucketLabels[granularity]; !ok {
+		return "", fmt.Errorf("unknown granularity `%s`, use hour, day, week or month", raw)
+	}
+	return granularity, nil
+}
+
+// ParseLocation loads the IANA time zone the buckets are computed in, by default UTC.
+func ParseLocation(raw string) (*time.Location, error) {
+	if raw == "" {
+		return time.UTC, nil
+	}
+	if raw == "Local" {
+		return nil, fmt.Errorf("unknown time zone `%s`", raw)
+	}
+	location, err := time.LoadLocation(raw)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone `%s`", raw)
+	}
+	return location, nil
+}
+
+// bucketsOf returns the granularity and the time zone of the filter, with their defaults.
+func bucketsOf(filter web.StatsFilter) (string, *time.Location) {
+	granularity, location := filter.Granularity, filter.Location
+	if _, ok := bucketLabels[granularity]; !ok {
+		granularity = GranularityDay
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	return granularity, location
+}
+
+// periodOf returns the instants the dates of the filter start and end at in its time zone, the end excluded.
+func periodOf(filter web.StatsFilter) (time.Time, time.Time, error) {
+	_, location := bucketsOf(filter)
+	start, err := time.ParseInLocation(time.DateOnly, filter.StartDate, location)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date `%s`", filter.StartDate)
+	}
+	end, err := time.ParseInLocation(time.DateOnly, filter.EndDate, location)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date `%s`", filter.EndDate)
+	}
+	return start, end.AddDate(0, 0, 1), nil
+}
+
+// hourAligned reports whether the time zone is a whole number of hours away from UTC during the period, so the
+// hourly rollups can be counted in it.
+func hourAligned(location *time.Location, start time.Time, end time.Time) bool {
+	for _, t := range []time.Time{start, end} {
+		if _, offset := t.In(location).Zone(); offset%3600 != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bucketStart returns the start of the bucket holding t.
+func bucketStart(t time.Time, granularity string) time.Time {
+	switch granularity {
+	case GranularityHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case GranularityWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+// nextBucket returns the start of the bucket following the one starting at t.
+func nextBucket(t time.Time, granularity string) time.Time {
+	switch granularity {
+	case GranularityHour:
+		return t.Add(time.Hour)
+	case GranularityWeek:
+		return t.AddDate(0, 0, 7)
+	case GranularityMonth:
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// fillBuckets adds an empty count for every bucket of the period without stats, so charts have no gaps, and
+// sorts the counts by date.
+func fillBuckets(counts []web.StatsByDate, filter web.StatsFilter) []web.StatsByDate {
+	start, end, err := periodOf(filter)
+	if err != nil {
+		return counts
+	}
+	granularity, location := bucketsOf(filter)
+	layout := bucketLabels[granularity].layout
+	seen := make(map[string]bool, len(counts))
+	for _, count := range counts {
+		seen[count.Date] = true
+	}
+	for t := bucketStart(start.In(location), granularity); t.Before(end); t = nextBucket(t, granularity) {
+		if label := t.Format(layout); !seen[label] {
+			seen[label] = true
+			counts = append(counts, web.StatsByDate{Date: label})
+		}
+	}
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].Date < counts[j].Date
+	})
+	return counts
+}
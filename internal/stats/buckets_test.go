@@ -0,0 +1,189 @@
+package stats
+
+import (
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func location(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("unable to load the time zone %s: %v", name, err)
+	}
+	return location
+}
+
+func TestParseGranularity(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "", want: GranularityDay},
+		{raw: "hour", want: GranularityHour},
+		{raw: " Week ", want: GranularityWeek},
+		{raw: "MONTH", want: GranularityMonth},
+		{raw: "year", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseGranularity(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGranularity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseGranularity() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{raw: "", want: "UTC"},
+		{raw: "America/Sao_Paulo", want: "America/Sao_Paulo"},
+		{raw: "Local", wantErr: true},
+		{raw: "Mars/Olympus_Mons", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseLocation(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLocation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("ParseLocation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBucketStart(t *testing.T) {
+	// A Sunday afternoon
+	now := time.Date(2023, 10, 8, 15, 42, 10, 0, time.UTC)
+	tests := []struct {
+		granularity string
+		want        time.Time
+	}{
+		{granularity: GranularityHour, want: time.Date(2023, 10, 8, 15, 0, 0, 0, time.UTC)},
+		{granularity: GranularityDay, want: time.Date(2023, 10, 8, 0, 0, 0, 0, time.UTC)},
+		{granularity: GranularityWeek, want: time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC)},
+		{granularity: GranularityMonth, want: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.granularity, func(t *testing.T) {
+			if got := bucketStart(now, tt.granularity); !got.Equal(tt.want) {
+				t.Errorf("bucketStart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHourAligned(t *testing.T) {
+	start := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	tests := []struct {
+		zone string
+		want bool
+	}{
+		{zone: "UTC", want: true},
+		{zone: "Europe/Berlin", want: true},
+		{zone: "America/Sao_Paulo", want: true},
+		{zone: "Asia/Kolkata", want: false},
+		{zone: "Australia/Adelaide", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.zone, func(t *testing.T) {
+			if got := hourAligned(location(t, tt.zone), start, end); got != tt.want {
+				t.Errorf("hourAligned() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// dates returns the dates of the counts.
+func dates(counts []web.StatsByDate) []string {
+	result := make([]string, len(counts))
+	for i, count := range counts {
+		result[i] = count.Date
+	}
+	return result
+}
+
+func TestFillBuckets(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter web.StatsFilter
+		counts []web.StatsByDate
+		want   []string
+	}{
+		{
+			name:   "days without stats",
+			filter: web.StatsFilter{StartDate: "2023-10-01", EndDate: "2023-10-03"},
+			counts: []web.StatsByDate{{Date: "2023-10-02", Total: 3}},
+			want:   []string{"2023-10-01", "2023-10-02", "2023-10-03"},
+		},
+		{
+			name:   "counts of the same day kept",
+			filter: web.StatsFilter{StartDate: "2023-10-01", EndDate: "2023-10-02", Granularity: GranularityDay},
+			counts: []web.StatsByDate{{Date: "2023-10-02", OS: "iOS"}, {Date: "2023-10-01", OS: "iOS"}, {Date: "2023-10-02", OS: "Android"}},
+			want:   []string{"2023-10-01", "2023-10-02", "2023-10-02"},
+		},
+		{
+			name:   "weeks starting on monday",
+			filter: web.StatsFilter{StartDate: "2023-10-04", EndDate: "2023-10-16", Granularity: GranularityWeek},
+			want:   []string{"2023-10-02", "2023-10-09", "2023-10-16"},
+		},
+		{
+			name:   "months",
+			filter: web.StatsFilter{StartDate: "2023-01-31", EndDate: "2023-03-01", Granularity: GranularityMonth},
+			want:   []string{"2023-01-01", "2023-02-01", "2023-03-01"},
+		},
+		{
+			name:   "invalid dates",
+			filter: web.StatsFilter{StartDate: "yesterday", EndDate: "2023-10-01"},
+			counts: []web.StatsByDate{{Date: "2023-10-01"}},
+			want:   []string{"2023-10-01"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dates(fillBuckets(tt.counts, tt.filter)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fillBuckets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFillBucketsHours(t *testing.T) {
+	tests := []struct {
+		name  string
+		zone  string
+		date  string
+		hours int
+	}{
+		{name: "day", zone: "UTC", date: "2023-10-01", hours: 24},
+		{name: "daylight saving start", zone: "Europe/Berlin", date: "2023-03-26", hours: 23},
+		// The repeated hour shares the label of the first one
+		{name: "daylight saving end", zone: "Europe/Berlin", date: "2023-10-29", hours: 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := web.StatsFilter{StartDate: tt.date, EndDate: tt.date, Granularity: GranularityHour, Location: location(t, tt.zone)}
+			got := dates(fillBuckets(nil, filter))
+			if len(got) != tt.hours {
+				t.Fatalf("fillBuckets() = %d buckets, want %d", len(got), tt.hours)
+			}
+			if got[0] != tt.date+"T00:00" {
+				t.Errorf("fillBuckets() first bucket = %q, want %q", got[0], tt.date+"T00:00")
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dimensions maps the dimensions stats can be grouped by to their column.
+var dimensions = map[string]string{
+	"os":       "os",
+	"browser":  "browser",
+	"device":   "device",
+	"country":  "country",
+	"region":   "region",
+	"referrer": "referrer",
+	"language": "language",
+	"target":   "target",
+}
+
+// defaultGroupBy are the dimensions stats are grouped by when none is requested.
+var defaultGroupBy = []string{"os", "browser"}
+
+// ParseGroupBy validates a comma separated list of dimensions, by default the OS and the browser.
+func ParseGroupBy(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return defaultGroupBy, nil
+	}
+	var groupBy []string
+	seen := make(map[string]bool)
+	for _, dimension := range strings.Split(raw, ",") {
+		dimension = strings.ToLower(strings.TrimSpace(dimension))
+		if _, ok := dimensions[dimension]; !ok {
+			return nil, fmt.Errorf("unknown groupBy dimension `%s`", dimension)
+		}
+		if !seen[dimension] {
+			seen[dimension] = true
+			groupBy = append(groupBy, dimension)
+		}
+	}
+	return groupBy, nil
+}
+
+// groupColumns returns the dimension columns to select from the stats or rollups aliased s, and the same
+// dimensions to select and group by from the counts, both prefixed with a comma.
+func groupColumns(groupBy []string) (string, string) {
+	var selected, grouped strings.Builder
+	for _, dimension := range groupBy {
+		column, ok := dimensions[dimension]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&selected, ", COALESCE(s.%s, '') as %s", column, dimension)
+		fmt.Fprintf(&grouped, ", %s", dimension)
+	}
+	return selected.String(), grouped.String()
+}
@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"sync"
+)
+
+// Types of the events pushed to the streams.
+const (
+	EventClick = "click"
+	EventView  = "view"
+)
+
+// subscriptionBuffer is the number of events a subscriber may lag behind before its events are dropped.
+const subscriptionBuffer = 64
+
+// Event is a visit to a link or a page, pushed as it is registered to the streams of its owner or, when it is shared,
+// of its workspace only.
+type Event struct {
+	Type        string       `json:"type"`
+	UserId      string       `json:"-"`
+	WorkspaceId *uuid.UUID   `json:"-"`
+	Stats       domain.Stats `json:"stats"`
+}
+
+// Hub is an in-process publisher of the events of each user and workspace to its subscribers. A nil Hub publishes
+// nothing, and its subscriptions never receive events.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[*Subscription]bool
+	closed      bool
+}
+
+// Subscription receives the events of a user or workspace until it is closed, or the hub is.
+type Subscription struct {
+	Events <-chan Event
+	events chan Event
+	hub    *Hub
+	topic  string
+}
+
+// NewHub creates a hub without subscribers.
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[string]map[*Subscription]bool)}
+}
+
+// Subscribe returns a subscription to the events of the user. Its channel is closed at once when the hub is closed.
+func (h *Hub) Subscribe(userId string) *Subscription {
+	return h.subscribe(userId)
+}
+
+// SubscribeWorkspace returns a subscription to the events of the links and pages of the workspace.
+func (h *Hub) SubscribeWorkspace(workspaceId uuid.UUID) *Subscription {
+	return h.subscribe(workspaceTopic(workspaceId))
+}
+
+// subscribe adds a subscriber to the events of the topic
+func (h *Hub) subscribe(topic string) *Subscription {
+	events := make(chan Event, subscriptionBuffer)
+	subscription := &Subscription{Events: events, events: events, hub: h, topic: topic}
+	if h == nil {
+		return subscription
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(events)
+		return subscription
+	}
+	if h.subscribers[topic] == nil {
+		h.subscribers[topic] = make(map[*Subscription]bool)
+	}
+	h.subscribers[topic][subscription] = true
+	return subscription
+}
+
+// Close stops the subscription and closes its channel.
+func (s *Subscription) Close() {
+	if s.hub == nil {
+		return
+	}
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if !s.hub.subscribers[s.topic][s] {
+		return
+	}
+	delete(s.hub.subscribers[s.topic], s)
+	if len(s.hub.subscribers[s.topic]) == 0 {
+		delete(s.hub.subscribers, s.topic)
+	}
+	close(s.events)
+}
+
+// Publish pushes the event to the subscribers of its workspace, or of its user when it isn't shared, so members
+// removed from the workspace stop receiving the visits of the links and pages they created there. Subscribers
+// lagging behind miss it.
+func (h *Hub) Publish(event Event) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if event.WorkspaceId != nil {
+		h.deliver(workspaceTopic(*event.WorkspaceId), event)
+		return
+	}
+	h.deliver(event.UserId, event)
+}
+
+// deliver pushes the event to the subscribers of the topic, the caller holds the lock
+func (h *Hub) deliver(topic string, event Event) {
+	for subscription := range h.subscribers[topic] {
+		select {
+		case subscription.events <- event:
+		default:
+		}
+	}
+}
+
+// workspaceTopic returns the topic of the events of a workspace, apart from the user IDs
+func workspaceTopic(workspaceId uuid.UUID) string {
+	return "workspace:" + workspaceId.String()
+}
+
+// Close closes every subscription, ending the streams so the server can shut down.
+func (h *Hub) Close() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for topic, subscriptions := range h.subscribers {
+		for subscription := range subscriptions {
+			close(subscription.events)
+		}
+		delete(h.subscribers, topic)
+	}
+}
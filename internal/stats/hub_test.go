@@ -0,0 +1,74 @@
+package stats
+
+import (
+	"github.com/google/uuid"
+	"testing"
+)
+
+// received returns the events delivered to the subscription so far.
+func received(s *Subscription) []Event {
+	var events []Event
+	for {
+		select {
+		case event := <-s.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestHubPublish(t *testing.T) {
+	workspaceId := uuid.New()
+	tests := []struct {
+		name            string
+		event           Event
+		userEvents      int
+		workspaceEvents int
+	}{
+		{name: "personal visit", event: Event{Type: EventClick, UserId: "creator"}, userEvents: 1},
+		{name: "workspace visit", event: Event{Type: EventClick, UserId: "creator", WorkspaceId: &workspaceId}, workspaceEvents: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub()
+			defer hub.Close()
+			// The creator of the link was removed from the workspace, only the other member streams the workspace
+			creator := hub.Subscribe("creator")
+			member := hub.Subscribe("member")
+			workspace := hub.SubscribeWorkspace(workspaceId)
+
+			hub.Publish(tt.event)
+
+			if got := len(received(creator)); got != tt.userEvents {
+				t.Errorf("creator received %d events, want %d", got, tt.userEvents)
+			}
+			if got := len(received(member)); got != 0 {
+				t.Errorf("member received %d events, want 0", got)
+			}
+			if got := len(received(workspace)); got != tt.workspaceEvents {
+				t.Errorf("workspace received %d events, want %d", got, tt.workspaceEvents)
+			}
+		})
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub()
+	subscription := hub.Subscribe("user")
+	hub.Close()
+	if _, ok := <-subscription.Events; ok {
+		t.Error("subscription still open after the hub is closed")
+	}
+	if _, ok := <-hub.Subscribe("user").Events; ok {
+		t.Error("subscription to a closed hub is open")
+	}
+	// Closing a subscription of a closed hub is a no-op
+	subscription.Close()
+
+	var nilHub *Hub
+	nilHub.Publish(Event{UserId: "user"})
+	if events := received(nilHub.Subscribe("user")); len(events) != 0 {
+		t.Errorf("nil hub delivered %d events", len(events))
+	}
+}
@@ -0,0 +1,36 @@
+package stats
+
+import (
+	"fmt"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// Migrate migrates the Stats model. The stats saved before bot visits were flagged are human visits, they are marked
+// as such before the flag becomes required, so the queries excluding bots keep counting them.
+func Migrate(db *gorm.DB) error {
+	if db.Migrator().HasColumn(&domain.Stats{}, "is_bot") {
+		if err := db.Exec("UPDATE stats SET is_bot = false WHERE is_bot IS NULL").Error; err != nil {
+			return fmt.Errorf("unable to flag the stats saved before bot visits: %v", err)
+		}
+		if err := db.Exec("ALTER TABLE stats ALTER COLUMN is_bot SET DEFAULT false, ALTER COLUMN is_bot SET NOT NULL").Error; err != nil {
+			return fmt.Errorf("unable to require the bot flag of the stats: %v", err)
+		}
+	}
+	return db.AutoMigrate(&domain.Stats{})
+}
+
+// MigrateRollups migrates the StatsRollup model and records the cutover the first time, before any stats is rolled up
+// as it is saved. The stats saved before the cutover are rolled up by cmd/rollups.
+func MigrateRollups(db *gorm.DB) error {
+	if err := db.AutoMigrate(&domain.StatsRollup{}, &domain.StatsRollupCutover{}); err != nil {
+		return err
+	}
+	cutover := domain.StatsRollupCutover{ID: 1, At: time.Now().UTC()}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&cutover).Error; err != nil {
+		return fmt.Errorf("unable to record the stats rollups cutover: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package stats
+
+import (
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/testdb"
+	"github.com/ronilsonalves/5lnk/internal/utils"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"testing"
+	"time"
+)
+
+func TestMigrateFlagsLegacyStats(t *testing.T) {
+	db := testdb.Open(t, &domain.Link{})
+	link := domain.Link{Original: "https://example.com", Shortened: "legacy", UserId: "user"}
+	if err := db.Create(&link).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// The stats table as created before bot visits were flagged, then with the nullable flag of the first release
+	if err := db.Exec("CREATE TABLE stats (id uuid PRIMARY KEY, link_refer text, page_refer text, timestamp timestamptz, os text, browser text)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO stats (id, link_refer, timestamp) VALUES (?, ?, ?)", uuid.New(), link.ID.String(), time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("ALTER TABLE stats ADD COLUMN is_bot boolean").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO stats (id, link_refer, timestamp, is_bot) VALUES (?, ?, ?, NULL), (?, ?, ?, true)",
+		uuid.New(), link.ID.String(), time.Now(), uuid.New(), link.ID.String(), time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+
+	r := NewStatsRepository(db)
+	for _, tt := range []struct {
+		includeBots bool
+		want        int64
+	}{{includeBots: false, want: 2}, {includeBots: true, want: 3}} {
+		total, err := r.CountLinkClicksByUser("user", tt.includeBots)
+		if err != nil {
+			t.Fatalf("CountLinkClicksByUser() error = %v", err)
+		}
+		if total != tt.want {
+			t.Errorf("CountLinkClicksByUser(includeBots = %v) = %d, want %d", tt.includeBots, total, tt.want)
+		}
+		pagination := web.Pagination{PageNumber: 1, PageSize: 10}
+		var stats []domain.Stats
+		db.Scopes(utils.PaginateStatsByLinkRef(link.ID.String(), tt.includeBots, &stats, &pagination, db)).Find(&stats)
+		if pagination.Items != tt.want {
+			t.Errorf("PaginateStatsByLinkRef(includeBots = %v) counted %d stats, want %d", tt.includeBots, pagination.Items, tt.want)
+		}
+	}
+
+	// Stats saved without the flag are human visits
+	if err := db.Exec("INSERT INTO stats (id, link_refer, timestamp) VALUES (?, ?, ?)", uuid.New(), link.ID.String(), time.Now()).Error; err != nil {
+		t.Fatalf("unable to save stats without the bot flag: %v", err)
+	}
+	if total, _ := r.CountLinkClicksByUser("user", false); total != 3 {
+		t.Errorf("CountLinkClicksByUser() = %d after saving stats without the flag, want 3", total)
+	}
+}
@@ -0,0 +1,245 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQueueFull is returned when a visit is dropped because the queue is full.
+var ErrQueueFull = errors.New("the stats queue is full")
+
+// ErrQueueClosed is returned when a visit is registered after the queue was closed.
+var ErrQueueClosed = errors.New("the stats queue is closed")
+
+// maxFlushAttempts is the number of times a batch is saved before its visits are given up.
+const maxFlushAttempts = 3
+
+// flushBackoff is how long saving a batch waits after its first failure, growing with each attempt.
+var flushBackoff = time.Second
+
+// Queue stores the visits in the background, in batches of up to batchSize visits or every interval. When it is
+// full, visits are dropped or, with the block policy, the request waits up to maxWait for room in the queue.
+type Queue struct {
+	r         Repository
+	events    chan domain.Stats
+	batchSize int
+	interval  time.Duration
+	block     bool
+	maxWait   time.Duration
+	dropped   atomic.Int64
+	mu        sync.RWMutex
+	closed    bool
+	closing   chan struct{}
+	pending   sync.WaitGroup
+	done      chan struct{}
+}
+
+// NewQueue creates a queue holding up to size visits and starts saving them.
+func NewQueue(r Repository, size int, batchSize int, interval time.Duration, block bool, maxWait time.Duration) *Queue {
+	q := &Queue{
+		r:         r,
+		events:    make(chan domain.Stats, size),
+		batchSize: batchSize,
+		interval:  interval,
+		block:     block,
+		maxWait:   maxWait,
+		closing:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// NewQueueFromEnv creates a queue configured by STATS_QUEUE_SIZE, STATS_BATCH_SIZE, STATS_FLUSH_INTERVAL,
+// STATS_QUEUE_POLICY (drop or block) and STATS_QUEUE_MAX_WAIT, how long the block policy waits before dropping.
+func NewQueueFromEnv(r Repository) (*Queue, error) {
+	size, err := positiveEnv("STATS_QUEUE_SIZE", 10000)
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := positiveEnv("STATS_BATCH_SIZE", 500)
+	if err != nil {
+		return nil, err
+	}
+	interval, err := durationEnv("STATS_FLUSH_INTERVAL", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	maxWait, err := durationEnv("STATS_QUEUE_MAX_WAIT", time.Millisecond*250)
+	if err != nil {
+		return nil, err
+	}
+	var block bool
+	switch policy := os.Getenv("STATS_QUEUE_POLICY"); policy {
+	case "", "drop":
+		block = false
+	case "block":
+		block = true
+	default:
+		return nil, fmt.Errorf("unknown stats queue policy `%s`, use drop or block", policy)
+	}
+	return NewQueue(r, size, batchSize, interval, block, maxWait), nil
+}
+
+// positiveEnv reads a positive number from the environment variable, or returns the default when it is not set.
+func positiveEnv(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid %s value `%s`", key, value)
+	}
+	return n, nil
+}
+
+// durationEnv reads a positive duration from the environment variable, or returns the default when it is not set.
+func durationEnv(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s value `%s`", key, value)
+	}
+	return d, nil
+}
+
+// Enqueue adds a visit to the queue. With the block policy it waits for room up to maxWait, without holding the
+// queue lock so it can be closed meanwhile.
+func (q *Queue) Enqueue(stats domain.Stats) error {
+	q.mu.RLock()
+	if q.closed {
+		q.mu.RUnlock()
+		return ErrQueueClosed
+	}
+	q.pending.Add(1)
+	q.mu.RUnlock()
+	defer q.pending.Done()
+
+	if q.block {
+		timer := time.NewTimer(q.maxWait)
+		defer timer.Stop()
+		select {
+		case q.events <- stats:
+			return nil
+		case <-q.closing:
+			return ErrQueueClosed
+		case <-timer.C:
+			q.dropped.Add(1)
+			return ErrQueueFull
+		}
+	}
+	select {
+	case q.events <- stats:
+		return nil
+	default:
+		q.dropped.Add(1)
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting visits and waits until the queued ones are saved or the context is done.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.closing)
+	}
+	q.mu.Unlock()
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("stats queue not flushed, %d visits pending: %v", len(q.events), ctx.Err())
+	}
+}
+
+// run saves the visits in batches. A batch failing to save is kept, and tried again with the visits queued
+// meanwhile once its backoff is over. A full batch stops consuming the queue while backing off, so the visits wait
+// in the queue, where the drop or block policy applies once it is full.
+func (q *Queue) run() {
+	defer close(q.done)
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+	batch := make([]domain.Stats, 0, q.batchSize)
+	var attempts int
+	var retryAt time.Time
+	save := func() {
+		if time.Now().Before(retryAt) {
+			return
+		}
+		if err := q.flush(batch); err != nil {
+			if attempts++; attempts < maxFlushAttempts {
+				retryAt = time.Now().Add(time.Duration(attempts) * flushBackoff)
+				return
+			}
+			log.Printf("ERROR: unable to save %d visits due to %v", len(batch), err.Error())
+		}
+		batch, attempts, retryAt = batch[:0], 0, time.Time{}
+	}
+	for {
+		events := q.events
+		if len(batch) >= q.batchSize {
+			events = nil
+		}
+		select {
+		case stats := <-events:
+			batch = append(batch, stats)
+			if len(batch) >= q.batchSize {
+				save()
+			}
+		case <-ticker.C:
+			save()
+		case <-q.closing:
+			// No visit is queued once the pending ones are in
+			q.pending.Wait()
+			for {
+				for len(batch) < q.batchSize && len(q.events) > 0 {
+					batch = append(batch, <-q.events)
+				}
+				q.flushAll(batch, attempts)
+				if len(q.events) == 0 {
+					return
+				}
+				batch, attempts = batch[:0], 0
+			}
+		}
+	}
+}
+
+// flush saves a batch, reporting the visits dropped since the last one.
+func (q *Queue) flush(batch []domain.Stats) error {
+	if dropped := q.dropped.Swap(0); dropped > 0 {
+		log.Printf("WARNING: %d visits dropped, the stats queue is full", dropped)
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	return q.r.SaveBatch(batch)
+}
+
+// flushAll saves a batch left when the queue is closed, trying again while the database fails.
+func (q *Queue) flushAll(batch []domain.Stats, attempts int) {
+	for attempt := attempts + 1; ; attempt++ {
+		err := q.flush(batch)
+		if err == nil {
+			return
+		}
+		if attempt >= maxFlushAttempts {
+			log.Printf("ERROR: unable to save %d visits due to %v", len(batch), err.Error())
+			return
+		}
+		time.Sleep(time.Duration(attempt) * flushBackoff)
+	}
+}
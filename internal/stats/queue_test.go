@@ -0,0 +1,222 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchRepository records the saved batches, failing the first failures saves.
+type batchRepository struct {
+	Repository
+	mu       sync.Mutex
+	failures int
+	calls    int
+	batches  [][]domain.Stats
+}
+
+func (r *batchRepository) SaveBatch(batch []domain.Stats) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("database unavailable")
+	}
+	r.batches = append(r.batches, append([]domain.Stats(nil), batch...))
+	return nil
+}
+
+// sizes returns the number of calls to SaveBatch and the sizes of the saved batches.
+func (r *batchRepository) sizes() (int, []int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sizes []int
+	for _, batch := range r.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return r.calls, sizes
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second * 5)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+}
+
+func equalSizes(got []int, want []int) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func newTestQueue(t *testing.T, r Repository, size int, batchSize int, interval time.Duration) *Queue {
+	q := NewQueue(r, size, batchSize, interval, false, 0)
+	t.Cleanup(func() { _ = q.Close(context.Background()) })
+	return q
+}
+
+func TestEnqueueFull(t *testing.T) {
+	tests := []struct {
+		name    string
+		block   bool
+		maxWait time.Duration
+	}{
+		{name: "drop", block: false},
+		{name: "block", block: true, maxWait: time.Millisecond * 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Nothing consumes the queue
+			q := &Queue{events: make(chan domain.Stats, 1), block: tt.block, maxWait: tt.maxWait, closing: make(chan struct{})}
+			if err := q.Enqueue(domain.Stats{}); err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+			start := time.Now()
+			if err := q.Enqueue(domain.Stats{}); !errors.Is(err, ErrQueueFull) {
+				t.Fatalf("Enqueue() error = %v, want %v", err, ErrQueueFull)
+			}
+			if elapsed := time.Since(start); elapsed < tt.maxWait {
+				t.Errorf("Enqueue() gave up after %v, want %v", elapsed, tt.maxWait)
+			}
+			if dropped := q.dropped.Load(); dropped != 1 {
+				t.Errorf("dropped = %d, want 1", dropped)
+			}
+		})
+	}
+}
+
+func TestEnqueueBlock(t *testing.T) {
+	q := &Queue{events: make(chan domain.Stats, 1), block: true, maxWait: time.Second * 5, closing: make(chan struct{})}
+	if err := q.Enqueue(domain.Stats{}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		<-q.events
+	}()
+	if err := q.Enqueue(domain.Stats{}); err != nil {
+		t.Errorf("Enqueue() error = %v once the queue has room", err)
+	}
+
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		close(q.closing)
+	}()
+	if err := q.Enqueue(domain.Stats{}); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Enqueue() error = %v once the queue is closed, want %v", err, ErrQueueClosed)
+	}
+}
+
+func TestQueueFlush(t *testing.T) {
+	tests := []struct {
+		name      string
+		batchSize int
+		interval  time.Duration
+		visits    int
+		want      []int
+	}{
+		{name: "batch size", batchSize: 2, interval: time.Hour, visits: 5, want: []int{2, 2}},
+		{name: "interval", batchSize: 100, interval: time.Millisecond * 20, visits: 3, want: []int{3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &batchRepository{}
+			q := newTestQueue(t, r, 10, tt.batchSize, tt.interval)
+			for i := 0; i < tt.visits; i++ {
+				if err := q.Enqueue(domain.Stats{}); err != nil {
+					t.Fatalf("Enqueue() error = %v", err)
+				}
+			}
+			eventually(t, func() bool {
+				_, sizes := r.sizes()
+				return equalSizes(sizes, tt.want)
+			})
+		})
+	}
+}
+
+func TestQueueRetry(t *testing.T) {
+	backoff := flushBackoff
+	flushBackoff = time.Millisecond * 10
+	t.Cleanup(func() { flushBackoff = backoff })
+
+	r := &batchRepository{failures: maxFlushAttempts - 1}
+	q := newTestQueue(t, r, 10, 1, time.Millisecond*5)
+	if err := q.Enqueue(domain.Stats{}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	eventually(t, func() bool {
+		calls, sizes := r.sizes()
+		return calls == maxFlushAttempts && equalSizes(sizes, []int{1})
+	})
+}
+
+func TestQueueBackoff(t *testing.T) {
+	backoff := flushBackoff
+	flushBackoff = time.Hour
+	t.Cleanup(func() { flushBackoff = backoff })
+
+	r := &batchRepository{failures: 1}
+	q := NewQueue(r, 2, 2, time.Millisecond*5, false, 0)
+	for i := 0; i < 2; i++ {
+		if err := q.Enqueue(domain.Stats{}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	eventually(t, func() bool {
+		calls, _ := r.sizes()
+		return calls == 1
+	})
+
+	// The failed batch is full, the next visits wait in the queue until it is full
+	for i := 0; i < 2; i++ {
+		if err := q.Enqueue(domain.Stats{}); err != nil {
+			t.Fatalf("Enqueue() error = %v while backing off", err)
+		}
+	}
+	if err := q.Enqueue(domain.Stats{}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Enqueue() error = %v while backing off with a full queue, want %v", err, ErrQueueFull)
+	}
+
+	// Closing saves the failed batch and the queued visits, in batches of up to the batch size
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, sizes := r.sizes(); !equalSizes(sizes, []int{2, 2}) {
+		t.Errorf("saved batches of %v visits, want [2 2]", sizes)
+	}
+}
+
+func TestQueueClose(t *testing.T) {
+	r := &batchRepository{}
+	q := NewQueue(r, 10, 100, time.Hour, false, 0)
+	for i := 0; i < 3; i++ {
+		if err := q.Enqueue(domain.Stats{}); err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+	}
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, sizes := r.sizes(); !equalSizes(sizes, []int{3}) {
+		t.Errorf("saved batches of %v visits on close, want [3]", sizes)
+	}
+	if err := q.Enqueue(domain.Stats{}); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Enqueue() error = %v after Close(), want %v", err, ErrQueueClosed)
+	}
+}
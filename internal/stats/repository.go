@@ -1,28 +1,43 @@
 package stats
 
 import (
+	"database/sql"
+	"errors"
 	"github.com/google/uuid"
 	"github.com/ronilsonalves/5lnk/internal/domain"
 	"github.com/ronilsonalves/5lnk/internal/utils"
+	"github.com/ronilsonalves/5lnk/pkg/hll"
 	"github.com/ronilsonalves/5lnk/pkg/web"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"log"
+	"sort"
+	"time"
 )
 
 type Repository interface {
 	CountLinksByUser(userId string) (int64, error)
-	CountLinkClicksByUser(userId string) (int64, error)
+	CountLinkClicksByUser(userId string, includeBots bool) (int64, error)
 	CountPagesByUser(userId string) (int64, error)
-	CountPageViewsByUser(userId string) (int64, error)
-	CreateLinkStats(stats domain.Stats) error
-	CreatePageStats(stats domain.Stats) error
-	FindStatsByUser(pagination web.Pagination, userId string) (web.Pagination, error)
-	FindLinkStats(pagination web.Pagination, linkId string) (web.Pagination, error)
-	FindPageStats(pagination web.Pagination, pageId string) (web.Pagination, error)
-	FindLinkStatsByUserAndDate(userId, startDate, endDate string) (*[]web.StatsByDate, error)
-	FindPageStatsByUserAndDate(userId, startDate, endDate string) (*[]web.StatsByDate, error)
-	CountPageStatsByDate(pageId uuid.UUID, startDate, endDate string) (*[]web.StatsByDate, error)
-	CountLinkStatsByDate(linkId uuid.UUID, startDate, endDate string) (*[]web.StatsByDate, error)
+	CountPageViewsByUser(userId string, includeBots bool) (int64, error)
+	CountLinksByWorkspace(workspaceId uuid.UUID) (int64, error)
+	CountLinkClicksByWorkspace(workspaceId uuid.UUID, includeBots bool) (int64, error)
+	CountPagesByWorkspace(workspaceId uuid.UUID) (int64, error)
+	CountPageViewsByWorkspace(workspaceId uuid.UUID, includeBots bool) (int64, error)
+	SaveBatch(batch []domain.Stats) error
+	BackfillRollups() error
+	FindStatsByUser(pagination web.Pagination, userId string, includeBots bool) (web.Pagination, error)
+	StreamStatsByUser(userId string, fn func(stats domain.Stats) error) error
+	FindLinkStats(pagination web.Pagination, linkId string, includeBots bool) (web.Pagination, error)
+	FindPageStats(pagination web.Pagination, pageId string, includeBots bool) (web.Pagination, error)
+	FindLinkStatsByUserAndDate(userId string, filter web.StatsFilter) (*[]web.StatsByDate, error)
+	FindPageStatsByUserAndDate(userId string, filter web.StatsFilter) (*[]web.StatsByDate, error)
+	CountPageStatsByDate(pageId uuid.UUID, filter web.StatsFilter) (*[]web.StatsByDate, error)
+	CountLinkStatsByDate(linkId uuid.UUID, filter web.StatsFilter) (*[]web.StatsByDate, error)
+	CountLinkStatsByVariant(linkId uuid.UUID, filter web.StatsFilter) (*[]web.VariantStats, error)
+	CountLinkStatsByDay(linkId uuid.UUID, filter web.StatsFilter) ([]web.VisitorsByDate, error)
+	CountPageStatsByDay(pageId uuid.UUID, filter web.StatsFilter) ([]web.VisitorsByDate, error)
+	FindVisitorSketches(kind string, ref string, filter web.StatsFilter) ([]domain.VisitorSketch, error)
 	Delete(statsId string) error
 }
 
@@ -35,54 +50,119 @@ func NewStatsRepository(db *gorm.DB) Repository {
 	return &statsRepository{db: db}
 }
 
-// CreateLinkStats registers in database a new stats for a link
-func (r *statsRepository) CreateLinkStats(stats domain.Stats) error {
-	if err := r.db.Create(&stats).Error; err != nil {
-		log.Printf("ERROR: unable to register stats for link: %v", err.Error())
-		return err
+// SaveBatch registers in database a batch of stats, adding the clicks and views of the visitors to their links
+// and pages counters, the stats to the hourly rollups and the visitors to the sketches of the day
+func (r *statsRepository) SaveBatch(batch []domain.Stats) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(batch, len(batch)).Error; err != nil {
+			return err
+		}
+		clicks, views := make(map[string]int), make(map[string]int)
+		for _, stats := range batch {
+			if stats.IsBot {
+				continue
+			}
+			if stats.LinkRefer != "" {
+				clicks[stats.LinkRefer]++
+			}
+			if stats.PageRefer != "" {
+				views[stats.PageRefer]++
+			}
+		}
+		for id, n := range clicks {
+			if err := tx.Exec("UPDATE links SET clicks = clicks + ? WHERE id::text = ?", n, id).Error; err != nil {
+				return err
+			}
+		}
+		for id, n := range views {
+			if err := tx.Exec("UPDATE links_pages SET views = views + ? WHERE id::text = ?", n, id).Error; err != nil {
+				return err
+			}
+		}
+		ids := make([]uuid.UUID, len(batch))
+		for i, stats := range batch {
+			ids[i] = stats.ID
+		}
+		if err := rollupStats(tx, "id IN ?", ids); err != nil {
+			return err
+		}
+		return addVisitors(tx, batch)
+	})
+	if err != nil {
+		log.Printf("ERROR: unable to register a batch of %d stats: %v", len(batch), err.Error())
 	}
-	return nil
+	return err
 }
 
-// CreatePageStats registers in database a new stats for a page
-func (r *statsRepository) CreatePageStats(stats domain.Stats) error {
-	if err := r.db.Create(&stats).Error; err != nil {
-		log.Printf("ERROR: unable to register stats for page: %v", err.Error())
+// BackfillRollups builds the hourly rollups of the stats saved before the cutover, a day at a time. Each hour is
+// recorded along with its rollups and skipped afterwards, so it can be stopped and run again.
+func (r *statsRepository) BackfillRollups() error {
+	var cutover domain.StatsRollupCutover
+	if err := r.db.Take(&cutover).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNoRollupCutover
+		}
+		return err
+	}
+	end := cutover.At.UTC()
+	var first sql.NullTime
+	if err := r.db.Model(&domain.Stats{}).Select("MIN(timestamp)").Where("timestamp < ?", end).Row().Scan(&first); err != nil {
 		return err
 	}
+	if !first.Valid {
+		return nil
+	}
+	for day := first.Time.UTC().Truncate(24 * time.Hour); day.Before(end); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		if next.After(end) {
+			next = end
+		}
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			if err := rollupStats(tx, "timestamp >= ? AND timestamp < ? AND NOT EXISTS (SELECT 1 FROM stats_rollup_backfills b "+
+				"WHERE b.bucket = "+rollupHour+")", day, next); err != nil {
+				return err
+			}
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(hoursOf(day, next)).Error
+		})
+		if err != nil {
+			log.Printf("ERROR: unable to build the stats rollups of %s: %v", day.Format(time.DateOnly), err.Error())
+			return err
+		}
+		log.Printf("INFO: stats rollups of %s built", day.Format(time.DateOnly))
+	}
 	return nil
 }
 
 // FindLinkStatsByUserAndDate returns all link stats for a user and date
-func (r *statsRepository) FindLinkStatsByUserAndDate(userId, startDate, endDate string) (*[]web.StatsByDate, error) {
+func (r *statsRepository) FindLinkStatsByUserAndDate(userId string, filter web.StatsFilter) (*[]web.StatsByDate, error) {
 	var userLinkStatsByDate []web.StatsByDate
-	if err := r.db.Raw("SELECT DATE(timestamp) as date, os, browser, COUNT(stats.id) as total from stats INNER JOIN links ON links.id::text = link_refer WHERE links.user_id = ? AND DATE(timestamp) BETWEEN ? AND ? GROUP BY DATE(timestamp), os, browser", userId, startDate, endDate).Scan(&userLinkStatsByDate).Error; err != nil {
+	if err := countByDate(r.db,
+		"INNER JOIN links ON links.id::text = s.ref WHERE s.kind = '"+rollupLink+"' AND links.user_id = ? AND links.workspace_id IS NULL",
+		"INNER JOIN links ON links.id::text = s.link_refer WHERE links.user_id = ? AND links.workspace_id IS NULL",
+		[]interface{}{userId}, filter, &userLinkStatsByDate); err != nil {
 		log.Printf("ERROR: unable to find the user link stats by date due to %v", err.Error())
 		return &[]web.StatsByDate{}, err
 	}
-	for _, s := range userLinkStatsByDate {
-		s.Date = s.Date[0:10]
-	}
 	return &userLinkStatsByDate, nil
 }
 
 // FindPageStatsByUserAndDate returns all page stats for a user and date
-func (r *statsRepository) FindPageStatsByUserAndDate(userId, startDate, endDate string) (*[]web.StatsByDate, error) {
+func (r *statsRepository) FindPageStatsByUserAndDate(userId string, filter web.StatsFilter) (*[]web.StatsByDate, error) {
 	var userPageStatsByDate []web.StatsByDate
-	if err := r.db.Raw("SELECT DATE(timestamp) as date, os, browser, COUNT(stats.id) as total from stats INNER JOIN links_pages as l ON l.id::text = page_refer WHERE l.user_id = ? AND DATE(timestamp) BETWEEN ? AND ? GROUP BY DATE(timestamp), os, browser", userId, startDate, endDate).Scan(&userPageStatsByDate).Error; err != nil {
+	if err := countByDate(r.db,
+		"INNER JOIN links_pages as l ON l.id::text = s.ref WHERE s.kind = '"+rollupPage+"' AND l.user_id = ? AND l.workspace_id IS NULL",
+		"INNER JOIN links_pages as l ON l.id::text = s.page_refer WHERE l.user_id = ? AND l.workspace_id IS NULL",
+		[]interface{}{userId}, filter, &userPageStatsByDate); err != nil {
 		log.Printf("ERROR: unable to find the user page stats by date due to %v", err.Error())
 		return &[]web.StatsByDate{}, err
 	}
-	for _, s := range userPageStatsByDate {
-		s.Date = s.Date[0:10]
-	}
 	return &userPageStatsByDate, nil
 }
 
 // FindStatsByUser returns all stats for a user
-func (r *statsRepository) FindStatsByUser(pagination web.Pagination, userId string) (web.Pagination, error) {
+func (r *statsRepository) FindStatsByUser(pagination web.Pagination, userId string, includeBots bool) (web.Pagination, error) {
 	var stats []domain.Stats
-	if err := r.db.Scopes(utils.PaginateStatsByUserID(userId, stats, &pagination, r.db)).Find(&stats).Error; err != nil {
+	if err := r.db.Scopes(utils.PaginateStatsByUserID(userId, includeBots, stats, &pagination, r.db)).Find(&stats).Error; err != nil {
 		log.Printf("ERROR: unable to list stats by user: %v", err.Error())
 		return web.Pagination{}, err
 	}
@@ -90,10 +170,30 @@ func (r *statsRepository) FindStatsByUser(pagination web.Pagination, userId stri
 	return pagination, nil
 }
 
+// StreamStatsByUser calls fn for every stats of the user links and pages, reading them row by row
+func (r *statsRepository) StreamStatsByUser(userId string, fn func(stats domain.Stats) error) error {
+	rows, err := r.db.Raw("SELECT s.* FROM stats s INNER JOIN links l ON s.link_refer = l.id::text WHERE l.user_id = ? AND l.workspace_id IS NULL UNION ALL SELECT s2.* FROM stats s2 INNER JOIN links_pages p ON s2.page_refer = p.id::text WHERE p.user_id = ? AND p.workspace_id IS NULL ORDER BY timestamp", userId, userId).Rows()
+	if err != nil {
+		log.Printf("ERROR: unable to stream stats by user: %v", err.Error())
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var stats domain.Stats
+		if err := r.db.ScanRows(rows, &stats); err != nil {
+			return err
+		}
+		if err := fn(stats); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 // FindLinkStats returns all stats for a link
-func (r *statsRepository) FindLinkStats(pagination web.Pagination, linkId string) (web.Pagination, error) {
+func (r *statsRepository) FindLinkStats(pagination web.Pagination, linkId string, includeBots bool) (web.Pagination, error) {
 	var stats []domain.Stats
-	if err := r.db.Scopes(utils.PaginateStatsByLinkRef(linkId, stats, &pagination, r.db)).Find(&stats).Error; err != nil {
+	if err := r.db.Scopes(utils.PaginateStatsByLinkRef(linkId, includeBots, stats, &pagination, r.db)).Find(&stats).Error; err != nil {
 		log.Printf("ERROR: unable to find stats for link due to %v", err.Error())
 		return web.Pagination{}, err
 	}
@@ -102,10 +202,10 @@ func (r *statsRepository) FindLinkStats(pagination web.Pagination, linkId string
 }
 
 // FindPageStats returns all stats for a page
-func (r *statsRepository) FindPageStats(pagination web.Pagination, pageId string) (web.Pagination, error) {
+func (r *statsRepository) FindPageStats(pagination web.Pagination, pageId string, includeBots bool) (web.Pagination, error) {
 	var stats []domain.Stats
 
-	if err := r.db.Scopes(utils.PaginateStatsByPageRef(pageId, stats, &pagination, r.db)).Find(&stats).Error; err != nil {
+	if err := r.db.Scopes(utils.PaginateStatsByPageRef(pageId, includeBots, stats, &pagination, r.db)).Find(&stats).Error; err != nil {
 		log.Printf("ERROR: unable to find stats for page due to %v", err.Error())
 		return web.Pagination{}, err
 	}
@@ -115,76 +215,300 @@ func (r *statsRepository) FindPageStats(pagination web.Pagination, pageId string
 }
 
 // CountPageStatsByDate returns the number of views by date
-func (r *statsRepository) CountPageStatsByDate(pageId uuid.UUID, startDate, endDate string) (*[]web.StatsByDate, error) {
+func (r *statsRepository) CountPageStatsByDate(pageId uuid.UUID, filter web.StatsFilter) (*[]web.StatsByDate, error) {
 	var statsByDate []web.StatsByDate
-	if err := r.db.Raw("SELECT DATE(timestamp) as date, os, browser, COUNT(id) as total FROM stats WHERE page_refer = ? AND DATE(timestamp) BETWEEN ? AND ? GROUP BY DATE(timestamp), os, browser", pageId, startDate, endDate).Scan(&statsByDate).Error; err != nil {
+	if err := countByDate(r.db,
+		"WHERE s.kind = '"+rollupPage+"' AND s.ref = ?",
+		"WHERE s.page_refer = ?",
+		[]interface{}{pageId.String()}, filter, &statsByDate); err != nil {
 		log.Printf("ERROR: unable to count the number of views by date: %v", err.Error())
 		return &[]web.StatsByDate{}, err
 	}
-	for _, s := range statsByDate {
-		s.Date = s.Date[0:10]
-	}
 	return &statsByDate, nil
 }
 
 // CountLinkStatsByDate returns the number of clicks by date
-func (r *statsRepository) CountLinkStatsByDate(linkId uuid.UUID, startDate, endDate string) (*[]web.StatsByDate, error) {
+func (r *statsRepository) CountLinkStatsByDate(linkId uuid.UUID, filter web.StatsFilter) (*[]web.StatsByDate, error) {
 	var statsByDate []web.StatsByDate
-	if err := r.db.Raw("SELECT DATE(timestamp) as date, os, browser, COUNT(id) as total FROM stats WHERE link_refer = ? AND DATE(timestamp) BETWEEN ? AND ? GROUP BY DATE(timestamp), os, browser", linkId, startDate, endDate).Scan(&statsByDate).Error; err != nil {
+	if err := countByDate(r.db,
+		"WHERE s.kind = '"+rollupLink+"' AND s.ref = ?",
+		"WHERE s.link_refer = ?",
+		[]interface{}{linkId.String()}, filter, &statsByDate); err != nil {
 		log.Printf("ERROR: unable to count the number of clicks by date: %v", err.Error())
 		return &[]web.StatsByDate{}, err
 	}
-	for _, s := range statsByDate {
-		s.Date = s.Date[0:10]
-	}
 	return &statsByDate, nil
 }
 
+// CountLinkStatsByVariant returns the number of clicks of each variant of a link
+func (r *statsRepository) CountLinkStatsByVariant(linkId uuid.UUID, filter web.StatsFilter) (*[]web.VariantStats, error) {
+	var statsByVariant []web.VariantStats
+	if err := r.db.Raw("SELECT v.id as variant, v.label, v.destination, v.weight, COUNT(s.id) as total FROM link_variants v LEFT JOIN stats s ON s.variant = v.id::text AND DATE(s.timestamp) BETWEEN ? AND ? AND (? OR NOT s.is_bot) WHERE v.link_refer = ? GROUP BY v.id, v.label, v.destination, v.weight ORDER BY v.weight DESC", filter.StartDate, filter.EndDate, filter.IncludeBots, linkId).Scan(&statsByVariant).Error; err != nil {
+		log.Printf("ERROR: unable to count the number of clicks by variant: %v", err.Error())
+		return &[]web.VariantStats{}, err
+	}
+	return &statsByVariant, nil
+}
+
+// CountLinkStatsByDay returns the number of clicks of each day in UTC, the days of the visitor sketches
+func (r *statsRepository) CountLinkStatsByDay(linkId uuid.UUID, filter web.StatsFilter) ([]web.VisitorsByDate, error) {
+	var days []web.VisitorsByDate
+	filter.GroupBy, filter.Granularity, filter.Location = nil, GranularityDay, time.UTC
+	if err := countByDate(r.db,
+		"WHERE s.kind = '"+rollupLink+"' AND s.ref = ?",
+		"WHERE s.link_refer = ?",
+		[]interface{}{linkId.String()}, filter, &days); err != nil {
+		log.Printf("ERROR: unable to count the number of clicks by day: %v", err.Error())
+		return nil, err
+	}
+	return days, nil
+}
+
+// CountPageStatsByDay returns the number of views of each day in UTC, the days of the visitor sketches
+func (r *statsRepository) CountPageStatsByDay(pageId uuid.UUID, filter web.StatsFilter) ([]web.VisitorsByDate, error) {
+	var days []web.VisitorsByDate
+	filter.GroupBy, filter.Granularity, filter.Location = nil, GranularityDay, time.UTC
+	if err := countByDate(r.db,
+		"WHERE s.kind = '"+rollupPage+"' AND s.ref = ?",
+		"WHERE s.page_refer = ?",
+		[]interface{}{pageId.String()}, filter, &days); err != nil {
+		log.Printf("ERROR: unable to count the number of views by day: %v", err.Error())
+		return nil, err
+	}
+	return days, nil
+}
+
+// sketchKey identifies the visitor sketch of a day
+type sketchKey struct {
+	kind string
+	ref  string
+	day  time.Time
+}
+
+// addVisitors inserts the visitors of the batch in the sketches of their links, pages and owners
+func addVisitors(tx *gorm.DB, batch []domain.Stats) error {
+	hashes := make(map[sketchKey][]uint64)
+	var linkIds, pageIds []string
+	for _, stats := range batch {
+		hash, ok := visitorKey(stats.VisitorHash)
+		if !ok || stats.IsBot {
+			continue
+		}
+		day := stats.Timestamp.UTC().Truncate(24 * time.Hour)
+		if stats.LinkRefer != "" {
+			key := sketchKey{kind: domain.SketchLink, ref: stats.LinkRefer, day: day}
+			if len(hashes[key]) == 0 {
+				linkIds = append(linkIds, stats.LinkRefer)
+			}
+			hashes[key] = append(hashes[key], hash)
+		}
+		if stats.PageRefer != "" {
+			key := sketchKey{kind: domain.SketchPage, ref: stats.PageRefer, day: day}
+			if len(hashes[key]) == 0 {
+				pageIds = append(pageIds, stats.PageRefer)
+			}
+			hashes[key] = append(hashes[key], hash)
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	linkOwners, err := findOwners(tx, "links", linkIds)
+	if err != nil {
+		return err
+	}
+	pageOwners, err := findOwners(tx, "links_pages", pageIds)
+	if err != nil {
+		return err
+	}
+	for key, keyHashes := range hashes {
+		owner, ownerKind := linkOwners[key.ref], domain.SketchUserLinks
+		if key.kind == domain.SketchPage {
+			owner, ownerKind = pageOwners[key.ref], domain.SketchUserPages
+		}
+		// visitors of the links and pages of a workspace are counted for the workspace instead of their creator
+		ref := owner.UserId
+		if owner.WorkspaceId != "" {
+			ref, ownerKind = owner.WorkspaceId, domain.SketchWorkspaceLinks
+			if key.kind == domain.SketchPage {
+				ownerKind = domain.SketchWorkspacePages
+			}
+		}
+		if ref != "" {
+			ownerKey := sketchKey{kind: ownerKind, ref: ref, day: key.day}
+			hashes[ownerKey] = append(hashes[ownerKey], keyHashes...)
+		}
+	}
+
+	// sketches are locked in the same order by every batch, so concurrent batches can't deadlock
+	keys := make([]sketchKey, 0, len(hashes))
+	for key := range hashes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		if keys[i].ref != keys[j].ref {
+			return keys[i].ref < keys[j].ref
+		}
+		return keys[i].day.Before(keys[j].day)
+	})
+	for _, key := range keys {
+		if err := addToSketch(tx, key, hashes[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sketchOwner is the user and, when shared, the workspace of a link or page
+type sketchOwner struct {
+	UserId      string
+	WorkspaceId string
+}
+
+// findOwners returns the owner of each of the links or pages
+func findOwners(tx *gorm.DB, table string, ids []string) (map[string]sketchOwner, error) {
+	owners := make(map[string]sketchOwner)
+	if len(ids) == 0 {
+		return owners, nil
+	}
+	var rows []struct {
+		ID          string
+		UserId      string
+		WorkspaceId string
+	}
+	if err := tx.Table(table).Select("id::text as id, user_id, COALESCE(workspace_id::text, '') as workspace_id").Where("id::text IN ?", ids).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		owners[row.ID] = sketchOwner{UserId: row.UserId, WorkspaceId: row.WorkspaceId}
+	}
+	return owners, nil
+}
+
+// addToSketch inserts the hashes in a sketch, locking its row so concurrent batches aren't lost
+func addToSketch(tx *gorm.DB, key sketchKey, hashes []uint64) error {
+	row := domain.VisitorSketch{Kind: key.kind, Ref: key.ref, Day: key.day}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+		log.Printf("ERROR: unable to create the visitor sketch: %v", err.Error())
+		return err
+	}
+	var stored domain.VisitorSketch
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("kind = ? AND ref = ? AND day = ?", key.kind, key.ref, key.day).Take(&stored).Error; err != nil {
+		return err
+	}
+	sketch, err := hll.FromBytes(stored.Registers)
+	if err != nil {
+		return err
+	}
+	var changed bool
+	for _, hash := range hashes {
+		if sketch.Insert(hash) {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	if err := tx.Model(&domain.VisitorSketch{}).Where("kind = ? AND ref = ? AND day = ?", key.kind, key.ref, key.day).Update("registers", sketch.Bytes()).Error; err != nil {
+		log.Printf("ERROR: unable to update the visitor sketch: %v", err.Error())
+		return err
+	}
+	return nil
+}
+
+// FindVisitorSketches returns the daily visitor sketches of a link, a page, a user or a workspace in the date range
+func (r *statsRepository) FindVisitorSketches(kind string, ref string, filter web.StatsFilter) ([]domain.VisitorSketch, error) {
+	var sketches []domain.VisitorSketch
+	if err := r.db.Where("kind = ? AND ref = ? AND day BETWEEN ? AND ?", kind, ref, filter.StartDate, filter.EndDate).Order("day").Find(&sketches).Error; err != nil {
+		log.Printf("ERROR: unable to find the visitor sketches: %v", err.Error())
+		return nil, err
+	}
+	return sketches, nil
+}
+
 // Delete removes a stats from database
 func (r *statsRepository) Delete(statsId string) error {
 	return r.db.Where("id = ?", statsId).Delete(&domain.Stats{}).Error
 }
 
-// CountLinksByUser returns the number of links created by the user
+// CountLinksByUser returns the number of personal links created by the user
 func (r *statsRepository) CountLinksByUser(userId string) (int64, error) {
 	var count int64
-	if err := r.db.Model(&domain.Link{}).Where("user_id = ?", userId).Count(&count).Error; err != nil {
+	if err := r.db.Model(&domain.Link{}).Where("user_id = ? AND workspace_id IS NULL", userId).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-// CountLinkClicksByUser returns the number of clicks by the user
-func (r *statsRepository) CountLinkClicksByUser(userId string) (int64, error) {
-	var total int
-	if err := r.db.Raw("SELECT SUM(clicks) as total FROM links WHERE user_id = ?", userId).Scan(&total).Error; err != nil {
-		if err.Error() == `sql: Scan error on column index 0, name "total": converting NULL to int is unsupported` {
-			return 0, nil
-		}
+// CountLinkClicksByUser returns the number of clicks on the user personal links
+func (r *statsRepository) CountLinkClicksByUser(userId string, includeBots bool) (int64, error) {
+	var total int64
+	if err := r.db.Raw("SELECT COUNT(stats.id) FROM stats INNER JOIN links ON links.id::text = link_refer WHERE links.user_id = ? AND links.workspace_id IS NULL AND (? OR NOT stats.is_bot)", userId, includeBots).Scan(&total).Error; err != nil {
+		log.Printf("ERROR: unable to count the number of clicks by user: %v", err.Error())
 		return 0, err
 	}
-	return int64(total), nil
+	return total, nil
 }
 
-// CountPagesByUser returns the number of links pages created by the user
+// CountPagesByUser returns the number of personal links pages created by the user
 func (r *statsRepository) CountPagesByUser(userId string) (int64, error) {
 	var count int64
-	if err := r.db.Model(&domain.LinksPage{}).Where("user_id = ?", userId).Count(&count).Error; err != nil {
+	if err := r.db.Model(&domain.LinksPage{}).Where("user_id = ? AND workspace_id IS NULL", userId).Count(&count).Error; err != nil {
 		log.Printf("ERROR: unable to count the number of pages by user: %v", err.Error())
 		return 0, err
 	}
 	return count, nil
 }
 
-// CountPageViewsByUser returns the number of views by the user
-func (r *statsRepository) CountPageViewsByUser(userId string) (int64, error) {
-	var viewsCount int
-	if err := r.db.Raw("SELECT SUM(views) as views_count FROM links_pages WHERE user_id = ?", userId).Scan(&viewsCount).Error; err != nil {
-		if err.Error() == `sql: Scan error on column index 0, name "views_count": converting NULL to int is unsupported` {
-			return 0, nil
-		}
+// CountPageViewsByUser returns the number of views of the user personal pages
+func (r *statsRepository) CountPageViewsByUser(userId string, includeBots bool) (int64, error) {
+	var total int64
+	if err := r.db.Raw("SELECT COUNT(stats.id) FROM stats INNER JOIN links_pages as l ON l.id::text = page_refer WHERE l.user_id = ? AND l.workspace_id IS NULL AND (? OR NOT stats.is_bot)", userId, includeBots).Scan(&total).Error; err != nil {
 		log.Printf("ERROR: unable to count the number of views by user: %v", err.Error())
 		return 0, err
 	}
-	return int64(viewsCount), nil
+	return total, nil
+}
+
+// CountLinksByWorkspace returns the number of links of the workspace
+func (r *statsRepository) CountLinksByWorkspace(workspaceId uuid.UUID) (int64, error) {
+	var count int64
+	if err := r.db.Model(&domain.Link{}).Where("workspace_id = ?", workspaceId).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountLinkClicksByWorkspace returns the number of clicks on the workspace links
+func (r *statsRepository) CountLinkClicksByWorkspace(workspaceId uuid.UUID, includeBots bool) (int64, error) {
+	var total int64
+	if err := r.db.Raw("SELECT COUNT(stats.id) FROM stats INNER JOIN links ON links.id::text = link_refer WHERE links.workspace_id = ? AND (? OR NOT stats.is_bot)", workspaceId, includeBots).Scan(&total).Error; err != nil {
+		log.Printf("ERROR: unable to count the number of clicks by workspace: %v", err.Error())
+		return 0, err
+	}
+	return total, nil
+}
+
+// CountPagesByWorkspace returns the number of links pages of the workspace
+func (r *statsRepository) CountPagesByWorkspace(workspaceId uuid.UUID) (int64, error) {
+	var count int64
+	if err := r.db.Model(&domain.LinksPage{}).Where("workspace_id = ?", workspaceId).Count(&count).Error; err != nil {
+		log.Printf("ERROR: unable to count the number of pages by workspace: %v", err.Error())
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountPageViewsByWorkspace returns the number of views of the workspace pages
+func (r *statsRepository) CountPageViewsByWorkspace(workspaceId uuid.UUID, includeBots bool) (int64, error) {
+	var total int64
+	if err := r.db.Raw("SELECT COUNT(stats.id) FROM stats INNER JOIN links_pages as l ON l.id::text = page_refer WHERE l.workspace_id = ? AND (? OR NOT stats.is_bot)", workspaceId, includeBots).Scan(&total).Error; err != nil {
+		log.Printf("ERROR: unable to count the number of views by workspace: %v", err.Error())
+		return 0, err
+	}
+	return total, nil
 }
@@ -0,0 +1,68 @@
+package stats
+
+import (
+	"errors"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/internal/testdb"
+	"testing"
+	"time"
+)
+
+func TestBackfillRollups(t *testing.T) {
+	db := testdb.Open(t, &domain.Stats{}, &domain.StatsRollup{}, &domain.StatsRollupCutover{}, &domain.StatsRollupBackfill{})
+	r := NewStatsRepository(db)
+	if err := r.BackfillRollups(); !errors.Is(err, ErrNoRollupCutover) {
+		t.Fatalf("BackfillRollups() error = %v without a cutover, want %v", err, ErrNoRollupCutover)
+	}
+
+	cutover := time.Date(2026, 1, 2, 10, 30, 0, 0, time.UTC)
+	if err := db.Create(&domain.StatsRollupCutover{ID: 1, At: cutover}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, timestamp := range []time.Time{
+		time.Date(2026, 1, 1, 9, 10, 0, 0, time.UTC),
+		time.Date(2026, 1, 2, 10, 10, 0, 0, time.UTC),
+		time.Date(2026, 1, 2, 10, 40, 0, 0, time.UTC),
+	} {
+		if err := db.Create(&domain.Stats{LinkRefer: "link", Timestamp: timestamp}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The visit after the cutover was rolled up as it was saved, in the hour the backfill completes
+	live := domain.StatsRollup{Kind: rollupLink, Ref: "link", Bucket: time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC), Count: 1}
+	if err := db.Create(&live).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Running it again skips the hours already rolled up
+	for i := 0; i < 2; i++ {
+		if err := r.BackfillRollups(); err != nil {
+			t.Fatalf("BackfillRollups() error = %v", err)
+		}
+	}
+
+	var rollups []domain.StatsRollup
+	if err := db.Where("kind = ? AND ref = ? AND dimension = ''", rollupLink, "link").Order("bucket").Find(&rollups).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := map[time.Time]int64{
+		time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC):  1,
+		time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC): 2,
+	}
+	if len(rollups) != len(want) {
+		t.Fatalf("got %d rollups, want %d", len(rollups), len(want))
+	}
+	for _, rollup := range rollups {
+		if count := want[rollup.Bucket.UTC()]; rollup.Count != count {
+			t.Errorf("rollup of %v counts %d visits, want %d", rollup.Bucket, rollup.Count, count)
+		}
+	}
+
+	var hours int64
+	if err := db.Model(&domain.StatsRollupBackfill{}).Count(&hours).Error; err != nil {
+		t.Fatal(err)
+	}
+	if hours != 24+11 {
+		t.Errorf("recorded %d hours backfilled, want %d", hours, 24+11)
+	}
+}
@@ -0,0 +1,158 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"gorm.io/gorm"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNoRollupCutover is returned when backfilling the rollups before the server has started rolling up the stats.
+var ErrNoRollupCutover = errors.New("the stats rollups cutover isn't recorded, start the server before backfilling the rollups")
+
+// Kinds of stats rollups.
+const (
+	rollupLink = "link"
+	rollupPage = "page"
+)
+
+// rollupGroupings are the dimensions the rollups count the visits by, each on its own: none for all the visits,
+// every dimension, and the default grouping as the charts read it on every request.
+var rollupGroupings = func() []string {
+	groupings := []string{""}
+	for dimension := range dimensions {
+		groupings = append(groupings, dimension)
+	}
+	sort.Strings(groupings)
+	return append(groupings, groupingOf(defaultGroupBy))
+}()
+
+// valueSeparator joins the values of the dimensions of a grouping in a rollup.
+const valueSeparator = "chr(31)"
+
+// groupingOf returns the grouping of the dimensions, their names sorted and joined by a comma.
+func groupingOf(groupBy []string) string {
+	sorted := append([]string(nil), groupBy...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// rollupValues are the rows each stats is counted by, a grouping and its value, empty when the stats were saved
+// before the column existed.
+var rollupValues = func() string {
+	rows := make([]string, 0, len(rollupGroupings))
+	for _, grouping := range rollupGroupings {
+		values := []string{"''"}
+		if grouping != "" {
+			values = values[:0]
+			for _, dimension := range strings.Split(grouping, ",") {
+				values = append(values, "COALESCE("+dimensions[dimension]+", '')")
+			}
+		}
+		rows = append(rows, "('"+grouping+"', concat_ws("+valueSeparator+", "+strings.Join(values, ", ")+"))")
+	}
+	return "(VALUES " + strings.Join(rows, ", ") + ") AS d (dimension, value)"
+}()
+
+// rollupRefs are the columns of the stats referencing the link or page of each kind of rollup.
+var rollupRefs = map[string]string{rollupLink: "link_refer", rollupPage: "page_refer"}
+
+// rollupHour truncates the timestamp of the stats to its hour in UTC.
+const rollupHour = "date_trunc('hour', timestamp AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'"
+
+// rollupStats adds the stats matching the condition to the hourly rollups of their links and pages, once for each
+// grouping of dimensions.
+func rollupStats(tx *gorm.DB, condition string, args ...interface{}) error {
+	for kind, column := range rollupRefs {
+		query := "INSERT INTO stats_rollups (kind, ref, bucket, dimension, value, is_bot, count) " +
+			"SELECT '" + kind + "', " + column + ", " + rollupHour + ", d.dimension, d.value, COALESCE(is_bot, false), COUNT(*) " +
+			"FROM stats CROSS JOIN LATERAL " + rollupValues + " " +
+			"WHERE COALESCE(" + column + ", '') <> '' AND " + condition + " " +
+			"GROUP BY " + column + ", " + rollupHour + ", d.dimension, d.value, COALESCE(is_bot, false) " +
+			"ON CONFLICT (kind, ref, bucket, dimension, value, is_bot) DO UPDATE SET count = stats_rollups.count + EXCLUDED.count"
+		if err := tx.Exec(query, args...).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hoursOf returns the backfill records of the hours starting from start, an hour in UTC, until end.
+func hoursOf(start time.Time, end time.Time) []domain.StatsRollupBackfill {
+	var hours []domain.StatsRollupBackfill
+	for hour := start; hour.Before(end); hour = hour.Add(time.Hour) {
+		hours = append(hours, domain.StatsRollupBackfill{Bucket: hour})
+	}
+	return hours
+}
+
+// rollupColumns returns the dimensions to select from the value of the rollups aliased s, in the order of groupColumns.
+func rollupColumns(groupBy []string) string {
+	sorted := append([]string(nil), groupBy...)
+	sort.Strings(sorted)
+	var selected strings.Builder
+	for _, dimension := range groupBy {
+		if _, ok := dimensions[dimension]; !ok {
+			continue
+		}
+		position := sort.SearchStrings(sorted, dimension) + 1
+		fmt.Fprintf(&selected, ", split_part(s.value, %s, %d) as %s", valueSeparator, position, dimension)
+	}
+	return selected.String()
+}
+
+// hasRollup reports whether the rollups count the visits by the dimensions.
+func hasRollup(groupBy []string) bool {
+	grouping := groupingOf(groupBy)
+	for _, g := range rollupGroupings {
+		if g == grouping {
+			return true
+		}
+	}
+	return false
+}
+
+// countByDate counts the visits by the buckets and the dimensions of the filter, reading the rollups until today
+// and the raw stats of today. When the time zone isn't a whole number of hours away from UTC, or the rollups don't
+// count the visits by the dimensions, only the raw stats are read. The scopes, sharing the same arguments, select
+// the rollups and the stats, both aliased s.
+func countByDate(db *gorm.DB, rollupScope string, statsScope string, scopeArgs []interface{}, filter web.StatsFilter, dest interface{}) error {
+	start, end, err := periodOf(filter)
+	if err != nil {
+		return err
+	}
+	granularity, location := bucketsOf(filter)
+	cutoff := time.Now().UTC().Truncate(24 * time.Hour)
+	if !hourAligned(location, start, end) || !hasRollup(filter.GroupBy) {
+		cutoff = start
+	}
+	selected, grouped := groupColumns(filter.GroupBy)
+	bucket := func(column string) string {
+		return "TO_CHAR(date_trunc('" + granularity + "', " + column + " AT TIME ZONE ?), '" + bucketLabels[granularity].sql + "')"
+	}
+	query := "SELECT date" + grouped + ", SUM(total) as total FROM (" +
+		"SELECT " + bucket("s.bucket") + " as date" + rollupColumns(filter.GroupBy) + ", s.count as total FROM stats_rollups s " + rollupScope +
+		" AND s.dimension = ? AND s.bucket >= ? AND s.bucket < ? AND (? OR NOT s.is_bot)" +
+		" UNION ALL " +
+		"SELECT " + bucket("s.timestamp") + " as date" + selected + ", 1 as total FROM stats s " + statsScope +
+		" AND s.timestamp >= ? AND s.timestamp < ? AND (? OR NOT s.is_bot)" +
+		") counts GROUP BY date" + grouped + " ORDER BY date"
+
+	rollupEnd, statsStart := end, start
+	if cutoff.Before(rollupEnd) {
+		rollupEnd = cutoff
+	}
+	if cutoff.After(statsStart) {
+		statsStart = cutoff
+	}
+	args := []interface{}{location.String()}
+	args = append(args, scopeArgs...)
+	args = append(args, groupingOf(filter.GroupBy), start, rollupEnd, filter.IncludeBots, location.String())
+	args = append(args, scopeArgs...)
+	args = append(args, statsStart, end, filter.IncludeBots)
+	return db.Raw(query, args...).Scan(dest).Error
+}
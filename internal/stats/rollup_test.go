@@ -0,0 +1,187 @@
+package stats
+
+import (
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupingOf(t *testing.T) {
+	tests := []struct {
+		groupBy []string
+		want    string
+	}{
+		{groupBy: nil, want: ""},
+		{groupBy: []string{"os"}, want: "os"},
+		{groupBy: []string{"os", "browser"}, want: "browser,os"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := groupingOf(tt.groupBy); got != tt.want {
+				t.Errorf("groupingOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasRollup(t *testing.T) {
+	tests := []struct {
+		name    string
+		groupBy []string
+		want    bool
+	}{
+		{name: "no dimension", groupBy: nil, want: true},
+		{name: "one dimension", groupBy: []string{"country"}, want: true},
+		{name: "default grouping", groupBy: []string{"os", "browser"}, want: true},
+		{name: "default grouping reordered", groupBy: []string{"browser", "os"}, want: true},
+		{name: "other pair", groupBy: []string{"country", "os"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasRollup(tt.groupBy); got != tt.want {
+				t.Errorf("hasRollup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollupColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		groupBy []string
+		want    string
+	}{
+		{name: "no dimension", groupBy: nil, want: ""},
+		{name: "one dimension", groupBy: []string{"country"}, want: ", split_part(s.value, chr(31), 1) as country"},
+		{
+			name:    "values in the grouping order",
+			groupBy: []string{"os", "browser"},
+			want:    ", split_part(s.value, chr(31), 2) as os, split_part(s.value, chr(31), 1) as browser",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rollupColumns(tt.groupBy); got != tt.want {
+				t.Errorf("rollupColumns() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// capturedQuery builds the query of countByDate without running it, returning its SQL and arguments.
+func capturedQuery(t *testing.T, filter web.StatsFilter) (string, []interface{}) {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("unable to open the database: %v", err)
+	}
+	var query string
+	var args []interface{}
+	err = db.Callback().Row().After("gorm:row").Register("capture", func(tx *gorm.DB) {
+		query, args = tx.Statement.SQL.String(), tx.Statement.Vars
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var counts []web.StatsByDate
+	_ = countByDate(db, "WHERE s.kind = 'link' AND s.ref = ?", "WHERE s.link_refer = ?", []interface{}{"ref"}, filter, &counts)
+	if query == "" {
+		t.Fatal("countByDate() didn't query the database")
+	}
+	return query, args
+}
+
+func TestCountByDate(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := func(t time.Time) string { return t.Format(time.DateOnly) }
+	tests := []struct {
+		name       string
+		filter     web.StatsFilter
+		rollupEnd  time.Time
+		statsStart time.Time
+	}{
+		{
+			name:       "past period from the rollups",
+			filter:     web.StatsFilter{StartDate: "2023-10-01", EndDate: "2023-10-31", GroupBy: defaultGroupBy},
+			rollupEnd:  time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC),
+			statsStart: today,
+		},
+		{
+			name:       "today from the stats",
+			filter:     web.StatsFilter{StartDate: date(today.AddDate(0, 0, -7)), EndDate: date(today), GroupBy: []string{"country"}},
+			rollupEnd:  today,
+			statsStart: today,
+		},
+		{
+			name:       "grouping without rollups from the stats",
+			filter:     web.StatsFilter{StartDate: "2023-10-01", EndDate: "2023-10-31", GroupBy: []string{"country", "os"}},
+			rollupEnd:  time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC),
+			statsStart: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "time zone not aligned on hours from the stats",
+			filter:     web.StatsFilter{StartDate: "2023-10-01", EndDate: "2023-10-31", Location: kolkata},
+			rollupEnd:  time.Date(2023, 10, 1, 0, 0, 0, 0, kolkata),
+			statsStart: time.Date(2023, 10, 1, 0, 0, 0, 0, kolkata),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := capturedQuery(t, tt.filter)
+			if len(args) != 11 {
+				t.Fatalf("countByDate() queried with %d arguments, want 11: %v", len(args), args)
+			}
+			if grouping := args[2]; grouping != groupingOf(tt.filter.GroupBy) {
+				t.Errorf("rollups dimension = %v, want %q", grouping, groupingOf(tt.filter.GroupBy))
+			}
+			if rollupEnd := args[4].(time.Time); !rollupEnd.Equal(tt.rollupEnd) {
+				t.Errorf("rollups end = %v, want %v", rollupEnd, tt.rollupEnd)
+			}
+			if statsStart := args[8].(time.Time); !statsStart.Equal(tt.statsStart) {
+				t.Errorf("stats start = %v, want %v", statsStart, tt.statsStart)
+			}
+			for _, dimension := range tt.filter.GroupBy {
+				if !strings.Contains(query, "as "+dimension) {
+					t.Errorf("countByDate() query doesn't select %s: %s", dimension, query)
+				}
+			}
+		})
+	}
+}
+
+func TestHoursOf(t *testing.T) {
+	day := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		end  time.Time
+		want int
+		last time.Time
+	}{
+		{name: "whole day", end: day.AddDate(0, 0, 1), want: 24, last: day.Add(23 * time.Hour)},
+		{name: "until the cutover", end: day.Add(10*time.Hour + 30*time.Minute), want: 11, last: day.Add(10 * time.Hour)},
+		{name: "cutover on the hour", end: day.Add(10 * time.Hour), want: 10, last: day.Add(9 * time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hours := hoursOf(day, tt.end)
+			if len(hours) != tt.want {
+				t.Fatalf("hoursOf() returned %d hours, want %d", len(hours), tt.want)
+			}
+			if last := hours[len(hours)-1].Bucket; !last.Equal(tt.last) {
+				t.Errorf("hoursOf() last hour = %v, want %v", last, tt.last)
+			}
+		})
+	}
+}
@@ -3,39 +3,51 @@ package stats
 import (
 	"github.com/google/uuid"
 	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/pkg/hll"
 	"github.com/ronilsonalves/5lnk/pkg/web"
+	"log"
+	"time"
 )
 
 type Service interface {
-	GetUserStatsOverview(userId string) (web.StatsOverview, error)
-	RegisterLinkClick(stats domain.Stats) error
-	RegisterPageView(stats domain.Stats) error
-	GetStatsByUserId(pagination web.Pagination, userId string) (web.Pagination, error)
-	GetLinkStats(pagination web.Pagination, linkId string) (web.Pagination, error)
-	GetPageStats(pagination web.Pagination, pageId string) (web.Pagination, error)
-	GetLinkStatsByUserIdAndDate(userId, startDate, endDate string) (*[]web.StatsByDate, error)
-	GetPageStatsByUserIdAndDate(userId, startDate, endDate string) (*[]web.StatsByDate, error)
-	GetLinkStatsByDate(linkId uuid.UUID, startDate, endDate string) (*[]web.StatsByDate, error)
-	GetPageStatsByDate(pageId uuid.UUID, startDate, endDate string) (*[]web.StatsByDate, error)
+	GetUserStatsOverview(userId string, filter web.StatsFilter) (web.StatsOverview, error)
+	GetWorkspaceStatsOverview(workspaceId uuid.UUID, filter web.StatsFilter) (web.StatsOverview, error)
+	RegisterLinkClick(userId string, workspaceId *uuid.UUID, stats domain.Stats) error
+	RegisterPageView(userId string, workspaceId *uuid.UUID, stats domain.Stats) error
+	Subscribe(userId string) *Subscription
+	SubscribeWorkspace(workspaceId uuid.UUID) *Subscription
+	GetStatsByUserId(pagination web.Pagination, userId string, includeBots bool) (web.Pagination, error)
+	GetLinkStats(pagination web.Pagination, linkId string, includeBots bool) (web.Pagination, error)
+	GetPageStats(pagination web.Pagination, pageId string, includeBots bool) (web.Pagination, error)
+	GetLinkStatsByUserIdAndDate(userId string, filter web.StatsFilter) (*[]web.StatsByDate, error)
+	GetPageStatsByUserIdAndDate(userId string, filter web.StatsFilter) (*[]web.StatsByDate, error)
+	GetLinkStatsByDate(linkId uuid.UUID, filter web.StatsFilter) (*[]web.StatsByDate, error)
+	GetPageStatsByDate(pageId uuid.UUID, filter web.StatsFilter) (*[]web.StatsByDate, error)
+	GetLinkStatsByVariant(linkId uuid.UUID, filter web.StatsFilter) (*[]web.VariantStats, error)
+	GetLinkVisitors(linkId uuid.UUID, filter web.StatsFilter) (web.VisitorStats, error)
+	GetPageVisitors(pageId uuid.UUID, filter web.StatsFilter) (web.VisitorStats, error)
 }
 
 type statsService struct {
-	r Repository
+	r   Repository
+	q   *Queue
+	hub *Hub
 }
 
-// NewStatsService creates a new stats service
-func NewStatsService(r Repository) Service {
-	return &statsService{r: r}
+// NewStatsService creates a new stats service. Visits are saved through the queue, or right away when it is nil,
+// and published to the streams of the hub.
+func NewStatsService(r Repository, q *Queue, hub *Hub) Service {
+	return &statsService{r: r, q: q, hub: hub}
 }
 
-// GetUserStatsOverview returns a summary of the user links and pages stats
-func (s *statsService) GetUserStatsOverview(userId string) (web.StatsOverview, error) {
+// GetUserStatsOverview returns a summary of the user links and pages stats, with the unique visitors of the date range
+func (s *statsService) GetUserStatsOverview(userId string, filter web.StatsFilter) (web.StatsOverview, error) {
 	links, err := s.r.CountLinksByUser(userId)
 	if err != nil {
 		return web.StatsOverview{}, err
 	}
 
-	clicks, err := s.r.CountLinkClicksByUser(userId)
+	clicks, err := s.r.CountLinkClicksByUser(userId, filter.IncludeBots)
 	if err != nil {
 		return web.StatsOverview{}, err
 	}
@@ -45,64 +57,234 @@ func (s *statsService) GetUserStatsOverview(userId string) (web.StatsOverview, e
 		return web.StatsOverview{}, err
 	}
 
-	views, err := s.r.CountPageViewsByUser(userId)
+	views, err := s.r.CountPageViewsByUser(userId, filter.IncludeBots)
+	if err != nil {
+		return web.StatsOverview{}, err
+	}
+
+	linkVisitors, err := s.countUnique(domain.SketchUserLinks, userId, filter)
+	if err != nil {
+		return web.StatsOverview{}, err
+	}
+
+	pageVisitors, err := s.countUnique(domain.SketchUserPages, userId, filter)
 	if err != nil {
 		return web.StatsOverview{}, err
 	}
 
 	return web.StatsOverview{
 		Links: web.LinksSummary{
-			Total:  links,
-			Clicks: clicks,
+			Total:    links,
+			Clicks:   clicks,
+			Visitors: linkVisitors,
 		},
 		Pages: web.PagesSummary{
-			Total: pages,
-			Views: views,
+			Total:    pages,
+			Views:    views,
+			Visitors: pageVisitors,
 		},
 	}, nil
 }
 
-// RegisterLinkClick registers a new click for a link
-func (s *statsService) RegisterLinkClick(stats domain.Stats) error {
-	return s.r.CreateLinkStats(stats)
+// GetWorkspaceStatsOverview returns a summary of the workspace links and pages stats, with the unique visitors of the
+// date range
+func (s *statsService) GetWorkspaceStatsOverview(workspaceId uuid.UUID, filter web.StatsFilter) (web.StatsOverview, error) {
+	links, err := s.r.CountLinksByWorkspace(workspaceId)
+	if err != nil {
+		return web.StatsOverview{}, err
+	}
+
+	clicks, err := s.r.CountLinkClicksByWorkspace(workspaceId, filter.IncludeBots)
+	if err != nil {
+		return web.StatsOverview{}, err
+	}
+
+	pages, err := s.r.CountPagesByWorkspace(workspaceId)
+	if err != nil {
+		return web.StatsOverview{}, err
+	}
+
+	views, err := s.r.CountPageViewsByWorkspace(workspaceId, filter.IncludeBots)
+	if err != nil {
+		return web.StatsOverview{}, err
+	}
+
+	linkVisitors, err := s.countUnique(domain.SketchWorkspaceLinks, workspaceId.String(), filter)
+	if err != nil {
+		return web.StatsOverview{}, err
+	}
+
+	pageVisitors, err := s.countUnique(domain.SketchWorkspacePages, workspaceId.String(), filter)
+	if err != nil {
+		return web.StatsOverview{}, err
+	}
+
+	return web.StatsOverview{
+		Links: web.LinksSummary{
+			Total:    links,
+			Clicks:   clicks,
+			Visitors: linkVisitors,
+		},
+		Pages: web.PagesSummary{
+			Total:    pages,
+			Views:    views,
+			Visitors: pageVisitors,
+		},
+	}, nil
 }
 
-// RegisterPageView registers a new view for a page
-func (s *statsService) RegisterPageView(stats domain.Stats) error {
-	return s.r.CreatePageStats(stats)
+// RegisterLinkClick registers a new click for a link of the user, or of the workspace when it is shared
+func (s *statsService) RegisterLinkClick(userId string, workspaceId *uuid.UUID, stats domain.Stats) error {
+	return s.register(Event{Type: EventClick, UserId: userId, WorkspaceId: workspaceId, Stats: stats})
+}
+
+// RegisterPageView registers a new view for a page of the user, or of the workspace when it is shared
+func (s *statsService) RegisterPageView(userId string, workspaceId *uuid.UUID, stats domain.Stats) error {
+	return s.register(Event{Type: EventView, UserId: userId, WorkspaceId: workspaceId, Stats: stats})
+}
+
+// register queues a visit and publishes it, the clicks and views counters are updated when it is saved
+func (s *statsService) register(event Event) error {
+	if s.q == nil {
+		if err := s.r.SaveBatch([]domain.Stats{event.Stats}); err != nil {
+			return err
+		}
+	} else if err := s.q.Enqueue(event.Stats); err != nil {
+		// dropped visits are reported by the queue
+		if err != ErrQueueFull {
+			return err
+		}
+		return nil
+	}
+	s.hub.Publish(event)
+	return nil
+}
+
+// Subscribe returns a subscription to the clicks and views of the user links and pages
+func (s *statsService) Subscribe(userId string) *Subscription {
+	return s.hub.Subscribe(userId)
+}
+
+// SubscribeWorkspace returns a subscription to the clicks and views of the workspace links and pages
+func (s *statsService) SubscribeWorkspace(workspaceId uuid.UUID) *Subscription {
+	return s.hub.SubscribeWorkspace(workspaceId)
 }
 
 // GetStatsByUserId returns all stats for a user
-func (s *statsService) GetStatsByUserId(pagination web.Pagination, userId string) (web.Pagination, error) {
-	return s.r.FindStatsByUser(pagination, userId)
+func (s *statsService) GetStatsByUserId(pagination web.Pagination, userId string, includeBots bool) (web.Pagination, error) {
+	return s.r.FindStatsByUser(pagination, userId, includeBots)
 }
 
 // GetLinkStats returns all stats for a link
-func (s *statsService) GetLinkStats(pagination web.Pagination, linkId string) (web.Pagination, error) {
-	return s.r.FindLinkStats(pagination, linkId)
+func (s *statsService) GetLinkStats(pagination web.Pagination, linkId string, includeBots bool) (web.Pagination, error) {
+	return s.r.FindLinkStats(pagination, linkId, includeBots)
 }
 
 // GetPageStats returns all stats for a page
-func (s *statsService) GetPageStats(pagination web.Pagination, pageId string) (web.Pagination, error) {
-	return s.r.FindPageStats(pagination, pageId)
+func (s *statsService) GetPageStats(pagination web.Pagination, pageId string, includeBots bool) (web.Pagination, error) {
+	return s.r.FindPageStats(pagination, pageId, includeBots)
+}
+
+// GetLinkStatsByUserIdAndDate returns all stats for a link grouped by date, every bucket of the period included
+func (s *statsService) GetLinkStatsByUserIdAndDate(userId string, filter web.StatsFilter) (*[]web.StatsByDate, error) {
+	counts, err := s.r.FindLinkStatsByUserAndDate(userId, filter)
+	if err != nil {
+		return counts, err
+	}
+	filled := fillBuckets(*counts, filter)
+	return &filled, nil
+}
+
+// GetPageStatsByUserIdAndDate returns all stats for a page grouped by date, every bucket of the period included
+func (s *statsService) GetPageStatsByUserIdAndDate(userId string, filter web.StatsFilter) (*[]web.StatsByDate, error) {
+	counts, err := s.r.FindPageStatsByUserAndDate(userId, filter)
+	if err != nil {
+		return counts, err
+	}
+	filled := fillBuckets(*counts, filter)
+	return &filled, nil
+}
+
+// GetLinkStatsByDate returns all stats for a link grouped by date, every bucket of the period included
+func (s *statsService) GetLinkStatsByDate(linkId uuid.UUID, filter web.StatsFilter) (*[]web.StatsByDate, error) {
+	counts, err := s.r.CountLinkStatsByDate(linkId, filter)
+	if err != nil {
+		return counts, err
+	}
+	filled := fillBuckets(*counts, filter)
+	return &filled, nil
 }
 
-// GetLinkStatsByUserIdAndDate returns all stats for a link grouped by date
-func (s *statsService) GetLinkStatsByUserIdAndDate(userId, startDate, endDate string) (*[]web.StatsByDate, error) {
-	return s.r.FindLinkStatsByUserAndDate(userId, startDate, endDate)
+// GetPageStatsByDate returns all stats for a page grouped by date, every bucket of the period included
+func (s *statsService) GetPageStatsByDate(pageId uuid.UUID, filter web.StatsFilter) (*[]web.StatsByDate, error) {
+	counts, err := s.r.CountPageStatsByDate(pageId, filter)
+	if err != nil {
+		return counts, err
+	}
+	filled := fillBuckets(*counts, filter)
+	return &filled, nil
 }
 
-// GetPageStatsByUserIdAndDate returns all stats for a page grouped by date
-func (s *statsService) GetPageStatsByUserIdAndDate(userId, startDate, endDate string) (*[]web.StatsByDate, error) {
-	return s.r.FindPageStatsByUserAndDate(userId, startDate, endDate)
+// GetLinkStatsByVariant returns the clicks of each variant of a link
+func (s *statsService) GetLinkStatsByVariant(linkId uuid.UUID, filter web.StatsFilter) (*[]web.VariantStats, error) {
+	return s.r.CountLinkStatsByVariant(linkId, filter)
 }
 
-// GetLinkStatsByDate returns all stats for a link grouped by date
-func (s *statsService) GetLinkStatsByDate(linkId uuid.UUID, startDate, endDate string) (*[]web.StatsByDate, error) {
-	return s.r.CountLinkStatsByDate(linkId, startDate, endDate)
+// GetLinkVisitors returns the total and unique clicks of a link for each day and the whole date range
+func (s *statsService) GetLinkVisitors(linkId uuid.UUID, filter web.StatsFilter) (web.VisitorStats, error) {
+	days, err := s.r.CountLinkStatsByDay(linkId, filter)
+	if err != nil {
+		return web.VisitorStats{}, err
+	}
+	sketches, err := s.r.FindVisitorSketches(domain.SketchLink, linkId.String(), filter)
+	if err != nil {
+		return web.VisitorStats{}, err
+	}
+	return countVisitors(days, sketches), nil
 }
 
-// GetPageStatsByDate returns all stats for a page grouped by date
-func (s *statsService) GetPageStatsByDate(pageId uuid.UUID, startDate, endDate string) (*[]web.StatsByDate, error) {
-	return s.r.CountPageStatsByDate(pageId, startDate, endDate)
+// GetPageVisitors returns the total and unique views of a page for each day and the whole date range
+func (s *statsService) GetPageVisitors(pageId uuid.UUID, filter web.StatsFilter) (web.VisitorStats, error) {
+	days, err := s.r.CountPageStatsByDay(pageId, filter)
+	if err != nil {
+		return web.VisitorStats{}, err
+	}
+	sketches, err := s.r.FindVisitorSketches(domain.SketchPage, pageId.String(), filter)
+	if err != nil {
+		return web.VisitorStats{}, err
+	}
+	return countVisitors(days, sketches), nil
+}
+
+// countUnique returns the unique visitors of the sketches of the date range
+func (s *statsService) countUnique(kind string, ref string, filter web.StatsFilter) (int64, error) {
+	sketches, err := s.r.FindVisitorSketches(kind, ref, filter)
+	if err != nil {
+		return 0, err
+	}
+	return countVisitors(nil, sketches).Unique, nil
+}
+
+// countVisitors sets the unique visitors of each day from its sketch and merges the sketches for the whole range.
+// Sketches that can't be read are skipped.
+func countVisitors(days []web.VisitorsByDate, sketches []domain.VisitorSketch) web.VisitorStats {
+	daily := make(map[string]*hll.Sketch, len(sketches))
+	total := hll.New()
+	for _, stored := range sketches {
+		sketch, err := hll.FromBytes(stored.Registers)
+		if err != nil {
+			log.Printf("ERROR: unable to read the visitor sketch of %s %s: %v", stored.Kind, stored.Ref, err.Error())
+			continue
+		}
+		daily[stored.Day.Format(time.DateOnly)] = sketch
+		total.Merge(sketch)
+	}
+	visitors := web.VisitorStats{Unique: int64(total.Count()), Days: days}
+	for i := range visitors.Days {
+		visitors.Total += visitors.Days[i].Total
+		if sketch, ok := daily[visitors.Days[i].Date]; ok {
+			visitors.Days[i].Unique = int64(sketch.Count())
+		}
+	}
+	return visitors
 }
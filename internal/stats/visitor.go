@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// visitorSecret keys the visitor fingerprints, so they can't be reversed to the visitor IP address.
+var (
+	visitorSecret     []byte
+	visitorSecretOnce sync.Once
+)
+
+// getVisitorSecret reads the fingerprint key from VISITOR_HASH_SECRET. When it is not set a random one is generated,
+// so the same visitor gets a new fingerprint when the server restarts.
+func getVisitorSecret() []byte {
+	visitorSecretOnce.Do(func() {
+		if secret := os.Getenv("VISITOR_HASH_SECRET"); secret != "" {
+			visitorSecret = []byte(secret)
+			return
+		}
+		visitorSecret = make([]byte, 32)
+		if _, err := rand.Read(visitorSecret); err != nil {
+			log.Fatalf("error generating visitor hash secret: %v", err)
+		}
+		log.Println("WARNING: VISITOR_HASH_SECRET not set, using a random secret")
+	})
+	return visitorSecret
+}
+
+// Fingerprint returns an anonymous identifier of the visitor derived from its address and user agent. It is salted
+// with the day of the visit, so the same visitor can't be followed from one day to another.
+func Fingerprint(day time.Time, address, userAgent string) string {
+	salt := hmac.New(sha256.New, getVisitorSecret())
+	salt.Write([]byte(day.UTC().Format(time.DateOnly)))
+	mac := hmac.New(sha256.New, salt.Sum(nil))
+	mac.Write([]byte(address + "|" + userAgent))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// visitorKey returns the hash of a fingerprint counted by the visitor sketches.
+func visitorKey(fingerprint string) (uint64, bool) {
+	data, err := hex.DecodeString(fingerprint)
+	if err != nil || len(data) < 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(data), true
+}
@@ -0,0 +1,40 @@
+// Package testdb opens the Postgres database the repository tests run against.
+package testdb
+
+import (
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"os"
+	"testing"
+)
+
+// Open connects to the database of TEST_DATABASE_URL and migrates the models, skipping the test when it isn't set.
+// The returned transaction is rolled back when the test ends, so tests don't see each other's rows.
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set, skipping the database test")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("unable to connect to the test database: %v", err)
+	}
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("unable to start the test transaction: %v", tx.Error)
+	}
+	t.Cleanup(func() {
+		tx.Rollback()
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	for _, model := range models {
+		if err := tx.AutoMigrate(model); err != nil {
+			t.Fatalf("unable to migrate the test database: %v", err)
+		}
+	}
+	return tx
+}
@@ -0,0 +1,355 @@
+package urlsafety
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"golang.org/x/net/idna"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// resolveTimeout bounds the DNS lookup of a destination host.
+const resolveTimeout = time.Second * 2
+
+// resolveConcurrency is how many hosts of a batch are resolved at once.
+const resolveConcurrency = 16
+
+// lookupIPAddr resolves the addresses of a host.
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// Checker normalises destination URLs and rejects the unsafe ones: non-http(s) schemes, private network targets
+// and the domains or patterns of the blocklist.
+type Checker struct {
+	blockedDomains  map[string]bool
+	blockedPatterns []*regexp.Regexp
+	resolveHosts    bool
+	// hosts caches the outcome of the host lookups of a batch, and deadline bounds them, both unset outside batches
+	hosts    *hostCache
+	deadline time.Time
+}
+
+// hostCache holds the outcome of the lookup of each host, nil for the hosts with only public addresses.
+type hostCache struct {
+	mu      sync.Mutex
+	results map[string]error
+}
+
+// NewChecker creates a checker without blocklist. When resolveHosts is set, host names are resolved to make sure
+// none of their addresses is private.
+func NewChecker(resolveHosts bool) *Checker {
+	return &Checker{blockedDomains: make(map[string]bool), resolveHosts: resolveHosts}
+}
+
+// NewCheckerFromEnv creates a checker from the URL_BLOCKLIST_PATH and URL_RESOLVE_HOSTS environment variables, host
+// names are resolved unless URL_RESOLVE_HOSTS is false.
+func NewCheckerFromEnv() (*Checker, error) {
+	c := NewChecker(os.Getenv("URL_RESOLVE_HOSTS") != "false")
+	if path := os.Getenv("URL_BLOCKLIST_PATH"); path != "" {
+		if err := c.LoadBlocklist(path); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// LoadBlocklist reads a blocklist file. Each line is either a domain, blocking it and its subdomains, or a
+// `regex:` prefixed pattern matched against the normalised URL. Blank lines and # comments are skipped.
+func (c *Checker) LoadBlocklist(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open the url blocklist: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if pattern, ok := strings.CutPrefix(entry, "regex:"); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid url blocklist pattern at line %d: %v", line, err)
+			}
+			c.blockedPatterns = append(c.blockedPatterns, re)
+			continue
+		}
+		domain, err := idna.Lookup.ToASCII(strings.TrimPrefix(strings.ToLower(entry), "*."))
+		if err != nil {
+			return fmt.Errorf("invalid url blocklist domain at line %d: %v", line, err)
+		}
+		c.blockedDomains[domain] = true
+	}
+	return scanner.Err()
+}
+
+// ForBatch returns a checker sharing the blocklist that resolves every host once, giving up on the lookups still
+// pending when the timeout elapses. It is meant for the URLs of a single bulk request.
+func (c *Checker) ForBatch(timeout time.Duration) *Checker {
+	batch := *c
+	batch.hosts = &hostCache{results: make(map[string]error)}
+	batch.deadline = time.Now().Add(timeout)
+	return &batch
+}
+
+// Prepare resolves the hosts of the URLs concurrently, so the checks of a batch read their outcome from its cache.
+// Invalid and blocked URLs are left to Check.
+func (c *Checker) Prepare(raws []string) {
+	if !c.resolveHosts || c.hosts == nil {
+		return
+	}
+	hosts := make(map[string]bool)
+	for _, raw := range raws {
+		if destination, err := Normalize(raw); err == nil && net.ParseIP(destination.Hostname()) == nil {
+			hosts[destination.Hostname()] = true
+		}
+	}
+	pending := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < resolveConcurrency && i < len(hosts); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range pending {
+				_ = c.checkNetwork(host)
+			}
+		}()
+	}
+	for host := range hosts {
+		pending <- host
+	}
+	close(pending)
+	wg.Wait()
+}
+
+// Check returns the normalised form of the URL, or an error explaining why it can't be used as a destination.
+func (c *Checker) Check(raw string) (string, error) {
+	destination, err := Normalize(raw)
+	if err != nil {
+		return "", err
+	}
+	host := destination.Hostname()
+
+	if c.isBlockedDomain(host) {
+		return "", fmt.Errorf("the url `%s` is blocked", raw)
+	}
+	for _, pattern := range c.blockedPatterns {
+		if pattern.MatchString(destination.String()) {
+			return "", fmt.Errorf("the url `%s` is blocked", raw)
+		}
+	}
+	if err := c.checkNetwork(host); err != nil {
+		return "", err
+	}
+	return destination.String(), nil
+}
+
+// Normalize trims the URL, defaults its scheme to https, lower-cases its scheme and host, converts international
+// domain names to their ASCII form and removes default ports. Only http and https URLs without credentials are valid.
+func Normalize(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") && !strings.Contains(strings.SplitN(raw, "/", 2)[0], ":") {
+		raw = "https://" + raw
+	}
+	destination, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url `%s` provided", raw)
+	}
+	destination.Scheme = strings.ToLower(destination.Scheme)
+	if destination.Scheme != "http" && destination.Scheme != "https" {
+		return nil, fmt.Errorf("the url scheme `%s` is not allowed, only http and https", destination.Scheme)
+	}
+	if destination.User != nil {
+		return nil, fmt.Errorf("urls with credentials are not allowed")
+	}
+
+	host, port := strings.ToLower(destination.Hostname()), destination.Port()
+	if host == "" {
+		return nil, fmt.Errorf("invalid url `%s` provided", raw)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+		if ip.To4() == nil {
+			host = "[" + host + "]"
+		}
+	} else if isNumericHost(host) {
+		// Browsers read hosts ending with a number as IPv4 addresses, e.g. 2130706433, 0x7f.1 or 127.1
+		ip, ok := parseIPv4(host)
+		if !ok {
+			return nil, fmt.Errorf("invalid url host `%s` provided", destination.Hostname())
+		}
+		host = ip.String()
+	} else if host, err = idna.Lookup.ToASCII(strings.TrimSuffix(host, ".")); err != nil {
+		return nil, fmt.Errorf("invalid url host `%s` provided", destination.Hostname())
+	}
+	if (destination.Scheme == "http" && port == "80") || (destination.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	destination.Host = host
+	if port != "" {
+		destination.Host += ":" + port
+	}
+	return destination, nil
+}
+
+// isBlockedDomain checks the host and all its parent domains against the blocklist.
+func (c *Checker) isBlockedDomain(host string) bool {
+	for domain := host; domain != ""; {
+		if c.blockedDomains[domain] {
+			return true
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	return false
+}
+
+// checkNetwork rejects loopback, private, link-local and other non-public targets.
+func (c *Checker) checkNetwork(host string) error {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") || strings.HasSuffix(host, ".internal") {
+		return fmt.Errorf("urls targeting private networks are not allowed")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !isPublic(ip) {
+			return fmt.Errorf("urls targeting private networks are not allowed")
+		}
+		return nil
+	}
+	if !c.resolveHosts {
+		return nil
+	}
+	if c.hosts == nil {
+		return c.resolve(host)
+	}
+	c.hosts.mu.Lock()
+	err, ok := c.hosts.results[host]
+	c.hosts.mu.Unlock()
+	if ok {
+		return err
+	}
+	err = c.resolve(host)
+	c.hosts.mu.Lock()
+	c.hosts.results[host] = err
+	c.hosts.mu.Unlock()
+	return err
+}
+
+// resolve looks the host up, rejecting it when one of its addresses isn't public.
+func (c *Checker) resolve(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	if !c.deadline.IsZero() {
+		if !time.Now().Before(c.deadline) {
+			return fmt.Errorf("unable to resolve the url host `%s` in time", host)
+		}
+		ctx, cancel = context.WithDeadline(ctx, c.deadline)
+		defer cancel()
+	}
+	addresses, err := lookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("unable to resolve the url host `%s`", host)
+	}
+	for _, address := range addresses {
+		if !isPublic(address.IP) {
+			return fmt.Errorf("urls targeting private networks are not allowed")
+		}
+	}
+	return nil
+}
+
+// isNumericHost reports whether the last label of the host is a decimal or hexadecimal number, making it an IPv4
+// address for browsers.
+func isNumericHost(host string) bool {
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+	last := labels[len(labels)-1]
+	if hex, ok := strings.CutPrefix(last, "0x"); ok {
+		return strings.Trim(hex, "0123456789abcdef") == ""
+	}
+	return last != "" && strings.Trim(last, "0123456789") == ""
+}
+
+// parseIPv4 parses the host like inet_aton: one to four decimal, octal (0 prefixed) or hexadecimal (0x prefixed)
+// parts, the last one filling the remaining bytes of the address.
+func parseIPv4(host string) (net.IP, bool) {
+	parts := strings.Split(strings.TrimSuffix(host, "."), ".")
+	if len(parts) > 4 {
+		return nil, false
+	}
+	numbers := make([]uint64, len(parts))
+	for i, part := range parts {
+		base, digits := 10, part
+		if hex, ok := strings.CutPrefix(part, "0x"); ok {
+			base, digits = 16, hex
+		} else if len(part) > 1 && part[0] == '0' {
+			base, digits = 8, part[1:]
+		}
+		if digits == "" {
+			if base != 16 {
+				return nil, false
+			}
+			digits = "0"
+		}
+		n, err := strconv.ParseUint(digits, base, 32)
+		if err != nil {
+			return nil, false
+		}
+		numbers[i] = n
+	}
+	last := len(numbers) - 1
+	for _, n := range numbers[:last] {
+		if n > 0xff {
+			return nil, false
+		}
+	}
+	if numbers[last] >= 1<<(8*(4-last)) {
+		return nil, false
+	}
+	address := numbers[last]
+	for i, n := range numbers[:last] {
+		address |= n << (8 * (3 - i))
+	}
+	return net.IPv4(byte(address>>24), byte(address>>16), byte(address>>8), byte(address)), true
+}
+
+// specialPurpose are the IANA special-purpose address blocks, and the multicast and reserved ranges, none of
+// them reachable as a public destination.
+var specialPurpose = func() []*net.IPNet {
+	blocks := []string{
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+		"192.0.0.0/24", "192.0.2.0/24", "192.31.196.0/24", "192.52.193.0/24", "192.88.99.0/24", "192.168.0.0/16",
+		"192.175.48.0/24", "198.18.0.0/15", "198.51.100.0/24", "203.0.113.0/24", "224.0.0.0/4", "240.0.0.0/4",
+		"::/128", "::1/128", "64:ff9b::/96", "64:ff9b:1::/48", "100::/64", "2001::/23", "2001:db8::/32",
+		"2002::/16", "2620:4f:8000::/48", "3fff::/20", "5f00::/16", "fc00::/7", "fe80::/10", "fec0::/10", "ff00::/8",
+	}
+	networks := make([]*net.IPNet, len(blocks))
+	for i, block := range blocks {
+		_, network, err := net.ParseCIDR(block)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}()
+
+// isPublic reports whether the address is outside every special-purpose block. IPv4-mapped IPv6 addresses are
+// checked as their IPv4 address.
+func isPublic(ip net.IP) bool {
+	for _, network := range specialPurpose {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,9 +1,11 @@
 package utils
 
 import (
+	"github.com/google/uuid"
 	"github.com/ronilsonalves/5lnk/pkg/web"
 	"gorm.io/gorm"
 	"math"
+	"time"
 )
 
 // Paginate returns a function that can be used to paginate a query.
@@ -17,36 +19,51 @@ func Paginate(value interface{}, pagination *web.Pagination, db *gorm.DB) func(d
 	}
 }
 
-// PaginateStatsByUserID returns a function that can be used to paginate a query to retrieve stats data from a user.
-func PaginateStatsByUserID(userId string, value interface{}, pagination *web.Pagination, db *gorm.DB) func(db *gorm.DB) *gorm.DB {
+// CreatedAfter returns a function that can be used to read the rows of a query in batches by creation, the batch
+// starting after the row created at createdAt with the id. A zero createdAt starts with the first row.
+func CreatedAfter(createdAt time.Time, id uuid.UUID, size int) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if !createdAt.IsZero() {
+			db = db.Where("(created_at, id) > (?, ?)", createdAt, id)
+		}
+		return db.Order("created_at, id").Limit(size)
+	}
+}
+
+// PaginateStatsByUserID returns a function that can be used to paginate a query to retrieve stats data from the
+// personal links and pages of a user.
+// Bot visits are only included when includeBots is set.
+func PaginateStatsByUserID(userId string, includeBots bool, value interface{}, pagination *web.Pagination, db *gorm.DB) func(db *gorm.DB) *gorm.DB {
 	var totalItems int64
-	selectQuery := "SELECT s.id, s.link_refer, s.page_refer, s.timestamp, s.os, s.browser FROM stats s INNER JOIN links l ON s.link_refer = l.id::text WHERE l.user_id = ? UNION SELECT s2.id, s2.link_refer, s2.page_refer, s2.timestamp, s2.os, s2.browser FROM stats s2 INNER JOIN links_pages p ON s2.page_refer = p.id::text WHERE p.user_id = ?"
-	db.Raw(selectQuery, userId, userId).Count(&totalItems)
+	owned := "(link_refer IN (SELECT id::text FROM links WHERE user_id = ? AND workspace_id IS NULL) OR page_refer IN (SELECT id::text FROM links_pages WHERE user_id = ? AND workspace_id IS NULL))"
+	db.Model(value).Where(owned, userId, userId).Where("? OR NOT is_bot", includeBots).Count(&totalItems)
 	pagination.Items = totalItems
 	pagination.TotalPages = int64(math.Ceil(float64(totalItems) / float64(pagination.PageSize)))
 	return func(db *gorm.DB) *gorm.DB {
-		return db.Offset(pagination.GetOffset()).Limit(pagination.GetLimit()).Order(pagination.GetSort())
+		return db.Offset(pagination.GetOffset()).Limit(pagination.GetLimit()).Order(pagination.GetSort()).Where(owned, userId, userId).Where("? OR NOT is_bot", includeBots)
 	}
 }
 
 // PaginateStatsByPageRef returns a function that can be used to paginate a query to retrieve stats data from a page.
-func PaginateStatsByPageRef(pageId string, value interface{}, pagination *web.Pagination, db *gorm.DB) func(db *gorm.DB) *gorm.DB {
+// Bot visits are only included when includeBots is set.
+func PaginateStatsByPageRef(pageId string, includeBots bool, value interface{}, pagination *web.Pagination, db *gorm.DB) func(db *gorm.DB) *gorm.DB {
 	var totalItems int64
-	db.Model(value).Where("page_refer = ?", pageId).Count(&totalItems)
+	db.Model(value).Where("page_refer = ?", pageId).Where("? OR NOT is_bot", includeBots).Count(&totalItems)
 	pagination.Items = totalItems
 	pagination.TotalPages = int64(math.Ceil(float64(totalItems) / float64(pagination.PageSize)))
 	return func(db *gorm.DB) *gorm.DB {
-		return db.Offset(pagination.GetOffset()).Limit(pagination.GetLimit()).Order(pagination.GetSort()).Where("page_refer = ?", pageId)
+		return db.Offset(pagination.GetOffset()).Limit(pagination.GetLimit()).Order(pagination.GetSort()).Where("page_refer = ?", pageId).Where("? OR NOT is_bot", includeBots)
 	}
 }
 
 // PaginateStatsByLinkRef returns a function that can be used to paginate a query to retrieve stats data from a link.
-func PaginateStatsByLinkRef(linkId string, value interface{}, pagination *web.Pagination, db *gorm.DB) func(db *gorm.DB) *gorm.DB {
+// Bot visits are only included when includeBots is set.
+func PaginateStatsByLinkRef(linkId string, includeBots bool, value interface{}, pagination *web.Pagination, db *gorm.DB) func(db *gorm.DB) *gorm.DB {
 	var totalItems int64
-	db.Model(value).Where("link_refer = ?", linkId).Count(&totalItems)
+	db.Model(value).Where("link_refer = ?", linkId).Where("? OR NOT is_bot", includeBots).Count(&totalItems)
 	pagination.Items = totalItems
 	pagination.TotalPages = int64(math.Ceil(float64(totalItems) / float64(pagination.PageSize)))
 	return func(db *gorm.DB) *gorm.DB {
-		return db.Offset(pagination.GetOffset()).Limit(pagination.GetLimit()).Order(pagination.GetSort()).Where("link_refer = ?", linkId)
+		return db.Offset(pagination.GetOffset()).Limit(pagination.GetLimit()).Order(pagination.GetSort()).Where("link_refer = ?", linkId).Where("? OR NOT is_bot", includeBots)
 	}
 }
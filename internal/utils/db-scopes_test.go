@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestCreatedAfter(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		want      string
+	}{
+		{name: "first batch", want: `SELECT * FROM "links" ORDER BY created_at, id LIMIT 100`},
+		{
+			name:      "next batch",
+			createdAt: time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC),
+			want:      `SELECT * FROM "links" WHERE (created_at, id) > ('2023-10-01 12:00:00', '` + id.String() + `') ORDER BY created_at, id LIMIT 100`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				var rows []map[string]interface{}
+				return tx.Table("links").Scopes(CreatedAfter(tt.createdAt, id, 100)).Find(&rows)
+			})
+			if query != tt.want {
+				t.Errorf("CreatedAfter() query = %s, want %s", query, tt.want)
+			}
+		})
+	}
+}
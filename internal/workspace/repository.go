@@ -0,0 +1,169 @@
+package workspace
+
+import (
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log"
+	"time"
+)
+
+type Repository interface {
+	Create(workspace *domain.Workspace) error
+	FindById(id uuid.UUID) (*domain.Workspace, error)
+	FindAllByUser(userId string) (*[]domain.Workspace, error)
+	Update(workspace *domain.Workspace) error
+	Delete(workspace *domain.Workspace) error
+	CountResources(id uuid.UUID) (int64, error)
+	FindMember(workspaceId uuid.UUID, userId string) (*domain.WorkspaceMember, error)
+	SaveMember(member *domain.WorkspaceMember) error
+	DeleteMember(member *domain.WorkspaceMember) error
+	CountOwners(workspaceId uuid.UUID) (int64, error)
+	CreateInvitation(invitation *domain.WorkspaceInvitation) error
+	FindInvitation(id uuid.UUID) (*domain.WorkspaceInvitation, error)
+	FindInvitationsByWorkspace(workspaceId uuid.UUID) (*[]domain.WorkspaceInvitation, error)
+	DeleteInvitation(invitation *domain.WorkspaceInvitation) error
+	AcceptInvitation(id uuid.UUID, userId string, accept func(invitation *domain.WorkspaceInvitation) error) (*domain.WorkspaceMember, error)
+}
+
+type workspaceRepository struct {
+	db *gorm.DB
+}
+
+// NewWorkspaceRepository creates a new workspace repository
+func NewWorkspaceRepository(db *gorm.DB) Repository {
+	return &workspaceRepository{db: db}
+}
+
+// Create creates a new workspace with its members
+func (r *workspaceRepository) Create(workspace *domain.Workspace) error {
+	return r.db.Create(workspace).Error
+}
+
+// FindById finds a workspace by the ID with its members
+func (r *workspaceRepository) FindById(id uuid.UUID) (*domain.Workspace, error) {
+	var workspace domain.Workspace
+	if err := r.db.Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).Where("id = ?", id).First(&workspace).Error; err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+// FindAllByUser finds all workspaces the user is a member of
+func (r *workspaceRepository) FindAllByUser(userId string) (*[]domain.Workspace, error) {
+	var workspaces []domain.Workspace
+	if err := r.db.Where("id IN (SELECT workspace_id FROM workspace_members WHERE user_id = ?)", userId).
+		Order("created_at").Find(&workspaces).Error; err != nil {
+		log.Printf("ERROR: unable to find the workspaces by user due to %v", err.Error())
+		return nil, err
+	}
+	return &workspaces, nil
+}
+
+// Update updates the name of a workspace
+func (r *workspaceRepository) Update(workspace *domain.Workspace) error {
+	return r.db.Model(&domain.Workspace{ID: workspace.ID}).Update("name", workspace.Name).Error
+}
+
+// Delete deletes a workspace with its members and invitations
+func (r *workspaceRepository) Delete(workspace *domain.Workspace) error {
+	return r.db.Select("Members").Delete(workspace).Error
+}
+
+// CountResources returns the number of links and pages of a workspace
+func (r *workspaceRepository) CountResources(id uuid.UUID) (int64, error) {
+	var count int64
+	if err := r.db.Raw("SELECT (SELECT COUNT(*) FROM links WHERE workspace_id = ?) + (SELECT COUNT(*) FROM links_pages WHERE workspace_id = ?)", id, id).
+		Scan(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindMember finds the member of a workspace by the user ID
+func (r *workspaceRepository) FindMember(workspaceId uuid.UUID, userId string) (*domain.WorkspaceMember, error) {
+	var member domain.WorkspaceMember
+	if err := r.db.Where("workspace_id = ? AND user_id = ?", workspaceId, userId).First(&member).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// SaveMember adds a member to a workspace, or updates its role
+func (r *workspaceRepository) SaveMember(member *domain.WorkspaceMember) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "workspace_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(member).Error
+}
+
+// DeleteMember removes a member from a workspace
+func (r *workspaceRepository) DeleteMember(member *domain.WorkspaceMember) error {
+	return r.db.Where("workspace_id = ? AND user_id = ?", member.WorkspaceId, member.UserId).Delete(&domain.WorkspaceMember{}).Error
+}
+
+// CountOwners returns the number of owners of a workspace
+func (r *workspaceRepository) CountOwners(workspaceId uuid.UUID) (int64, error) {
+	var count int64
+	if err := r.db.Model(&domain.WorkspaceMember{}).Where("workspace_id = ? AND role = ?", workspaceId, domain.RoleOwner).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CreateInvitation creates a new invitation to a workspace
+func (r *workspaceRepository) CreateInvitation(invitation *domain.WorkspaceInvitation) error {
+	return r.db.Create(invitation).Error
+}
+
+// FindInvitation finds an invitation by the ID
+func (r *workspaceRepository) FindInvitation(id uuid.UUID) (*domain.WorkspaceInvitation, error) {
+	var invitation domain.WorkspaceInvitation
+	if err := r.db.Where("id = ?", id).First(&invitation).Error; err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// FindInvitationsByWorkspace finds all invitations to a workspace, the latest first
+func (r *workspaceRepository) FindInvitationsByWorkspace(workspaceId uuid.UUID) (*[]domain.WorkspaceInvitation, error) {
+	var invitations []domain.WorkspaceInvitation
+	if err := r.db.Where("workspace_id = ?", workspaceId).Order("created_at desc").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	return &invitations, nil
+}
+
+// DeleteInvitation deletes an invitation
+func (r *workspaceRepository) DeleteInvitation(invitation *domain.WorkspaceInvitation) error {
+	return r.db.Where("id = ?", invitation.ID).Delete(&domain.WorkspaceInvitation{}).Error
+}
+
+// AcceptInvitation locks the invitation and, when accept allows it, marks it accepted by the user and adds them to
+// the workspace, so an invitation can't be accepted twice
+func (r *workspaceRepository) AcceptInvitation(id uuid.UUID, userId string, accept func(invitation *domain.WorkspaceInvitation) error) (*domain.WorkspaceMember, error) {
+	var member domain.WorkspaceMember
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var invitation domain.WorkspaceInvitation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&invitation).Error; err != nil {
+			return err
+		}
+		if err := accept(&invitation); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := tx.Model(&invitation).Updates(map[string]interface{}{"accepted_by": userId, "accepted_at": now}).Error; err != nil {
+			return err
+		}
+		member = domain.WorkspaceMember{WorkspaceId: invitation.WorkspaceId, UserId: userId, Role: invitation.Role}
+		return tx.Create(&member).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
@@ -0,0 +1,257 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"gorm.io/gorm"
+	"log"
+	"strings"
+	"time"
+)
+
+// ErrForbidden is returned when a member's role doesn't grant an action in the workspace.
+var ErrForbidden = errors.New("your role in the workspace doesn't allow this action")
+
+// invitationTTL is how long an invitation can be accepted.
+const invitationTTL = time.Hour * 24 * 7
+
+type Service interface {
+	Create(userId string, request web.CreateWorkspace) (domain.Workspace, error)
+	GetWorkspace(workspaceId uuid.UUID) (*domain.Workspace, error)
+	GetAllByUser(userId string) (*[]domain.Workspace, error)
+	Update(workspaceId uuid.UUID, request web.CreateWorkspace) (*domain.Workspace, error)
+	Delete(workspaceId uuid.UUID) error
+	Authorize(workspaceId uuid.UUID, userId string, role string) (*domain.WorkspaceMember, error)
+	AuthorizeResource(ownerId string, workspaceId *uuid.UUID, userId string, role string) error
+	SetMemberRole(workspaceId uuid.UUID, actorId string, userId string, role string) (*domain.WorkspaceMember, error)
+	RemoveMember(workspaceId uuid.UUID, actorId string, userId string) error
+	Invite(workspaceId uuid.UUID, actorId string, request web.CreateInvitation) (domain.WorkspaceInvitation, error)
+	GetInvitations(workspaceId uuid.UUID) (*[]domain.WorkspaceInvitation, error)
+	RevokeInvitation(workspaceId uuid.UUID, invitationId uuid.UUID) error
+	AcceptInvitation(invitationId uuid.UUID, userId string) (*domain.WorkspaceMember, error)
+}
+
+type workspaceService struct {
+	r Repository
+}
+
+// NewWorkspaceService creates a new workspace service
+func NewWorkspaceService(r Repository) Service {
+	return &workspaceService{r: r}
+}
+
+// Create creates a new workspace owned by the user
+func (s *workspaceService) Create(userId string, request web.CreateWorkspace) (domain.Workspace, error) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return domain.Workspace{}, fmt.Errorf("the name is required")
+	}
+	workspace := domain.Workspace{
+		Name:      name,
+		CreatedBy: userId,
+		Members:   []domain.WorkspaceMember{{UserId: userId, Role: domain.RoleOwner}},
+	}
+	if err := s.r.Create(&workspace); err != nil {
+		log.Printf("ERROR: unable to create the workspace due to %v", err.Error())
+		return domain.Workspace{}, err
+	}
+	log.Printf("INFO: workspace `%s` created by user `%s`", workspace.ID, userId)
+	return workspace, nil
+}
+
+// GetWorkspace returns a workspace by the ID with its members
+func (s *workspaceService) GetWorkspace(workspaceId uuid.UUID) (*domain.Workspace, error) {
+	return s.r.FindById(workspaceId)
+}
+
+// GetAllByUser returns all workspaces the user is a member of
+func (s *workspaceService) GetAllByUser(userId string) (*[]domain.Workspace, error) {
+	log.Printf("INFO: getting all workspaces by userId `%v`...", userId)
+	return s.r.FindAllByUser(userId)
+}
+
+// Update renames a workspace
+func (s *workspaceService) Update(workspaceId uuid.UUID, request web.CreateWorkspace) (*domain.Workspace, error) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, fmt.Errorf("the name is required")
+	}
+	if err := s.r.Update(&domain.Workspace{ID: workspaceId, Name: name}); err != nil {
+		return nil, err
+	}
+	return s.r.FindById(workspaceId)
+}
+
+// Delete deletes a workspace, refusing it while the workspace still has links or pages so they aren't orphaned
+func (s *workspaceService) Delete(workspaceId uuid.UUID) error {
+	workspace, err := s.r.FindById(workspaceId)
+	if err != nil {
+		return err
+	}
+	count, err := s.r.CountResources(workspaceId)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("the workspace still has %d links and pages, delete them first", count)
+	}
+	if err := s.r.Delete(workspace); err != nil {
+		log.Printf("ERROR: unable to delete the workspace `%s` due to %v", workspaceId, err.Error())
+		return err
+	}
+	return nil
+}
+
+// Authorize returns the membership of the user when their role in the workspace grants the role. Users outside the
+// workspace get a not found error, so its existence isn't disclosed.
+func (s *workspaceService) Authorize(workspaceId uuid.UUID, userId string, role string) (*domain.WorkspaceMember, error) {
+	member, err := s.r.FindMember(workspaceId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !member.Can(role) {
+		return nil, ErrForbidden
+	}
+	return member, nil
+}
+
+// AuthorizeResource checks the access of the user to a link or page: personal resources are only accessed by their
+// owner, while workspace resources are accessed by the members with the role.
+func (s *workspaceService) AuthorizeResource(ownerId string, workspaceId *uuid.UUID, userId string, role string) error {
+	if workspaceId == nil {
+		if ownerId != userId {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	}
+	_, err := s.Authorize(*workspaceId, userId, role)
+	return err
+}
+
+// SetMemberRole changes the role of a member. Owners manage every role, admins only the roles below their own.
+func (s *workspaceService) SetMemberRole(workspaceId uuid.UUID, actorId string, userId string, role string) (*domain.WorkspaceMember, error) {
+	if !domain.ValidRole(role) {
+		return nil, fmt.Errorf("the role `%s` is not valid", role)
+	}
+	actor, err := s.Authorize(workspaceId, actorId, domain.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.r.FindMember(workspaceId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanManage(member.Role) || !actor.CanManage(role) {
+		return nil, ErrForbidden
+	}
+	if member.Role == domain.RoleOwner && role != domain.RoleOwner {
+		if err := s.keepOwner(workspaceId); err != nil {
+			return nil, err
+		}
+	}
+	member.Role = role
+	if err := s.r.SaveMember(member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember removes a member from a workspace. Members can leave by themselves, otherwise owners remove every
+// member and admins only the members below their role.
+func (s *workspaceService) RemoveMember(workspaceId uuid.UUID, actorId string, userId string) error {
+	member, err := s.r.FindMember(workspaceId, userId)
+	if err != nil {
+		return err
+	}
+	if actorId != userId {
+		actor, err := s.Authorize(workspaceId, actorId, domain.RoleAdmin)
+		if err != nil {
+			return err
+		}
+		if !actor.CanManage(member.Role) {
+			return ErrForbidden
+		}
+	}
+	if member.Role == domain.RoleOwner {
+		if err := s.keepOwner(workspaceId); err != nil {
+			return err
+		}
+	}
+	return s.r.DeleteMember(member)
+}
+
+// keepOwner refuses to demote or remove the last owner of a workspace
+func (s *workspaceService) keepOwner(workspaceId uuid.UUID) error {
+	owners, err := s.r.CountOwners(workspaceId)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return fmt.Errorf("the workspace must keep at least one owner")
+	}
+	return nil
+}
+
+// Invite creates an invitation to join the workspace with a role the actor can manage
+func (s *workspaceService) Invite(workspaceId uuid.UUID, actorId string, request web.CreateInvitation) (domain.WorkspaceInvitation, error) {
+	if !domain.ValidRole(request.Role) {
+		return domain.WorkspaceInvitation{}, fmt.Errorf("the role `%s` is not valid", request.Role)
+	}
+	actor, err := s.Authorize(workspaceId, actorId, domain.RoleAdmin)
+	if err != nil {
+		return domain.WorkspaceInvitation{}, err
+	}
+	if !actor.CanManage(request.Role) {
+		return domain.WorkspaceInvitation{}, ErrForbidden
+	}
+	invitation := domain.WorkspaceInvitation{
+		WorkspaceId: workspaceId,
+		Email:       strings.TrimSpace(request.Email),
+		Role:        request.Role,
+		InvitedBy:   actorId,
+		ExpiresAt:   time.Now().Add(invitationTTL),
+	}
+	if err := s.r.CreateInvitation(&invitation); err != nil {
+		log.Printf("ERROR: unable to create the invitation to workspace `%s` due to %v", workspaceId, err.Error())
+		return domain.WorkspaceInvitation{}, err
+	}
+	return invitation, nil
+}
+
+// GetInvitations returns all invitations to a workspace
+func (s *workspaceService) GetInvitations(workspaceId uuid.UUID) (*[]domain.WorkspaceInvitation, error) {
+	return s.r.FindInvitationsByWorkspace(workspaceId)
+}
+
+// RevokeInvitation deletes an invitation to a workspace
+func (s *workspaceService) RevokeInvitation(workspaceId uuid.UUID, invitationId uuid.UUID) error {
+	invitation, err := s.r.FindInvitation(invitationId)
+	if err != nil {
+		return err
+	}
+	if invitation.WorkspaceId != workspaceId {
+		return gorm.ErrRecordNotFound
+	}
+	return s.r.DeleteInvitation(invitation)
+}
+
+// AcceptInvitation adds the user to the workspace of a pending invitation, with the role it was sent with
+func (s *workspaceService) AcceptInvitation(invitationId uuid.UUID, userId string) (*domain.WorkspaceMember, error) {
+	member, err := s.r.AcceptInvitation(invitationId, userId, func(invitation *domain.WorkspaceInvitation) error {
+		if !invitation.IsPending(time.Now()) {
+			return fmt.Errorf("the invitation has already been accepted or has expired")
+		}
+		if _, err := s.r.FindMember(invitation.WorkspaceId, userId); err == nil {
+			return fmt.Errorf("you are already a member of the workspace")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("INFO: user `%s` joined the workspace `%s` as %s", userId, member.WorkspaceId, member.Role)
+	return member, nil
+}
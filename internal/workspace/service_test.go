@@ -0,0 +1,192 @@
+package workspace
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/ronilsonalves/5lnk/internal/domain"
+	"gorm.io/gorm"
+	"testing"
+)
+
+// membersRepository stores the members of the workspaces in memory.
+type membersRepository struct {
+	Repository
+	members map[string]*domain.WorkspaceMember
+}
+
+// newMembersRepository creates a repository of the members of a workspace, by user ID and role.
+func newMembersRepository(workspaceId uuid.UUID, roles map[string]string) *membersRepository {
+	r := &membersRepository{members: make(map[string]*domain.WorkspaceMember)}
+	for userId, role := range roles {
+		r.members[workspaceId.String()+userId] = &domain.WorkspaceMember{WorkspaceId: workspaceId, UserId: userId, Role: role}
+	}
+	return r
+}
+
+func (r *membersRepository) FindMember(workspaceId uuid.UUID, userId string) (*domain.WorkspaceMember, error) {
+	member, ok := r.members[workspaceId.String()+userId]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *member
+	return &found, nil
+}
+
+func (r *membersRepository) SaveMember(member *domain.WorkspaceMember) error {
+	saved := *member
+	r.members[member.WorkspaceId.String()+member.UserId] = &saved
+	return nil
+}
+
+func (r *membersRepository) DeleteMember(member *domain.WorkspaceMember) error {
+	delete(r.members, member.WorkspaceId.String()+member.UserId)
+	return nil
+}
+
+func (r *membersRepository) CountOwners(workspaceId uuid.UUID) (int64, error) {
+	var count int64
+	for _, member := range r.members {
+		if member.WorkspaceId == workspaceId && member.Role == domain.RoleOwner {
+			count++
+		}
+	}
+	return count, nil
+}
+
+var roles = map[string]string{
+	"owner":  domain.RoleOwner,
+	"admin":  domain.RoleAdmin,
+	"editor": domain.RoleEditor,
+	"viewer": domain.RoleViewer,
+}
+
+func TestAuthorize(t *testing.T) {
+	workspaceId := uuid.New()
+	s := NewWorkspaceService(newMembersRepository(workspaceId, roles))
+	tests := []struct {
+		name    string
+		userId  string
+		role    string
+		wantErr error
+	}{
+		{name: "owner administrates", userId: "owner", role: domain.RoleAdmin},
+		{name: "admin administrates", userId: "admin", role: domain.RoleAdmin},
+		{name: "editor edits", userId: "editor", role: domain.RoleEditor},
+		{name: "editor can't administrate", userId: "editor", role: domain.RoleAdmin, wantErr: ErrForbidden},
+		{name: "viewer reads", userId: "viewer", role: domain.RoleViewer},
+		{name: "viewer can't edit", userId: "viewer", role: domain.RoleEditor, wantErr: ErrForbidden},
+		{name: "outsider not told the workspace exists", userId: "outsider", role: domain.RoleViewer, wantErr: gorm.ErrRecordNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Authorize(workspaceId, tt.userId, tt.role); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Authorize() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorizeResource(t *testing.T) {
+	workspaceId := uuid.New()
+	s := NewWorkspaceService(newMembersRepository(workspaceId, roles))
+	tests := []struct {
+		name        string
+		ownerId     string
+		workspaceId *uuid.UUID
+		userId      string
+		role        string
+		wantErr     error
+	}{
+		{name: "personal resource of the user", ownerId: "user", userId: "user", role: domain.RoleEditor},
+		{name: "personal resource of another user", ownerId: "user", userId: "owner", role: domain.RoleViewer, wantErr: gorm.ErrRecordNotFound},
+		{name: "workspace resource created by another member", ownerId: "owner", workspaceId: &workspaceId, userId: "editor", role: domain.RoleEditor},
+		{name: "workspace resource read only", ownerId: "viewer", workspaceId: &workspaceId, userId: "viewer", role: domain.RoleEditor, wantErr: ErrForbidden},
+		{name: "workspace resource of a removed member", ownerId: "removed", workspaceId: &workspaceId, userId: "removed", role: domain.RoleViewer, wantErr: gorm.ErrRecordNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.AuthorizeResource(tt.ownerId, tt.workspaceId, tt.userId, tt.role); !errors.Is(err, tt.wantErr) {
+				t.Errorf("AuthorizeResource() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetMemberRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		roles   map[string]string
+		actorId string
+		userId  string
+		role    string
+		wantErr bool
+	}{
+		{name: "owner promotes to owner", roles: roles, actorId: "owner", userId: "admin", role: domain.RoleOwner},
+		{name: "admin promotes below admin", roles: roles, actorId: "admin", userId: "viewer", role: domain.RoleEditor},
+		{name: "admin can't promote to admin", roles: roles, actorId: "admin", userId: "editor", role: domain.RoleAdmin, wantErr: true},
+		{name: "admin can't demote an admin", roles: map[string]string{"owner": domain.RoleOwner, "admin": domain.RoleAdmin, "other": domain.RoleAdmin}, actorId: "admin", userId: "other", role: domain.RoleViewer, wantErr: true},
+		{name: "editor can't change roles", roles: roles, actorId: "editor", userId: "viewer", role: domain.RoleEditor, wantErr: true},
+		{name: "owner demotes another owner", roles: map[string]string{"owner": domain.RoleOwner, "other": domain.RoleOwner}, actorId: "owner", userId: "other", role: domain.RoleAdmin},
+		{name: "last owner can't be demoted", roles: roles, actorId: "owner", userId: "owner", role: domain.RoleAdmin, wantErr: true},
+		{name: "unknown role", roles: roles, actorId: "owner", userId: "viewer", role: "manager", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceId := uuid.New()
+			r := newMembersRepository(workspaceId, tt.roles)
+			s := NewWorkspaceService(r)
+			previous, _ := r.FindMember(workspaceId, tt.userId)
+			_, err := s.SetMemberRole(workspaceId, tt.actorId, tt.userId, tt.role)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetMemberRole() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			want := tt.role
+			if tt.wantErr {
+				want = previous.Role
+			}
+			if member, _ := r.FindMember(workspaceId, tt.userId); member.Role != want {
+				t.Errorf("role = %s, want %s", member.Role, want)
+			}
+		})
+	}
+}
+
+func TestRemoveMember(t *testing.T) {
+	tests := []struct {
+		name    string
+		roles   map[string]string
+		actorId string
+		userId  string
+		wantErr bool
+	}{
+		{name: "member leaves", roles: roles, actorId: "viewer", userId: "viewer"},
+		{name: "owner removes an admin", roles: roles, actorId: "owner", userId: "admin"},
+		{name: "admin removes an editor", roles: roles, actorId: "admin", userId: "editor"},
+		{name: "admin can't remove an owner", roles: roles, actorId: "admin", userId: "owner", wantErr: true},
+		{name: "editor can't remove a viewer", roles: roles, actorId: "editor", userId: "viewer", wantErr: true},
+		{name: "outsider can't remove a member", roles: roles, actorId: "outsider", userId: "viewer", wantErr: true},
+		{name: "last owner can't leave", roles: roles, actorId: "owner", userId: "owner", wantErr: true},
+		{name: "owner leaves while another owner stays", roles: map[string]string{"owner": domain.RoleOwner, "other": domain.RoleOwner}, actorId: "owner", userId: "owner"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceId := uuid.New()
+			r := newMembersRepository(workspaceId, tt.roles)
+			s := NewWorkspaceService(r)
+			err := s.RemoveMember(workspaceId, tt.actorId, tt.userId)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RemoveMember() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			_, err = r.FindMember(workspaceId, tt.userId)
+			if removed := err != nil; removed == tt.wantErr {
+				t.Errorf("member removed = %v, want %v", removed, !tt.wantErr)
+			}
+			// A removed member loses access to the workspace resources, including the ones they created
+			if !tt.wantErr {
+				if err := s.AuthorizeResource(tt.userId, &workspaceId, tt.userId, domain.RoleViewer); err == nil {
+					t.Error("AuthorizeResource() succeeded for a removed member")
+				}
+			}
+		})
+	}
+}
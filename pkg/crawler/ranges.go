@@ -0,0 +1,72 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// Ranges is a list of the IP networks crawlers, uptime monitors and scanners are known to use, loaded from a
+// file holding one CIDR or single address per line, as published by Google, Bing or the monitoring services.
+// A nil Ranges matches no address.
+type Ranges struct {
+	networks []*net.IPNet
+}
+
+// Open loads the ranges stored at path. An empty path returns nil Ranges.
+func Open(path string) (*Ranges, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open crawler ip ranges: %v", err)
+	}
+	defer file.Close()
+	return Load(file)
+}
+
+// Load reads the ranges from r. Blank lines and # comments are skipped.
+func Load(r io.Reader) (*Ranges, error) {
+	ranges := &Ranges{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		entry, _, _ := strings.Cut(scanner.Text(), "#")
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid crawler ip range at line %d: %v", line, err)
+		}
+		ranges.networks = append(ranges.networks, network)
+	}
+	return ranges, scanner.Err()
+}
+
+// Contains reports whether the address belongs to one of the ranges.
+func (r *Ranges) Contains(address string) bool {
+	if r == nil {
+		return false
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range r.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package crawler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContains(t *testing.T) {
+	ranges, err := Load(strings.NewReader(`
+# Googlebot
+66.249.64.0/19
+2001:4860:4801:10::/64
+
+203.0.113.7 # a single monitor
+2001:db8::1
+`))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	tests := []struct {
+		address string
+		want    bool
+	}{
+		{address: "66.249.66.1", want: true},
+		{address: "66.249.96.1", want: false},
+		{address: "2001:4860:4801:10::2a", want: true},
+		{address: "2001:4860:4801:11::1", want: false},
+		{address: "203.0.113.7", want: true},
+		{address: "203.0.113.8", want: false},
+		{address: "2001:db8::1", want: true},
+		{address: "2001:db8::2", want: false},
+		{address: "not an address", want: false},
+		{address: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if got := ranges.Contains(tt.address); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	if _, err := Load(strings.NewReader("66.249.64.0/19\n66.249.64.0/33\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Load() error = %v, want an error at line 2", err)
+	}
+}
+
+func TestNilRanges(t *testing.T) {
+	ranges, err := Open("")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if ranges.Contains("66.249.66.1") {
+		t.Error("Contains() = true for nil ranges, want false")
+	}
+}
@@ -0,0 +1,111 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DB is an offline IP to country database loaded from a CSV file. Each row holds an IP range, the
+// ISO 3166-1 alpha-2 code of its country and optionally its region: `start_ip,end_ip,country_code[,region]`,
+// as distributed by the free DB-IP and IP2Location LITE databases. A nil DB resolves every address to an
+// unknown location.
+type DB struct {
+	ranges []ipRange
+}
+
+type ipRange struct {
+	start    net.IP
+	end      net.IP
+	location Location
+}
+
+// Location is the country code and the region of an address.
+type Location struct {
+	Country string
+	Region  string
+}
+
+// Open loads the database stored at path. An empty path returns a nil DB.
+func Open(path string) (*DB, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open geoip database: %v", err)
+	}
+	defer file.Close()
+	return Load(file)
+}
+
+// Load reads the database rows from r.
+func Load(r io.Reader) (*DB, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	db := &DB{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read geoip database at line %d: %v", line, err)
+		}
+		if len(record) < 3 {
+			return nil, fmt.Errorf("invalid geoip database row at line %d", line)
+		}
+		start, end := net.ParseIP(strings.TrimSpace(record[0])), net.ParseIP(strings.TrimSpace(record[1]))
+		if start == nil || end == nil {
+			// Skip headers and comments
+			continue
+		}
+		location := Location{Country: strings.ToUpper(strings.TrimSpace(record[2]))}
+		if len(record) > 3 {
+			location.Region = strings.TrimSpace(record[3])
+		}
+		db.ranges = append(db.ranges, ipRange{
+			start:    start.To16(),
+			end:      end.To16(),
+			location: location,
+		})
+	}
+
+	sort.Slice(db.ranges, func(i, j int) bool {
+		return bytes.Compare(db.ranges[i].start, db.ranges[j].start) < 0
+	})
+	return db, nil
+}
+
+// Country returns the country code of the address, or an empty string when it is unknown.
+func (db *DB) Country(address string) string {
+	return db.Lookup(address).Country
+}
+
+// Lookup returns the location of the address, empty when it is unknown.
+func (db *DB) Lookup(address string) Location {
+	if db == nil {
+		return Location{}
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return Location{}
+	}
+	ip = ip.To16()
+
+	// Find the last range starting at or before the address
+	i := sort.Search(len(db.ranges), func(i int) bool {
+		return bytes.Compare(db.ranges[i].start, ip) > 0
+	}) - 1
+	if i < 0 || bytes.Compare(ip, db.ranges[i].end) > 0 {
+		return Location{}
+	}
+	return db.ranges[i].location
+}
@@ -0,0 +1,83 @@
+package hll
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// precision is the number of hash bits selecting a register, 4096 registers with a standard error of about 1.6%.
+const (
+	precision = 12
+	registers = 1 << precision
+)
+
+// Sketch is a HyperLogLog sketch estimating the number of distinct hashes inserted in it. Sketches of the same
+// precision can be merged, so the distinct count of a range is the union of its daily sketches.
+type Sketch struct {
+	registers []uint8
+}
+
+// New returns an empty sketch.
+func New() *Sketch {
+	return &Sketch{registers: make([]uint8, registers)}
+}
+
+// FromBytes restores a sketch from its registers. Empty data returns an empty sketch.
+func FromBytes(data []byte) (*Sketch, error) {
+	if len(data) == 0 {
+		return New(), nil
+	}
+	if len(data) != registers {
+		return nil, fmt.Errorf("invalid sketch size %d, expected %d", len(data), registers)
+	}
+	s := New()
+	copy(s.registers, data)
+	return s, nil
+}
+
+// Bytes returns the registers of the sketch, to be stored.
+func (s *Sketch) Bytes() []byte {
+	data := make([]byte, registers)
+	copy(data, s.registers)
+	return data
+}
+
+// Insert adds a uniformly distributed 64 bits hash to the sketch, reporting whether the sketch changed.
+func (s *Sketch) Insert(hash uint64) bool {
+	index := hash >> (64 - precision)
+	rank := uint8(bits.LeadingZeros64(hash<<precision|1<<(precision-1)) + 1)
+	if rank <= s.registers[index] {
+		return false
+	}
+	s.registers[index] = rank
+	return true
+}
+
+// Merge adds the hashes of other to the sketch.
+func (s *Sketch) Merge(other *Sketch) {
+	for i, rank := range other.registers {
+		if rank > s.registers[i] {
+			s.registers[i] = rank
+		}
+	}
+}
+
+// Count returns the estimated number of distinct hashes inserted in the sketch.
+func (s *Sketch) Count() uint64 {
+	m := float64(registers)
+	var sum float64
+	var zeros int
+	for _, rank := range s.registers {
+		sum += 1 / float64(uint64(1)<<rank)
+		if rank == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	// small cardinalities are better estimated by linear counting
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
@@ -0,0 +1,120 @@
+package hll
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// hash returns a uniformly distributed hash of the value.
+func hash(value int) uint64 {
+	sum := sha256.Sum256([]byte(strconv.Itoa(value)))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// sketchOf returns a sketch of the values from start to end, the end excluded.
+func sketchOf(start, end int) *Sketch {
+	s := New()
+	for i := start; i < end; i++ {
+		s.Insert(hash(i))
+	}
+	return s
+}
+
+// assertEstimate fails when the count is further than tolerance, relative, from want.
+func assertEstimate(t *testing.T, count uint64, want int, tolerance float64) {
+	t.Helper()
+	if math.Abs(float64(count)-float64(want)) > tolerance*float64(want) {
+		t.Errorf("Count() = %d, want %d within %.0f%%", count, want, tolerance*100)
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		distinct  int
+		tolerance float64
+	}{
+		{name: "one", distinct: 1, tolerance: 0},
+		{name: "small", distinct: 100, tolerance: 0.02},
+		{name: "linear counting range", distinct: 5000, tolerance: 0.05},
+		{name: "large", distinct: 200000, tolerance: 0.05},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertEstimate(t, sketchOf(0, tt.distinct).Count(), tt.distinct, tt.tolerance)
+		})
+	}
+	if count := New().Count(); count != 0 {
+		t.Errorf("Count() = %d for an empty sketch, want 0", count)
+	}
+}
+
+func TestInsert(t *testing.T) {
+	s := New()
+	if !s.Insert(hash(1)) {
+		t.Error("Insert() = false for a new hash, want true")
+	}
+	if s.Insert(hash(1)) {
+		t.Error("Insert() = true for a hash already inserted, want false")
+	}
+	for i := 0; i < 10; i++ {
+		s.Insert(hash(1))
+	}
+	if count := s.Count(); count != 1 {
+		t.Errorf("Count() = %d after inserting the same hash, want 1", count)
+	}
+	if !s.Insert(0) {
+		t.Error("Insert() = false for the zero hash, want true")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  [2]int
+		second [2]int
+		want   int
+	}{
+		{name: "disjoint", first: [2]int{0, 10000}, second: [2]int{10000, 20000}, want: 20000},
+		{name: "overlapping", first: [2]int{0, 10000}, second: [2]int{5000, 15000}, want: 15000},
+		{name: "same values", first: [2]int{0, 10000}, second: [2]int{0, 10000}, want: 10000},
+		{name: "empty", first: [2]int{0, 10000}, second: [2]int{0, 0}, want: 10000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := sketchOf(tt.first[0], tt.first[1])
+			s.Merge(sketchOf(tt.second[0], tt.second[1]))
+			assertEstimate(t, s.Count(), tt.want, 0.05)
+		})
+	}
+}
+
+func TestFromBytes(t *testing.T) {
+	s := sketchOf(0, 1000)
+	restored, err := FromBytes(s.Bytes())
+	if err != nil {
+		t.Fatalf("FromBytes() error = %v", err)
+	}
+	if !bytes.Equal(restored.Bytes(), s.Bytes()) || restored.Count() != s.Count() {
+		t.Error("FromBytes() didn't restore the sketch")
+	}
+
+	empty, err := FromBytes(nil)
+	if err != nil || empty.Count() != 0 {
+		t.Errorf("FromBytes(nil) = %v, %v, want an empty sketch", empty, err)
+	}
+	if _, err := FromBytes(make([]byte, registers-1)); err == nil {
+		t.Error("FromBytes() error = nil for a truncated sketch, want an error")
+	}
+
+	// The stored registers aren't shared with the sketch
+	data := s.Bytes()
+	data[0]++
+	if bytes.Equal(s.Bytes(), data) {
+		t.Error("Bytes() shares the registers of the sketch")
+	}
+}
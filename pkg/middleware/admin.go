@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"github.com/ronilsonalves/5lnk/pkg/web"
+	"net/http"
+	"os"
+)
+
+// RequireAdmin is a middleware that only lets through the requests carrying the ADMIN_TOKEN in the
+// X-Admin-Token header. Admin routes are disabled when no token is configured.
+func RequireAdmin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := os.Getenv("ADMIN_TOKEN")
+		provided := ctx.GetHeader("X-Admin-Token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(provided)) != 1 {
+			web.BadResponse(ctx, http.StatusForbidden, "error", "forbidden")
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -1,8 +1,7 @@
 package middleware
 
 import (
-	"context"
-	firebase "firebase.google.com/go/v4"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/ronilsonalves/5lnk/config/auth"
 	"github.com/ronilsonalves/5lnk/internal/apikey"
@@ -12,13 +11,76 @@ import (
 	"strings"
 )
 
-// Authenticate is a middleware that checks if the user is authenticated.
-func Authenticate(c context.Context, app *firebase.App) gin.HandlerFunc {
-	app, err := auth.InitializeFirebase(c)
-	if err != nil {
-		log.Fatalf("error initializing app: %v\n\n", err)
+// Principal is the identity a request is authenticated as. Principals authenticated by an API key are limited to
+// the scopes of the key.
+type Principal struct {
+	UserId string
+	APIKey bool
+	Scopes []string
+}
+
+// Can reports whether the principal is granted the scope.
+func (p Principal) Can(scope string) bool {
+	return !p.APIKey || apikey.Grants(p.Scopes, scope)
+}
+
+// principalKey is the key of the principal in the request context.
+const principalKey = "principal"
+
+// GetPrincipal returns the principal authenticated by Authenticate.
+func GetPrincipal(ctx *gin.Context) Principal {
+	principal, _ := ctx.MustGet(principalKey).(Principal)
+	return principal
+}
+
+// RequireUser is a middleware that only lets the principal act on the user of the path param.
+func RequireUser(param string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Param(param) != GetPrincipal(ctx).UserId {
+			web.BadResponse(ctx, http.StatusForbidden, "error", "access denied to the resources of another user")
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// RequireScope is a middleware that only lets the principals granted the scope through.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !GetPrincipal(ctx).Can(scope) {
+			web.BadResponse(ctx, http.StatusForbidden, "error", fmt.Sprintf("the api key lacks the `%s` scope", scope))
+			return
+		}
+		ctx.Next()
 	}
+}
+
+// RequireToken is a middleware that rejects the principals authenticated by an API key, so a leaked key can't be
+// used to read or replace itself.
+func RequireToken() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if GetPrincipal(ctx).APIKey {
+			web.BadResponse(ctx, http.StatusForbidden, "error", "api keys can't manage api keys")
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// TokenFromQuery is a middleware that reads the token from the access_token query param when the Authorization
+// header is missing, as browsers can't set headers on EventSource and WebSocket requests.
+func TokenFromQuery() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if token := ctx.Query("access_token"); token != "" && ctx.GetHeader("Authorization") == "" {
+			ctx.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		ctx.Next()
+	}
+}
 
+// Authenticate is a middleware that checks if the user is authenticated, by an API key or by a token verified by
+// the authenticator.
+func Authenticate(authenticator auth.Authenticator, keys apikey.Service) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		rawAccessToken := strings.Replace(ctx.GetHeader("Authorization"), "Bearer ", "", 1)
 
@@ -28,32 +90,27 @@ func Authenticate(c context.Context, app *firebase.App) gin.HandlerFunc {
 			return
 		}
 
-		// If the token is an API Key, we need to check if it is valid.
-		if len(rawAccessToken) == 64 {
-			s := apikey.NewApiKeyService(app)
-			_, err := s.RetrieveUserId(rawAccessToken)
+		// If the token is an API Key, we need to check if it is valid. The allowlist is checked against ClientIP, which
+		// only reads the forwarding headers set by the trusted proxies and is the connection peer otherwise.
+		if apikey.IsToken(rawAccessToken) {
+			key, err := keys.Authenticate(rawAccessToken, ctx.ClientIP())
 			if err != nil {
 				log.Printf("error retrieving userId: %v\n\n", err)
 				web.BadResponse(ctx, http.StatusUnauthorized, "error", "unauthorized")
 				return
 			}
+			ctx.Set(principalKey, Principal{UserId: key.UserId, APIKey: true, Scopes: key.Scopes})
 			ctx.Next()
 			return
 		}
 
-		client, err := app.Auth(c)
-		if err != nil {
-			log.Printf("error getting Auth client: %v\n", err)
-			web.BadResponse(ctx, http.StatusInternalServerError, "error", "an error occurred while authenticating the user")
-			return
-		}
-
-		_, err = client.VerifyIDToken(c, rawAccessToken)
+		userId, err := authenticator.Authenticate(ctx.Request.Context(), rawAccessToken)
 		if err != nil {
 			log.Printf("error verifying ID token: %v\n\n", err)
 			web.BadResponse(ctx, http.StatusUnauthorized, "error", "unauthorized")
 			return
 		}
+		ctx.Set(principalKey, Principal{UserId: userId})
 		ctx.Next()
 	}
 }
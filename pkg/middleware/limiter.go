@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// AttemptLimiter limits how many failed attempts a key can make inside a time window.
+type AttemptLimiter struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	attempts map[string]*attempts
+}
+
+type attempts struct {
+	count int
+	reset time.Time
+}
+
+// NewAttemptLimiter creates a limiter allowing max failed attempts per key every window.
+func NewAttemptLimiter(max int, window time.Duration) *AttemptLimiter {
+	return &AttemptLimiter{
+		max:      max,
+		window:   window,
+		attempts: make(map[string]*attempts),
+	}
+}
+
+// Allow reports whether the key still has attempts left in the current window.
+func (l *AttemptLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a, ok := l.attempts[key]
+	if !ok || time.Now().After(a.reset) {
+		return true
+	}
+	return a.count < l.max
+}
+
+// Fail registers a failed attempt for the key.
+func (l *AttemptLimiter) Fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for k, a := range l.attempts {
+		if now.After(a.reset) {
+			delete(l.attempts, k)
+		}
+	}
+	a, ok := l.attempts[key]
+	if !ok {
+		a = &attempts{reset: now.Add(l.window)}
+		l.attempts[key] = a
+	}
+	a.count++
+}
+
+// Reset clears the failed attempts of the key.
+func (l *AttemptLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.attempts, key)
+}
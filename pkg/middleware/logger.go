@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Logger is the request logger of gin with the access_token query param redacted, as browsers pass their token in
+// the query of the stats streams.
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		var statusColor, methodColor, resetColor string
+		if param.IsOutputColor() {
+			statusColor = param.StatusCodeColor()
+			methodColor = param.MethodColor()
+			resetColor = param.ResetColor()
+		}
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			statusColor, param.StatusCode, resetColor,
+			param.Latency,
+			param.ClientIP,
+			methodColor, param.Method, resetColor,
+			redactToken(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// redactToken replaces the access_token query param of the path
+func redactToken(path string) string {
+	i := strings.IndexByte(path, '?')
+	if i < 0 {
+		return path
+	}
+	query, err := url.ParseQuery(path[i+1:])
+	if err != nil || !query.Has("access_token") {
+		if strings.Contains(path[i+1:], "access_token") {
+			return path[:i] + "?REDACTED"
+		}
+		return path
+	}
+	query.Set("access_token", "REDACTED")
+	return path[:i] + "?" + query.Encode()
+}
@@ -3,19 +3,85 @@ package middleware
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/mileusna/useragent"
+	"strings"
 )
 
 type FormattedUserAgent struct {
-	OS      string `json:"os"`
-	Browser string `json:"browser"`
+	OS       string `json:"os"`
+	Browser  string `json:"browser"`
+	Platform string `json:"platform"`
+	Device   string `json:"device"`
+	Crawler  bool   `json:"crawler"`
+	Bot      bool   `json:"bot"`
 }
 
-// GetFormattedUserAgent returns the user agent compounding the OS and Browser.
+// previewBots are the link preview crawlers of chat apps and social networks, not always recognised as bots.
+var previewBots = []string{
+	"facebookexternalhit", "facebot", "twitterbot", "slackbot", "slack-imgproxy", "linkedinbot", "discordbot",
+	"telegrambot", "whatsapp", "skypeuripreview", "pinterestbot", "redditbot", "embedly", "vkshare", "iframely",
+	"mastodon", "bluesky", "google-pagerenderer", "applebot", "snapchat", "viber", "line-poker",
+}
+
+// automatedAgents are the uptime monitors, scanners and HTTP libraries whose requests are not human visits.
+var automatedAgents = []string{
+	"uptimerobot", "pingdom", "statuscake", "site24x7", "betteruptime", "freshping", "checkly", "nagios", "zabbix",
+	"datadog", "newrelic", "zgrab", "masscan", "nmap", "nikto", "sqlmap", "nuclei", "censys", "curl/", "wget/",
+	"python-requests", "python-urllib", "aiohttp", "go-http-client", "java/", "okhttp", "axios", "node-fetch",
+	"libwww-perl", "scrapy", "headlesschrome", "phantomjs",
+}
+
+// Mobile platforms a visitor may open app links on.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+)
+
+// Device classes of a visitor.
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+)
+
+// GetFormattedUserAgent returns the user agent compounding the OS and Browser. Crawler is set for the link preview
+// crawlers of search engines and social networks, Bot for every automated client, crawlers included.
 func GetFormattedUserAgent(ctx *gin.Context) FormattedUserAgent {
 	rawUserAgent := ctx.Request.UserAgent()
 	ua := useragent.Parse(rawUserAgent)
+	var platform string
+	switch {
+	case ua.IsIOS():
+		platform = PlatformIOS
+	case ua.IsAndroid():
+		platform = PlatformAndroid
+	}
+	var device string
+	switch {
+	case ua.Tablet:
+		device = DeviceTablet
+	case ua.Mobile:
+		device = DeviceMobile
+	case ua.Desktop:
+		device = DeviceDesktop
+	}
+	crawler := ua.Bot || containsAny(rawUserAgent, previewBots)
 	return FormattedUserAgent{
-		OS:      ua.OS + " " + ua.OSVersion,
-		Browser: ua.Name + " " + ua.Version,
+		OS:       ua.OS + " " + ua.OSVersion,
+		Browser:  ua.Name + " " + ua.Version,
+		Platform: platform,
+		Device:   device,
+		Crawler:  crawler,
+		Bot:      crawler || rawUserAgent == "" || containsAny(rawUserAgent, automatedAgents),
+	}
+}
+
+// containsAny reports whether the user agent contains one of the lower-case names.
+func containsAny(rawUserAgent string, names []string) bool {
+	rawUserAgent = strings.ToLower(rawUserAgent)
+	for _, name := range names {
+		if strings.Contains(rawUserAgent, name) {
+			return true
+		}
 	}
+	return false
 }
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetFormattedUserAgent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name      string
+		userAgent string
+		crawler   bool
+		bot       bool
+		platform  string
+	}{
+		{
+			name:      "desktop browser",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36",
+		},
+		{
+			name:      "mobile browser",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+			platform:  PlatformIOS,
+		},
+		{
+			name:      "search engine crawler",
+			userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			crawler:   true,
+			bot:       true,
+		},
+		{
+			name:      "link preview crawler",
+			userAgent: "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+			crawler:   true,
+			bot:       true,
+		},
+		{
+			name:      "chat app preview",
+			userAgent: "WhatsApp/2.23.20.0",
+			crawler:   true,
+			bot:       true,
+		},
+		{
+			name:      "uptime monitor",
+			userAgent: "Site24x7",
+			bot:       true,
+		},
+		{
+			name:      "http library",
+			userAgent: "python-requests/2.31.0",
+			bot:       true,
+		},
+		{
+			name: "no user agent",
+			bot:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("GET", "/abc", nil)
+			ctx.Request.Header.Set("User-Agent", tt.userAgent)
+
+			ua := GetFormattedUserAgent(ctx)
+			if ua.Crawler != tt.crawler {
+				t.Errorf("Crawler = %v, want %v", ua.Crawler, tt.crawler)
+			}
+			if ua.Bot != tt.bot {
+				t.Errorf("Bot = %v, want %v", ua.Bot, tt.bot)
+			}
+			if ua.Platform != tt.platform {
+				t.Errorf("Platform = %q, want %q", ua.Platform, tt.platform)
+			}
+		})
+	}
+}
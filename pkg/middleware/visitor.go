@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ronilsonalves/5lnk/internal/apikey"
+	"net"
+	"strings"
+)
+
+// Headers describing the visitor of a page requested by a frontend on its behalf.
+const (
+	HeaderVisitorIP        = "X-Visitor-IP"
+	HeaderVisitorUserAgent = "X-Visitor-User-Agent"
+	HeaderVisitorLanguage  = "X-Visitor-Accept-Language"
+	HeaderVisitorReferer   = "X-Visitor-Referer"
+)
+
+const visitorAddressKey = "visitorAddress"
+
+// forwardedHeaders maps the request headers describing the visitor to the headers forwarding them.
+var forwardedHeaders = map[string]string{
+	"User-Agent":      HeaderVisitorUserAgent,
+	"Accept-Language": HeaderVisitorLanguage,
+	"Referer":         HeaderVisitorReferer,
+}
+
+// ForwardedVisitor is a middleware that describes the visitor by the headers forwarded by the frontend instead of
+// the frontend itself, when its API key is granted the visits:forward scope. Other principals can't set them.
+func ForwardedVisitor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal := GetPrincipal(ctx)
+		if principal.APIKey && apikey.Grants(principal.Scopes, apikey.ScopeVisitsForward) {
+			if ip := net.ParseIP(strings.TrimSpace(ctx.GetHeader(HeaderVisitorIP))); ip != nil {
+				ctx.Set(visitorAddressKey, ip.String())
+			}
+			for header, forwarded := range forwardedHeaders {
+				if value := ctx.GetHeader(forwarded); value != "" {
+					ctx.Request.Header.Set(header, value)
+				}
+			}
+		}
+		ctx.Next()
+	}
+}
+
+// VisitorAddress returns the address of the visitor forwarded by the frontend, or the client address.
+func VisitorAddress(ctx *gin.Context) string {
+	if address := ctx.GetString(visitorAddressKey); address != "" {
+		return address
+	}
+	return ctx.ClientIP()
+}
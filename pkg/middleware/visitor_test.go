@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestForwardedVisitor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name      string
+		principal Principal
+		ip        string
+		address   string
+		userAgent string
+	}{
+		{
+			name:      "forwarding key",
+			principal: Principal{UserId: "frontend", APIKey: true, Scopes: []string{"pages:read", "visits:forward"}},
+			ip:        " 203.0.113.9 ",
+			address:   "203.0.113.9",
+			userAgent: "Visitor/1.0",
+		},
+		{
+			name:      "every visits action",
+			principal: Principal{UserId: "frontend", APIKey: true, Scopes: []string{"pages:read", "visits:*"}},
+			ip:        "2001:db8::1",
+			address:   "2001:db8::1",
+			userAgent: "Visitor/1.0",
+		},
+		{
+			name:      "invalid forwarded address",
+			principal: Principal{UserId: "frontend", APIKey: true, Scopes: []string{"visits:forward"}},
+			ip:        "203.0.113.9, 10.0.0.1",
+			address:   "192.0.2.1",
+			userAgent: "Visitor/1.0",
+		},
+		{
+			name:      "key without the scope",
+			principal: Principal{UserId: "frontend", APIKey: true, Scopes: []string{"pages:read"}},
+			ip:        "203.0.113.9",
+			address:   "192.0.2.1",
+			userAgent: "Frontend/1.0",
+		},
+		{
+			name:      "user token",
+			principal: Principal{UserId: "user"},
+			ip:        "203.0.113.9",
+			address:   "192.0.2.1",
+			userAgent: "Frontend/1.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("GET", "/api/v1/pages/abc", nil)
+			ctx.Request.RemoteAddr = "192.0.2.1:4321"
+			ctx.Request.Header.Set("User-Agent", "Frontend/1.0")
+			ctx.Request.Header.Set(HeaderVisitorIP, tt.ip)
+			ctx.Request.Header.Set(HeaderVisitorUserAgent, "Visitor/1.0")
+			ctx.Set(principalKey, tt.principal)
+
+			ForwardedVisitor()(ctx)
+
+			if address := VisitorAddress(ctx); address != tt.address {
+				t.Errorf("VisitorAddress() = %q, want %q", address, tt.address)
+			}
+			if userAgent := ctx.Request.UserAgent(); userAgent != tt.userAgent {
+				t.Errorf("User-Agent = %q, want %q", userAgent, tt.userAgent)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package web
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+	"html/template"
+	"net/http"
+)
+
+// templates holds the HTML pages served to visitors on the redirect routes.
+var templates = template.Must(template.New("pages").Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+{{end}}
+
+{{define "style"}}<style>
+body{font-family:system-ui,sans-serif;background:#f5f5f5;color:#222;display:flex;align-items:center;justify-content:center;min-height:100vh;margin:0}
+main{background:#fff;border-radius:8px;box-shadow:0 1px 4px rgba(0,0,0,.1);padding:2rem;max-width:28rem;width:100%}
+h1{font-size:1.25rem;margin-top:0}
+a{color:#4f46e5}
+input,button{font:inherit;padding:.5rem;border-radius:4px;border:1px solid #ccc;width:100%;box-sizing:border-box;margin-top:.5rem}
+button{background:#4f46e5;color:#fff;border:0;cursor:pointer}
+.error{color:#b91c1c}
+</style>
+{{end}}
+
+{{define "gone"}}{{template "header"}}
+{{if .FallbackURL}}<meta http-equiv="refresh" content="3;url={{.FallbackURL}}">{{end}}
+<title>Link expired</title>
+{{template "style"}}
+</head>
+<body>
+<main>
+<h1>This link has expired</h1>
+<p>{{.Message}}</p>
+{{if .FallbackURL}}<p>You will be redirected to <a href="{{.FallbackURL}}">{{.FallbackURL}}</a>.</p>{{end}}
+</main>
+</body>
+</html>
+{{end}}
+
+{{define "unlock"}}{{template "header"}}
+<title>Protected link</title>
+{{template "style"}}
+</head>
+<body>
+<main>
+<h1>This link is password protected</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="{{.Action}}">
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="off" required autofocus>
+<button type="submit">Unlock</button>
+</form>
+</main>
+</body>
+</html>
+{{end}}
+
+{{define "redirect"}}{{template "header"}}
+{{if not .Script}}<meta http-equiv="refresh" content="0;url={{.Destination}}">{{end}}
+<title>Redirecting…</title>
+{{template "style"}}
+</head>
+<body>
+<main>
+<h1>Redirecting…</h1>
+<p>If you are not redirected, <a href="{{.Destination}}">continue to the destination</a>.</p>
+</main>
+{{if .Script}}<script>window.location.replace({{.Destination}});</script>{{end}}
+</body>
+</html>
+{{end}}
+
+{{define "app"}}{{template "header"}}
+<title>Opening the app…</title>
+{{template "style"}}
+</head>
+<body>
+<main>
+<h1>Opening the app…</h1>
+<p><a href="{{.AppURL}}">Open in the app</a> or <a href="{{.FallbackURL}}">continue without it</a>.</p>
+</main>
+<script>
+window.location.replace({{.AppURL}});
+setTimeout(function(){if(!document.hidden){window.location.replace({{.FallbackURL}});}}, 1500);
+</script>
+</body>
+</html>
+{{end}}
+
+{{define "preview"}}{{template "header"}}
+<title>{{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:url" content="{{.URL}}">
+<meta property="og:title" content="{{.Title}}">
+{{if .Description}}<meta property="og:description" content="{{.Description}}">
+<meta name="description" content="{{.Description}}">{{end}}
+{{if .Image}}<meta property="og:image" content="{{.Image}}">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:image" content="{{.Image}}">{{else}}<meta name="twitter:card" content="summary">{{end}}
+<meta name="twitter:title" content="{{.Title}}">
+{{if .Description}}<meta name="twitter:description" content="{{.Description}}">{{end}}
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+</body>
+</html>
+{{end}}
+
+{{define "warning"}}{{template "header"}}
+<title>Suspicious link</title>
+{{template "style"}}
+</head>
+<body>
+<main>
+<h1>This link may be unsafe</h1>
+<p>This link was reported as potentially harmful{{if .Reason}}: {{.Reason}}{{else}}.{{end}}</p>
+<p>Only continue if you trust its destination.</p>
+<p><a href="{{.ContinueURL}}" rel="nofollow noreferrer">Continue anyway</a></p>
+</main>
+</body>
+</html>
+{{end}}
+`))
+
+// HTMLResponse renders one of the visitor pages with the given status code.
+func HTMLResponse(ctx *gin.Context, statusCode int, name string, data interface{}) {
+	ctx.Render(statusCode, render.HTML{Template: templates, Name: name, Data: data})
+	ctx.Abort()
+}
+
+// GoneResponse tells the visitor the resource is no longer available. When a fallback URL is provided
+// an HTML page forwarding to it is served, otherwise the regular error payload is returned.
+func GoneResponse(ctx *gin.Context, message, fallbackURL string) {
+	if fallbackURL == "" {
+		BadResponse(ctx, http.StatusGone, "error", message)
+		return
+	}
+	HTMLResponse(ctx, http.StatusGone, "gone", gin.H{
+		"Message":     message,
+		"FallbackURL": fallbackURL,
+	})
+}
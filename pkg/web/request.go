@@ -1,28 +1,100 @@
 package web
 
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
 // CreateLinksPage represents the request to create a new links page
 type CreateLinksPage struct {
-	Title       string  `json:"title" binding:"required"`
-	Description string  `json:"description" binding:"required"`
-	ImageURL    string  `json:"imageURL" binding:"required"`
-	UserId      string  `json:"userId" binding:"required"`
-	Alias       string  `json:"alias" binding:"required"`
-	Domain      string  `json:"domain" binding:"required"`
-	Links       []links `json:"links" binding:"required"`
+	Title       string     `json:"title" binding:"required"`
+	Description string     `json:"description" binding:"required"`
+	ImageURL    string     `json:"imageURL" binding:"required"`
+	UserId      string     `json:"userId"`
+	WorkspaceId *uuid.UUID `json:"workspaceId"`
+	Alias       string     `json:"alias" binding:"required"`
+	Domain      string     `json:"domain" binding:"required"`
+	Links       []links    `json:"links" binding:"required"`
 }
 
 // CreateShortenURL represents the request to create a new shortened URL
 type CreateShortenURL struct {
-	URL         string `json:"url" binding:"required"`
-	ShortDomain string `json:"domain" biding:"required"`
-	UserId      string `json:"userId" biding:"required"`
-	Title       string `json:"title"`
-	PageRefer   string `json:"pageRefer"`
-	Alias       string `json:"alias"`
+	URL           string     `json:"url" binding:"required"`
+	ShortDomain   string     `json:"domain" biding:"required"`
+	UserId        string     `json:"userId" biding:"required"`
+	WorkspaceId   *uuid.UUID `json:"workspaceId"`
+	Title         string     `json:"title"`
+	PageRefer     string     `json:"pageRefer"`
+	Alias         string     `json:"alias"`
+	ExpiresAt     *time.Time `json:"expiresAt"`
+	MaxClicks     int        `json:"maxClicks"`
+	Password      string     `json:"password"`
+	Variants      []Variant  `json:"variants"`
+	RedirectType  string     `json:"redirectType"`
+	ForwardQuery  bool       `json:"forwardQuery"`
+	UTM           UTM        `json:"utm"`
+	App           AppLink    `json:"app"`
+	OGTitle       string     `json:"ogTitle"`
+	OGDescription string     `json:"ogDescription"`
+	OGImage       string     `json:"ogImage"`
+}
+
+// AppLink represents the mobile app URIs of a shortened URL and their store fallbacks
+type AppLink struct {
+	IOSURL          string `json:"iosUrl"`
+	IOSStoreURL     string `json:"iosStoreUrl"`
+	AndroidURL      string `json:"androidUrl"`
+	AndroidPackage  string `json:"androidPackage"`
+	AndroidStoreURL string `json:"androidStoreUrl"`
+}
+
+// UTM represents the campaign parameters appended to the destination of a shortened URL
+type UTM struct {
+	Source   string `json:"source"`
+	Medium   string `json:"medium"`
+	Campaign string `json:"campaign"`
+	Term     string `json:"term"`
+	Content  string `json:"content"`
+}
+
+// Variant represents a weighted destination of a shortened URL
+type Variant struct {
+	Label       string `json:"label"`
+	Destination string `json:"destination" binding:"required"`
+	Weight      int    `json:"weight" binding:"required"`
+}
+
+// BulkLinkReport represents the outcome of a bulk creation of shortened URLs
+type BulkLinkReport struct {
+	Created      int              `json:"created"`
+	Deduplicated int              `json:"deduplicated"`
+	Failed       int              `json:"failed"`
+	Results      []BulkLinkResult `json:"results"`
+}
+
+// BulkLinkResult represents the outcome of a single row of a bulk creation
+type BulkLinkResult struct {
+	Row       int    `json:"row"`
+	URL       string `json:"url"`
+	Status    string `json:"status"`
+	ID        string `json:"id,omitempty"`
+	Shortened string `json:"shortened,omitempty"`
+	FinalURL  string `json:"finalUrl,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// FlagLink represents the reason an admin flags a link as unsafe
+type FlagLink struct {
+	Reason string `json:"reason" binding:"required"`
 }
 
-type APIKey struct {
-	UserId string `json:"userId" binding:"required"`
+// CreateAPIKey represents the request to create a new API key
+type CreateAPIKey struct {
+	UserId     string     `json:"userId"`
+	Name       string     `json:"name" binding:"required"`
+	Scopes     []string   `json:"scopes" binding:"required"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	AllowedIPs []string   `json:"allowedIps"`
 }
 
 // StatsOverview represents the stats summary
@@ -37,19 +109,84 @@ type links struct {
 }
 
 type LinksSummary struct {
-	Total  int64 `json:"total"`
-	Clicks int64 `json:"clicks"`
+	Total    int64 `json:"total"`
+	Clicks   int64 `json:"clicks"`
+	Visitors int64 `json:"visitors"`
 }
 
 type PagesSummary struct {
-	Total int64 `json:"total"`
-	Views int64 `json:"views"`
+	Total    int64 `json:"total"`
+	Views    int64 `json:"views"`
+	Visitors int64 `json:"visitors"`
+}
+
+// VisitorStats represents the total and the estimated unique visits of a date range. Unique visitors are counted
+// apart from bots, and a visitor coming back on another day is counted again.
+type VisitorStats struct {
+	Total  int64            `json:"total"`
+	Unique int64            `json:"unique"`
+	Days   []VisitorsByDate `json:"days,omitempty"`
+}
+
+// VisitorsByDate represents the total and the estimated unique visits of a day
+type VisitorsByDate struct {
+	Date   string `json:"date"`
+	Total  int64  `json:"total"`
+	Unique int64  `json:"unique"`
+}
+
+// StatsPage represents a page of stats with the total and unique visits of the date range
+type StatsPage struct {
+	Pagination
+	Total  int64 `json:"total"`
+	Unique int64 `json:"unique"`
+}
+
+// VariantStats represents the clicks of a link variant
+type VariantStats struct {
+	Variant     string `json:"variant"`
+	Label       string `json:"label"`
+	Destination string `json:"destination"`
+	Weight      int    `json:"weight"`
+	Total       int64  `json:"total"`
+}
+
+// StatsFilter represents the period and the visitors the stats are computed for
+type StatsFilter struct {
+	StartDate   string
+	EndDate     string
+	IncludeBots bool
+	GroupBy     []string
+	Granularity string
+	Location    *time.Location
 }
 
 // StatsByDate represents the stats grouped by date
 type StatsByDate struct {
-	Total   int64  `json:"total"`
-	Date    string `json:"date"`
-	OS      string `json:"os"`
-	Browser string `json:"browser"`
+	Total    int64  `json:"total"`
+	Date     string `json:"date"`
+	OS       string `json:"os"`
+	Browser  string `json:"browser"`
+	Device   string `json:"device,omitempty"`
+	Country  string `json:"country,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Referrer string `json:"referrer,omitempty"`
+	Language string `json:"language,omitempty"`
+	Target   string `json:"target,omitempty"`
+}
+
+// CreateWorkspace represents the request to create or rename a workspace
+type CreateWorkspace struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// UpdateMember represents the request to change the role of a workspace member
+type UpdateMember struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// CreateInvitation represents the request to invite a user to a workspace
+type CreateInvitation struct {
+	Email string `json:"email"`
+	Role  string `json:"role" binding:"required"`
 }